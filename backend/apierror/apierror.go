@@ -0,0 +1,128 @@
+// Package apierror writes every error answer in one shape:
+//
+//	{"code": "validation_failed", "message": "...", "error": "...",
+//	 "fields": [{"field": "phone", "code": "required", "message": "..."}],
+//	 "request_id": "..."}
+//
+// code is for programs, message for people, and fields point at the form
+// inputs to highlight. error repeats the message for clients written
+// before the envelope.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID of a request, set by the request ID
+// middleware on the way in and echoed in the envelope
+const RequestIDHeader = "X-Request-ID"
+
+// Codes for the envelope. Handlers usually leave the code to the status.
+const (
+	BadRequest       = "bad_request"
+	InvalidJSON      = "invalid_json"
+	InvalidID        = "invalid_id"
+	ValidationFailed = "validation_failed"
+	Unauthorized     = "unauthorized"
+	NotFound         = "not_found"
+	MethodNotAllowed = "method_not_allowed"
+	Conflict         = "conflict"
+	TooLarge         = "too_large"
+	Unprocessable    = "unprocessable"
+	Internal         = "internal_error"
+)
+
+// FieldError is one problem with one field. Field is the JSON path of the
+// input, e.g. phone or members[1].name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Body is the error envelope
+type Body struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Error     string       `json:"error"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// byStatus is the code of an answer whose handler did not pick one
+var byStatus = map[int]string{
+	http.StatusBadRequest:            BadRequest,
+	http.StatusUnauthorized:          Unauthorized,
+	http.StatusNotFound:              NotFound,
+	http.StatusMethodNotAllowed:      MethodNotAllowed,
+	http.StatusConflict:              Conflict,
+	http.StatusRequestEntityTooLarge: TooLarge,
+	http.StatusUnprocessableEntity:   Unprocessable,
+	http.StatusInternalServerError:   Internal,
+}
+
+// CodeFor is the default code of a status
+func CodeFor(status int) string {
+	if code, ok := byStatus[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return Internal
+	}
+	return BadRequest
+}
+
+// New is the envelope for a message, with the request ID of the answer
+// being written to w
+func New(w http.ResponseWriter, code, message string) Body {
+	return Body{
+		Code:      code,
+		Message:   message,
+		Error:     message,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+}
+
+// Write answers with the envelope, its code taken from the status
+func Write(w http.ResponseWriter, status int, message string) {
+	WriteCode(w, status, CodeFor(status), message)
+}
+
+// WriteCode answers with the envelope and a chosen code
+func WriteCode(w http.ResponseWriter, status int, code, message string) {
+	WriteBody(w, status, New(w, code, message))
+}
+
+// Invalid answers 400 validation_failed listing the fields at fault
+func Invalid(w http.ResponseWriter, fields []FieldError) {
+	body := New(w, ValidationFailed, Summary(fields))
+	body.Fields = fields
+	WriteBody(w, http.StatusBadRequest, body)
+}
+
+// InvalidField answers 400 validation_failed for one field, for checks the
+// validate tags cannot express
+func InvalidField(w http.ResponseWriter, field, code, message string) {
+	Invalid(w, []FieldError{{Field: field, Code: code, Message: message}})
+}
+
+// WriteBody answers with a built envelope, or anything that embeds one
+func WriteBody(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Summary is the message for a list of field errors: the only one's
+// message, or a count
+func Summary(fields []FieldError) string {
+	switch len(fields) {
+	case 0:
+		return "Invalid request"
+	case 1:
+		return fields[0].Message
+	}
+	return "Some fields are not valid"
+}
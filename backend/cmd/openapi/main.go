@@ -0,0 +1,51 @@
+// Command openapi writes the OpenAPI document of the API to
+// docs/openapi.json. With -check it writes nothing and fails when a route
+// has no annotation, an annotation has no route, or the committed document
+// is out of date. Run it from the backend directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	routers "flood-relief-system/backend/routers"
+)
+
+func main() {
+	out := flag.String("out", filepath.Join("docs", "openapi.json"), "where the document is written")
+	check := flag.Bool("check", false, "fail instead of writing when the document is out of date")
+	flag.Parse()
+
+	routers.SetupRoutes()
+	spec, err := routers.Spec()
+	if err != nil {
+		log.Fatalf("❌ Routes and OpenAPI annotations do not match:\n%s", err)
+	}
+	data, err := spec.JSON()
+	if err != nil {
+		log.Fatal("❌ Failed to write OpenAPI document:", err)
+	}
+
+	if *check {
+		committed, err := os.ReadFile(*out)
+		if err != nil {
+			log.Fatal("❌ Failed to read the committed OpenAPI document:", err)
+		}
+		if !bytes.Equal(committed, data) {
+			log.Fatalf("❌ %s is out of date; run go run ./cmd/openapi", *out)
+		}
+		log.Printf("✅ %s is up to date", *out)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatal("❌ Failed to create the docs directory:", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal("❌ Failed to write OpenAPI document:", err)
+	}
+	log.Printf("✅ Wrote %s", *out)
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/events"
+	"flood-relief-system/backend/jobs"
 	middlewares "flood-relief-system/backend/middleware"
 	"flood-relief-system/backend/migrations"
 	routers "flood-relief-system/backend/routers"
+	"flood-relief-system/backend/webhooks"
 
 	"fmt"
 	"log"
@@ -26,9 +29,26 @@ func main() {
 	config.ConnectDatabase()
 	migrations.RunMigrations()
 
+	// Change stream: record changes from here on, queue webhook deliveries
+	// with them and fan them out
+	events.OnRecord(webhooks.Enqueue)
+	if err := events.Register(config.GetDB()); err != nil {
+		log.Fatal("❌ Failed to register change event callbacks:", err)
+	}
+	events.Start(config.GetDB())
+
+	// Background jobs
+	jobs.StartExpiryJob()
+	jobs.StartLowStockJob()
+	jobs.StartEscalationJob()
+	jobs.StartContactVerificationJob()
+	jobs.StartChangeEventPruneJob()
+	jobs.StartWebhookJob()
+
 	router := routers.SetupRoutes()
 
 	handler := middlewares.LoggerMiddleware(router)
+	handler = middlewares.RequestIDMiddleware(handler)
 	handler = middlewares.CORSMiddleware(handler)
 
 	port := os.Getenv("SERVER_PORT")
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/matching"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/tabular"
+	"flood-relief-system/backend/validation"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxImportRows caps the rows in one bulk import
+const maxImportRows = 5000
+
+// Import modes: save nothing unless every row is valid, or save the valid
+// rows and report the rest
+const (
+	importAllOrNothing = "all-or-nothing"
+	importSkipInvalid  = "skip-invalid"
+)
+
+var (
+	// errInvalidRow rolls back the savepoint of a row that failed validation
+	errInvalidRow = errors.New("invalid row")
+	// errImportRollback undoes a dry run or a failed all-or-nothing import
+	errImportRollback = errors.New("import rolled back")
+)
+
+// bulkEntity describes what can be imported for one kind of record. save
+// validates the row with the same rules as the create handler and creates
+// it; a message means the row is invalid.
+type bulkEntity struct {
+	fields []tabular.Field
+	save   func(tx *gorm.DB, values map[string]interface{}, actor string) (uint, string, error)
+}
+
+// bulkImportRow is the outcome for one row of the file
+type bulkImportRow struct {
+	Line   int      `json:"line"`
+	Status string   `json:"status"` // valid, invalid, created
+	ID     uint     `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// bulkImportReport is the response of a bulk import, dry run or not
+type bulkImportReport struct {
+	DryRun    bool            `json:"dry_run"`
+	Mode      string          `json:"mode"`
+	Committed bool            `json:"committed"`
+	Columns   map[string]int  `json:"columns"` // field → column number, from 1
+	Total     int             `json:"total"`
+	Valid     int             `json:"valid"`
+	Invalid   int             `json:"invalid"`
+	Created   int             `json:"created"`
+	Rows      []bulkImportRow `json:"rows"`
+}
+
+// decodeRow copies the converted cells onto a model through its JSON tags
+func decodeRow(values map[string]interface{}, model interface{}) string {
+	data, err := json.Marshal(values)
+	if err == nil {
+		err = json.Unmarshal(data, model)
+	}
+	if err != nil {
+		return "Row could not be read"
+	}
+	return ""
+}
+
+// rowMessage is the problem of a row from the field errors of the create
+// handler's validation
+func rowMessage(fields []apierror.FieldError) string {
+	return strings.Join(validation.Messages(fields), "; ")
+}
+
+var volunteerImport = bulkEntity{
+	fields: []tabular.Field{
+		{Name: "name"}, {Name: "email"}, {Name: "phone"}, {Name: "skills"},
+		{Name: "availability"}, {Name: "location"}, {Name: "status"},
+	},
+	save: func(tx *gorm.DB, values map[string]interface{}, actor string) (uint, string, error) {
+		var volunteer models.Volunteer
+		if msg := decodeRow(values, &volunteer); msg != "" {
+			return 0, msg, nil
+		}
+		if fields := validateVolunteer(&volunteer); fields != nil {
+			return 0, rowMessage(fields), nil
+		}
+
+		// Checked here so a repeated sign-up is reported rather than failing the import
+		var registered int64
+		if err := tx.Model(&models.Volunteer{}).Where("email = ?", volunteer.Email).Count(&registered).Error; err != nil {
+			return 0, "", err
+		}
+		if registered > 0 {
+			return 0, "A volunteer with this email is already registered", nil
+		}
+
+		if err := tx.Create(&volunteer).Error; err != nil {
+			return 0, "", err
+		}
+		return volunteer.ID, "", nil
+	},
+}
+
+var helpRequestImport = bulkEntity{
+	fields: []tabular.Field{
+		{Name: "name"}, {Name: "phone"}, {Name: "location"}, {Name: "description"},
+		{Name: "priority"}, {Name: "status"},
+	},
+	save: func(tx *gorm.DB, values map[string]interface{}, actor string) (uint, string, error) {
+		var helpRequest models.HelpRequest
+		if msg := decodeRow(values, &helpRequest); msg != "" {
+			return 0, msg, nil
+		}
+		if fields := validateHelpRequest(&helpRequest); fields != nil {
+			return 0, rowMessage(fields), nil
+		}
+		if err := tx.Create(&helpRequest).Error; err != nil {
+			return 0, "", err
+		}
+
+		// Behind its own savepoint, so a failed match does not fail the row
+		person := matching.Person{Name: helpRequest.Name, Phone: helpRequest.Phone}
+		err := tx.Transaction(func(matchTx *gorm.DB) error {
+			return matchNewPerson(matchTx, "help_request", helpRequest.ID, person, helpRequest.PhoneE164)
+		})
+		if err != nil {
+			log.Println("❌ Missing person matching failed:", err)
+		}
+		return helpRequest.ID, "", nil
+	},
+}
+
+var reliefSupplyImport = bulkEntity{
+	fields: []tabular.Field{
+		{Name: "item_name"}, {Name: "category"}, {Name: "quantity", Kind: tabular.Int}, {Name: "unit"},
+		{Name: "donor_id", Kind: tabular.ID}, {Name: "donor_name"}, {Name: "donor_phone"},
+		{Name: "location"}, {Name: "warehouse_id", Kind: tabular.ID}, {Name: "status"},
+		{Name: "expiry_date", Kind: tabular.Date}, {Name: "notes"},
+	},
+	save: func(tx *gorm.DB, values map[string]interface{}, actor string) (uint, string, error) {
+		var supply models.ReliefSupply
+		if msg := decodeRow(values, &supply); msg != "" {
+			return 0, msg, nil
+		}
+		status, msg, fields := prepareReliefSupply(tx, &supply)
+		if fields != nil {
+			return 0, rowMessage(fields), nil
+		}
+		if status == http.StatusInternalServerError {
+			return 0, "", errors.New(msg)
+		}
+		if status != 0 {
+			return 0, msg, nil
+		}
+		if err := createReliefSupply(tx, &supply, actor); err != nil {
+			return 0, "", err
+		}
+		return supply.ID, "", nil
+	},
+}
+
+// bulkImport reads the uploaded spreadsheet and imports its rows. Every row
+// is created inside one transaction, each behind a savepoint, so a dry run
+// checks exactly what a real import would (unique emails, donors, stock
+// ledger) and then rolls everything back.
+func bulkImport(w http.ResponseWriter, r *http.Request, entity bulkEntity) {
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	data, format, err := readImportFile(r)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, "Could not read the uploaded file")
+		return
+	}
+
+	sheet, err := tabular.Read(data, format)
+	if errors.Is(err, tabular.ErrFormat) {
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: csv, xlsx")
+		return
+	}
+	if errors.Is(err, tabular.ErrNoHeader) {
+		apierror.Write(w, http.StatusBadRequest, "The file has no header row")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, "Could not parse the file as "+format)
+		return
+	}
+
+	if len(sheet.Records) == 0 {
+		apierror.Write(w, http.StatusBadRequest, "The file has no rows to import")
+		return
+	}
+	if len(sheet.Records) > maxImportRows {
+		apierror.Write(w, http.StatusBadRequest, "Too many rows; import at most "+strconv.Itoa(maxImportRows)+" at a time")
+		return
+	}
+
+	// The mapping is field → column header, as ?mapping= or a form field
+	mapping := map[string]string{}
+	if raw := r.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid mapping. Send a JSON object of field name to column header")
+			return
+		}
+	}
+	columns, err := sheet.Columns(entity.fields, mapping)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, "Invalid mapping: "+err.Error())
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = importAllOrNothing
+	}
+	if mode != importAllOrNothing && mode != importSkipInvalid {
+		apierror.Write(w, http.StatusBadRequest, "Invalid mode. Use: all-or-nothing, skip-invalid")
+		return
+	}
+
+	report := bulkImportReport{
+		DryRun:  r.URL.Query().Get("dry_run") == "true",
+		Mode:    mode,
+		Columns: map[string]int{},
+		Total:   len(sheet.Records),
+		Rows:    []bulkImportRow{},
+	}
+	for field, position := range columns {
+		report.Columns[field] = position + 1
+	}
+	actor := requestActor(r)
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		for i, record := range sheet.Records {
+			row := bulkImportRow{Line: sheet.Lines[i], Status: "valid"}
+
+			values, problems := tabular.Values(record, entity.fields, columns)
+			row.Errors = problems
+			if len(row.Errors) == 0 {
+				err := tx.Transaction(func(rowTx *gorm.DB) error {
+					id, msg, err := entity.save(rowTx, values, actor)
+					if err != nil {
+						return err
+					}
+					if msg != "" {
+						row.Errors = append(row.Errors, msg)
+						return errInvalidRow
+					}
+					row.ID = id
+					return nil
+				})
+				if err != nil && !errors.Is(err, errInvalidRow) {
+					return err
+				}
+			}
+
+			if len(row.Errors) > 0 {
+				row.Status = "invalid"
+				row.ID = 0
+				report.Invalid++
+			} else {
+				report.Valid++
+			}
+			report.Rows = append(report.Rows, row)
+		}
+
+		if report.DryRun || (mode == importAllOrNothing && report.Invalid > 0) {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to import; nothing was saved")
+		return
+	}
+
+	report.Committed = err == nil
+	for i := range report.Rows {
+		switch {
+		case report.Rows[i].Status != "valid":
+		case report.Committed:
+			report.Rows[i].Status = "created"
+			report.Created++
+		default:
+			// Rolled back, so the IDs were never really taken
+			report.Rows[i].ID = 0
+		}
+	}
+
+	status := http.StatusOK
+	if report.Created > 0 {
+		status = http.StatusCreated
+	} else if !report.DryRun && !report.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+
+}
+
+// ImportVolunteers - POST
+// Registers volunteers from a CSV or XLSX sign-up sheet, sent as the
+// request body or the "file" field of a form. ?mapping= maps fields to the
+// sheet's column headers, e.g. {"name":"Full Name","phone":"Mobile"};
+// unmapped fields use the column of the same name. ?dry_run=true validates
+// without saving; ?mode=skip-invalid saves the valid rows when others fail
+// (the default, all-or-nothing, saves nothing).
+// http://localhost:8081/api/v1/volunteers/import?format=xlsx&dry_run=true
+func ImportVolunteers(w http.ResponseWriter, r *http.Request) {
+	bulkImport(w, r, volunteerImport)
+}
+
+// ImportHelpRequests - POST
+// Same options as ImportVolunteers
+// http://localhost:8081/api/v1/help-requests/import?format=csv&mode=skip-invalid
+func ImportHelpRequests(w http.ResponseWriter, r *http.Request) {
+	bulkImport(w, r, helpRequestImport)
+}
+
+// ImportReliefSupplies - POST
+// Same options as ImportVolunteers. Each row becomes a lot with an initial
+// receipt in the stock ledger, as with a single create.
+// http://localhost:8081/api/v1/relief-supplies/import?format=xlsx
+func ImportReliefSupplies(w http.ResponseWriter, r *http.Request) {
+	bulkImport(w, r, reliefSupplyImport)
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/directory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// maxImportBytes caps the size of an uploaded directory file
+const maxImportBytes = 10 << 20
+
+// writeContacts renders contacts as a vCard or CSV download
+func writeContacts(w http.ResponseWriter, contacts []models.EmergencyContact, format, filename string) {
+
+	var out bytes.Buffer
+	var err error
+	switch format {
+	case "", "vcf", "vcard":
+		err = directory.WriteVCards(&out, contacts)
+		w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.vcf"`)
+	case "csv":
+		err = directory.WriteCSV(&out, contacts)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: vcf, csv")
+		return
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		apierror.Write(w, http.StatusInternalServerError, "Failed to export emergency contacts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+
+}
+
+// ExportEmergencyContacts - GET
+// The directory as vCard 4.0 (default) or CSV.
+// Optional filters: ?service=, ?district=, ?active=true and ?verified=true
+// http://localhost:8081/api/v1/emergency-contacts/export?format=vcf&service=Medical
+func ExportEmergencyContacts(w http.ResponseWriter, r *http.Request) {
+
+	contacts := []models.EmergencyContact{}
+	db := config.GetDB()
+	params := r.URL.Query()
+
+	query := db.Order("organization_name ASC")
+	if service := params.Get("service"); service != "" {
+		query = query.Where("service_type = ?", service)
+	}
+	if district := params.Get("district"); district != "" {
+		query = query.Where("district = ?", district)
+	}
+	if params.Get("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+	if params.Get("verified") == "true" {
+		query = query.Where("verification_status = ?", "verified")
+	}
+
+	if err := query.Find(&contacts).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to retrieve emergency contacts")
+		return
+	}
+
+	writeContacts(w, contacts, params.Get("format"), "emergency-contacts")
+
+}
+
+// GetEmergencyContactVCard - GET
+// One contact as a vCard 4.0 file
+// http://localhost:8081/api/v1/emergency-contacts/{id}/vcard
+func GetEmergencyContactVCard(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var contact models.EmergencyContact
+	if err := config.GetDB().First(&contact, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Emergency contact not found")
+		return
+	}
+
+	writeContacts(w, []models.EmergencyContact{contact}, "vcf", "emergency-contact-"+strconv.Itoa(id))
+
+}
+
+// importRow is the outcome for one contact in an import file
+type importRow struct {
+	Line             int      `json:"line"`
+	OrganizationName string   `json:"organization_name"`
+	Phone            string   `json:"phone"`
+	Status           string   `json:"status"` // valid, invalid, duplicate, created
+	Errors           []string `json:"errors,omitempty"`
+	DuplicateOf      []uint   `json:"duplicate_of,omitempty"`      // existing contacts
+	DuplicateOfLine  int      `json:"duplicate_of_line,omitempty"` // earlier row in the same file
+}
+
+// importReport is the response of an import, dry run or not
+type importReport struct {
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Valid      int         `json:"valid"`
+	Invalid    int         `json:"invalid"`
+	Duplicates int         `json:"duplicates"`
+	Created    int         `json:"created"`
+	Rows       []importRow `json:"rows"`
+}
+
+// contactKeys are the ways a contact is recognised as one already known:
+// the same phone number, or the same organization for the same service
+// and district
+func contactKeys(c *models.EmergencyContact) []string {
+	keys := []string{}
+	for _, number := range []string{c.Phone, c.AlternatePhone} {
+		if key := phone.Key(number); key != "" {
+			keys = append(keys, "phone:"+key)
+		}
+	}
+	keys = append(keys, "org:"+strings.ToLower(strings.Join([]string{
+		strings.TrimSpace(c.OrganizationName), c.ServiceType, strings.TrimSpace(c.District),
+	}, "|")))
+	return keys
+}
+
+// readImportFile returns the uploaded file and its format, from a
+// multipart "file" field or the raw request body. The format comes from
+// ?format=, then the file name, then the content type.
+func readImportFile(r *http.Request) ([]byte, string, error) {
+	format := r.URL.Query().Get("format")
+	contentType := r.Header.Get("Content-Type")
+
+	var source io.Reader = r.Body
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		source = file
+		contentType = header.Header.Get("Content-Type")
+		name := strings.ToLower(header.Filename)
+		switch {
+		case format != "":
+		case strings.HasSuffix(name, ".vcf"):
+			format = "vcf"
+		case strings.HasSuffix(name, ".xlsx"):
+			format = "xlsx"
+		}
+	}
+
+	if format == "" {
+		format = "csv"
+		if strings.Contains(contentType, "vcard") {
+			format = "vcf"
+		} else if strings.Contains(contentType, "spreadsheetml") {
+			format = "xlsx"
+		}
+	}
+
+	data, err := io.ReadAll(source)
+	return data, format, err
+}
+
+// ImportEmergencyContacts - POST
+// Loads contacts from a CSV or vCard file, sent as the request body or as
+// the "file" field of a form. With ?dry_run=true nothing is saved and the
+// report shows what would happen. Invalid rows and likely duplicates are
+// skipped; ?allow_duplicates=true imports duplicates anyway.
+// http://localhost:8081/api/v1/emergency-contacts/import?format=csv&dry_run=true
+func ImportEmergencyContacts(w http.ResponseWriter, r *http.Request) {
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	data, format, err := readImportFile(r)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, "Could not read the uploaded file")
+		return
+	}
+
+	var cards []directory.Card
+	switch format {
+	case "csv":
+		cards, err = directory.ReadCSV(bytes.NewReader(data))
+	case "vcf", "vcard":
+		cards, err = directory.ReadVCards(bytes.NewReader(data))
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: csv, vcf")
+		return
+	}
+	if errors.Is(err, directory.ErrMissingColumns) {
+		apierror.Write(w, http.StatusBadRequest, "CSV needs organization_name and phone columns")
+		return
+	}
+	if errors.Is(err, directory.ErrNoCards) {
+		apierror.Write(w, http.StatusBadRequest, "No vCards found in the file")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, "Could not parse the file as "+format)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	allowDuplicates := r.URL.Query().Get("allow_duplicates") == "true"
+	db := config.GetDB()
+
+	var existing []models.EmergencyContact
+	if err := db.Find(&existing).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to check for duplicate contacts")
+		return
+	}
+	known := map[string][]uint{}
+	for i := range existing {
+		for _, key := range contactKeys(&existing[i]) {
+			known[key] = append(known[key], existing[i].ID)
+		}
+	}
+
+	report := importReport{DryRun: dryRun, Total: len(cards), Rows: []importRow{}}
+	seenInFile := map[string]int{}
+	toCreate := []int{} // indexes into cards and report.Rows
+
+	for i := range cards {
+		contact := &cards[i].Contact
+		row := importRow{Line: cards[i].Line, OrganizationName: contact.OrganizationName, Phone: contact.Phone, Status: "valid"}
+
+		if cards[i].Problem != "" {
+			row.Errors = append(row.Errors, cards[i].Problem)
+		}
+		if fields := validateEmergencyContact(contact); fields != nil {
+			row.Errors = append(row.Errors, validation.Messages(fields)...)
+		}
+
+		ids := map[uint]bool{}
+		for _, key := range contactKeys(contact) {
+			for _, id := range known[key] {
+				if !ids[id] {
+					ids[id] = true
+					row.DuplicateOf = append(row.DuplicateOf, id)
+				}
+			}
+			if line, ok := seenInFile[key]; ok && row.DuplicateOfLine == 0 {
+				row.DuplicateOfLine = line
+			}
+		}
+		for _, key := range contactKeys(contact) {
+			if _, ok := seenInFile[key]; !ok {
+				seenInFile[key] = row.Line
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Status = "invalid"
+			report.Invalid++
+		case len(row.DuplicateOf) > 0 || row.DuplicateOfLine != 0:
+			row.Status = "duplicate"
+			report.Duplicates++
+			if allowDuplicates {
+				toCreate = append(toCreate, i)
+			}
+		default:
+			report.Valid++
+			toCreate = append(toCreate, i)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if !dryRun && len(toCreate) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, i := range toCreate {
+				contact := &cards[i].Contact
+				active := contact.IsActive
+				contact.VerificationStatus = "unverified"
+				if err := tx.Create(contact).Error; err != nil {
+					return err
+				}
+				// is_active defaults to true in the database, so false has to be written separately
+				if !active {
+					if err := tx.Model(contact).Update("is_active", false).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			apierror.Write(w, http.StatusInternalServerError, "Failed to import emergency contacts; nothing was saved")
+			return
+		}
+
+		for _, i := range toCreate {
+			report.Rows[i].Status = "created"
+			report.Created++
+		}
+	}
+
+	status := http.StatusOK
+	if report.Created > 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/jobs"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// verificationRequest is the body of the verify and unreachable endpoints
+type verificationRequest struct {
+	Method     string `json:"method" validate:"required,oneof=call sms email in-person website"`
+	VerifiedBy string `json:"verified_by"`
+	Notes      string `json:"notes"`
+}
+
+// recordVerification applies the outcome of a verification attempt to a contact
+func recordVerification(w http.ResponseWriter, r *http.Request, reached bool) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var contact models.EmergencyContact
+	db := config.GetDB()
+
+	if err := db.First(&contact, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Emergency contact not found")
+		return
+	}
+
+	var body verificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if body.VerifiedBy == "" {
+		body.VerifiedBy = requestActor(r)
+	}
+
+	now := time.Now()
+	contact.VerifiedBy = body.VerifiedBy
+	contact.VerificationMethod = body.Method
+	contact.VerificationNotes = body.Notes
+	if reached {
+		contact.VerificationStatus = "verified"
+		contact.LastVerifiedAt = &now
+		contact.UnreachableAt = nil
+	} else {
+		// Keep the last good verification date so the queue shows how long it has been
+		contact.VerificationStatus = "unreachable"
+		contact.UnreachableAt = &now
+	}
+
+	if err := db.Save(&contact).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to record verification")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contact)
+
+}
+
+// VerifyEmergencyContact - POST
+// Records that someone reached the service on the contact's number
+// {"method":"call","verified_by":"Nimal (EOC desk)","notes":"Duty officer answered"}
+// http://localhost:8081/api/v1/emergency-contacts/{id}/verify
+func VerifyEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	recordVerification(w, r, true)
+}
+
+// MarkContactUnreachable - POST
+// Records a failed verification; unreachable contacts are skipped by the
+// on-call lookup and escalations until verified again
+// {"method":"call","notes":"Number not in service"}
+// http://localhost:8081/api/v1/emergency-contacts/{id}/unreachable
+func MarkContactUnreachable(w http.ResponseWriter, r *http.Request) {
+	recordVerification(w, r, false)
+}
+
+// GetVerificationDueContacts - GET
+// Active contacts to verify: unreachable first, then never verified, then
+// the longest since verification. Optional filters: ?service= and ?district=
+// http://localhost:8081/api/v1/emergency-contacts/verification-due
+func GetVerificationDueContacts(w http.ResponseWriter, r *http.Request) {
+
+	contacts := []models.EmergencyContact{}
+	db := config.GetDB()
+
+	// Stale verified contacts are included even before the job has flagged them
+	cutoff := time.Now().AddDate(0, 0, -jobs.VerificationDays())
+	query := db.Where("is_active = ?", true).
+		Where("(verification_status <> ? OR last_verified_at IS NULL OR last_verified_at < ?)", "verified", cutoff).
+		Order("CASE verification_status WHEN 'unreachable' THEN 0 ELSE 1 END").
+		Order("last_verified_at ASC NULLS FIRST").
+		Order("organization_name ASC")
+	if service := r.URL.Query().Get("service"); service != "" {
+		query = query.Where("service_type = ?", service)
+	}
+	if district := r.URL.Query().Get("district"); district != "" {
+		query = query.Where("district = ?", district)
+	}
+
+	if err := query.Find(&contacts).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch contacts due for verification")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contacts)
+
+}
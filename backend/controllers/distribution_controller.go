@@ -0,0 +1,528 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/entitlements"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateDistributionEvent - POST
+// http://localhost:8081/api/v1/distribution-events
+func CreateDistributionEvent(w http.ResponseWriter, r *http.Request) {
+
+	var event models.DistributionEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	db := config.GetDB()
+
+	// Events run from a warehouse take its name as their location
+	if event.WarehouseID != nil {
+		var warehouse models.Warehouse
+		if err := db.First(&warehouse, *event.WarehouseID).Error; err != nil || !warehouse.IsActive {
+			apierror.Write(w, http.StatusBadRequest, "Warehouse not found or not active")
+			return
+		}
+		if event.Location == "" {
+			event.Location = warehouse.Name
+		}
+	}
+
+	if fields := validation.Struct(&event); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if event.EventDate.IsZero() {
+		event.EventDate = time.Now()
+	}
+	event.Status = "open"
+	event.ClosedAt = nil
+	if event.CreatedBy == "" {
+		event.CreatedBy = requestActor(r)
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create distribution event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(event)
+
+}
+
+// GetAllDistributionEvents - GET
+// Optional filter: ?status=open
+func GetAllDistributionEvents(w http.ResponseWriter, r *http.Request) {
+
+	events := []models.DistributionEvent{}
+	db := config.GetDB()
+
+	query := db.Order("event_date DESC")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if exportList(w, r, query, &models.DistributionEvent{}, "distribution-events") {
+		return
+	}
+
+	if err := query.Find(&events).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch distribution events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+
+}
+
+// GetDistributionEventByID - GET
+// The event with totals per item and the number of households served
+func GetDistributionEventByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var event models.DistributionEvent
+	db := config.GetDB()
+
+	if err := db.First(&event, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Distribution event not found")
+		return
+	}
+
+	type itemTotal struct {
+		ItemName string `json:"item_name"`
+		Unit     string `json:"unit"`
+		Quantity int    `json:"quantity"`
+	}
+	totals := []itemTotal{}
+	db.Model(&models.Distribution{}).
+		Select("item_name, unit, SUM(quantity) AS quantity").
+		Where("distribution_event_id = ?", event.ID).
+		Group("item_name, unit").
+		Order("item_name").
+		Scan(&totals)
+
+	var households int64
+	db.Model(&models.Distribution{}).Where("distribution_event_id = ?", event.ID).
+		Distinct("household_id").Count(&households)
+
+	response := map[string]interface{}{
+		"event":      event,
+		"households": households,
+		"totals":     totals,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// CloseDistributionEvent - POST
+// No more handouts can be recorded once an event is closed
+func CloseDistributionEvent(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var event models.DistributionEvent
+	db := config.GetDB()
+
+	if err := db.First(&event, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Distribution event not found")
+		return
+	}
+
+	if event.Status == "closed" {
+		apierror.Write(w, http.StatusConflict, "Distribution event is already closed")
+		return
+	}
+
+	now := time.Now()
+	event.Status = "closed"
+	event.ClosedAt = &now
+	if err := db.Save(&event).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to close distribution event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(event)
+
+}
+
+// handoutRequest is the body of POST /distribution-events/{id}/distributions
+type handoutRequest struct {
+	HouseholdID uint                `json:"household_id" validate:"required"`
+	Items       []entitlements.Item `json:"items" validate:"required,dive"`
+}
+
+// CreateDistribution - POST
+// Records the items handed to a household. Items that break a block rule
+// refuse the whole handout with 409 and the violations; warn rules let it
+// through with warnings. Events tied to a warehouse issue the items from
+// its stock first-expiry-first-out.
+// http://localhost:8081/api/v1/distribution-events/{id}/distributions
+func CreateDistribution(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body handoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	var event models.DistributionEvent
+	db := config.GetDB()
+
+	if err := db.First(&event, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Distribution event not found")
+		return
+	}
+	if event.Status != "open" {
+		apierror.Write(w, http.StatusConflict, "Distribution event is closed")
+		return
+	}
+
+	var household models.Household
+	if err := db.Preload("Members").First(&household, body.HouseholdID).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Household not found")
+		return
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+	for i := range body.Items {
+		item := &body.Items[i]
+		unit, err := catalog.Normalize(item.Unit)
+		if err != nil {
+			apierror.InvalidField(w, fmt.Sprintf("items[%d].unit", i), "oneof", "Unknown unit. Use one of: "+strings.Join(catalog.Codes(), ", "))
+			return
+		}
+		item.Unit = unit.Code
+	}
+
+	var status int
+	var msg string
+	var blocked bool
+	var violations []entitlements.Violation
+	distributions := []models.Distribution{}
+	actor := requestActor(r)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the household so two counters cannot both hand out its last entitlement
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Household{}, household.ID).Error; err != nil {
+			return err
+		}
+
+		found, err := entitlements.Check(tx, &household, body.Items)
+		if err != nil {
+			return err
+		}
+		violations = found
+		if entitlements.Blocking(violations) {
+			blocked = true
+			return gorm.ErrInvalidData
+		}
+
+		reference := fmt.Sprintf("distribution event #%d", event.ID)
+		for _, item := range body.Items {
+			if event.WarehouseID != nil {
+				reason := fmt.Sprintf("Handed to household #%d", household.ID)
+				_, short, err := inventory.IssueLots(tx, item.ItemName, item.Unit, event.WarehouseID, item.Quantity, actor, reason, reference)
+				if short > 0 || err == inventory.ErrNegativeStock {
+					status, msg = http.StatusConflict, "Not enough "+item.ItemName+" in the event warehouse"
+					return inventory.ErrNegativeStock
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			distribution := models.Distribution{
+				DistributionEventID: event.ID,
+				HouseholdID:         household.ID,
+				ItemName:            item.ItemName,
+				Quantity:            item.Quantity,
+				Unit:                item.Unit,
+				Actor:               actor,
+			}
+			for _, v := range violations {
+				if strings.EqualFold(v.ItemName, item.ItemName) && v.Unit == item.Unit {
+					distribution.Warning = v.Message
+				}
+			}
+			if err := tx.Create(&distribution).Error; err != nil {
+				return err
+			}
+			distributions = append(distributions, distribution)
+		}
+		return nil
+	})
+	if err != nil {
+		if blocked {
+			// Over-collection: send the violations so the counter can explain
+			apierror.WriteBody(w, http.StatusConflict, struct {
+				apierror.Body
+				Violations []entitlements.Violation `json:"violations"`
+			}{apierror.New(w, apierror.Conflict, "Household has already received its entitlement"), violations})
+			return
+		}
+		if status != 0 {
+			apierror.Write(w, status, msg)
+			return
+		}
+		status, msg := ledgerError(err)
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	response := map[string]interface{}{
+		"distributions": distributions,
+		"warnings":      violations,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetEventDistributions - GET
+func GetEventDistributions(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	distributions := []models.Distribution{}
+	db := config.GetDB()
+
+	if err := db.Where("distribution_event_id = ?", id).Order("created_at DESC").Find(&distributions).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch distributions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(distributions)
+
+}
+
+// validateEntitlementRule stores the catalogue unit code on a rule whose
+// fields have been checked
+func validateEntitlementRule(db *gorm.DB, rule *models.EntitlementRule) (int, string) {
+	unit, status, msg := normalizeUnit(db, rule.Unit)
+	if status != 0 {
+		return status, msg
+	}
+	rule.Unit = unit
+
+	return 0, ""
+}
+
+// CreateEntitlementRule - POST
+// {"item_name":"Family Dry Ration Kit","unit":"kit","quantity":1,"period_days":7,"mode":"block"}
+// http://localhost:8081/api/v1/entitlement-rules
+func CreateEntitlementRule(w http.ResponseWriter, r *http.Request) {
+
+	var rule models.EntitlementRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if rule.Mode == "" {
+		rule.Mode = "block"
+	}
+
+	if fields := validation.Struct(&rule); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	if status, msg := validateEntitlementRule(db, &rule); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	rule.IsActive = true
+	if err := db.Create(&rule).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create entitlement rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+
+}
+
+// GetEntitlementRules - GET
+func GetEntitlementRules(w http.ResponseWriter, r *http.Request) {
+
+	rules := []models.EntitlementRule{}
+	db := config.GetDB()
+
+	query := db.Order("item_name, id")
+	if exportList(w, r, query, &models.EntitlementRule{}, "entitlement-rules") {
+		return
+	}
+
+	if err := query.Find(&rules).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch entitlement rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rules)
+
+}
+
+// UpdateEntitlementRule - PUT
+func UpdateEntitlementRule(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var rule models.EntitlementRule
+	db := config.GetDB()
+
+	if err := db.First(&rule, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Entitlement rule not found")
+		return
+	}
+
+	// Decode into a map as well so per_member and is_active can be set to false
+	var updateData models.EntitlementRule
+	var fields map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &updateData) != nil || json.Unmarshal(raw, &fields) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.ItemName != "" {
+		rule.ItemName = updateData.ItemName
+	}
+	if updateData.Unit != "" {
+		rule.Unit = updateData.Unit
+	}
+	if updateData.Quantity != 0 {
+		rule.Quantity = updateData.Quantity
+	}
+	if updateData.PeriodDays != 0 {
+		rule.PeriodDays = updateData.PeriodDays
+	}
+	if updateData.Mode != "" {
+		rule.Mode = updateData.Mode
+	}
+	if _, ok := fields["per_member"]; ok {
+		rule.PerMember = updateData.PerMember
+	}
+	if _, ok := fields["is_active"]; ok {
+		rule.IsActive = updateData.IsActive
+	}
+
+	if invalid := validation.Struct(&rule); invalid != nil {
+		apierror.Invalid(w, invalid)
+		return
+	}
+
+	if status, msg := validateEntitlementRule(db, &rule); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Save(&rule).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update entitlement rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rule)
+
+}
+
+// DeleteEntitlementRule - DELETE
+func DeleteEntitlementRule(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	db := config.GetDB()
+	result := db.Delete(&models.EntitlementRule{}, id)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete entitlement rule")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Write(w, http.StatusNotFound, "Entitlement rule not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Entitlement rule deleted successfully"}`))
+
+}
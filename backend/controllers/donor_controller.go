@@ -0,0 +1,517 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/donors"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/receipts"
+	"flood-relief-system/backend/sequence"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// linkDonor points a supply at its donor record. A donor_id wins; otherwise
+// the donor is found or created from the name and phone on the supply. The
+// donor's name and phone are copied onto the supply either way.
+func linkDonor(db *gorm.DB, supply *models.ReliefSupply) (int, string) {
+	if supply.DonorID == nil && supply.DonorPhone != "" {
+		number, msg := phone.Check(supply.DonorPhone, false)
+		if msg != "" {
+			return http.StatusBadRequest, msg
+		}
+		supply.DonorPhone = number.Display
+	}
+
+	var donor *models.Donor
+	if supply.DonorID != nil {
+		donor = &models.Donor{}
+		if err := db.First(donor, *supply.DonorID).Error; err != nil {
+			return http.StatusBadRequest, "Donor not found"
+		}
+	} else if supply.DonorName != "" || supply.DonorPhone != "" {
+		found, err := donors.FindOrCreate(db, supply.DonorName, supply.DonorPhone)
+		if err != nil {
+			return http.StatusInternalServerError, "Failed to record donor"
+		}
+		donor = found
+	} else {
+		return 0, ""
+	}
+
+	supply.DonorID = &donor.ID
+	supply.DonorName = donor.Name
+	supply.DonorPhone = donor.Phone
+	supply.DonorPhoneE164 = donor.PhoneE164
+	return 0, ""
+}
+
+// CreateDonor - POST
+// http://localhost:8081/api/v1/donors
+func CreateDonor(w http.ResponseWriter, r *http.Request) {
+
+	var donor models.Donor
+	if err := json.NewDecoder(r.Body).Decode(&donor); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if donor.Type == "" {
+		donor.Type = "individual"
+	}
+	if donor.PreferredLanguage == "" {
+		donor.PreferredLanguage = "en"
+	}
+	donor.PhoneE164 = ""
+	if donor.Phone != "" {
+		number, fields := phone.Field("phone", donor.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		donor.Phone, donor.PhoneE164 = number.Display, number.E164
+	}
+
+	if fields := validation.Struct(&donor); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	if err := db.Create(&donor).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create donor")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(donor)
+
+}
+
+// GetAllDonors - GET
+// Optional filters: ?type=organization and ?q= (name or phone)
+func GetAllDonors(w http.ResponseWriter, r *http.Request) {
+
+	donorList := []models.Donor{}
+	db := config.GetDB()
+
+	query := db.Order("name ASC")
+	if donorType := r.URL.Query().Get("type"); donorType != "" {
+		query = query.Where("type = ?", donorType)
+	}
+	if q := r.URL.Query().Get("q"); q != "" {
+		phoneWhere, phoneArg := phoneSearch(q)
+		query = query.Where("name ILIKE ? OR "+phoneWhere, "%"+q+"%", phoneArg)
+	}
+
+	if exportList(w, r, query, &models.Donor{}, "donors") {
+		return
+	}
+
+	if err := query.Find(&donorList).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch donors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(donorList)
+
+}
+
+// GetDonorByID - GET
+func GetDonorByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var donor models.Donor
+	db := config.GetDB()
+
+	if err := db.First(&donor, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(donor)
+
+}
+
+// UpdateDonor - PUT
+// Name and phone changes are copied onto the donor's supplies
+func UpdateDonor(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var donor models.Donor
+	db := config.GetDB()
+
+	if err := db.First(&donor, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	var updateData models.Donor
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.Name != "" {
+		donor.Name = updateData.Name
+	}
+	if updateData.Type != "" {
+		donor.Type = updateData.Type
+	}
+	if updateData.ContactPerson != "" {
+		donor.ContactPerson = updateData.ContactPerson
+	}
+	if updateData.Phone != "" {
+		number, fields := phone.Field("phone", updateData.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		donor.Phone, donor.PhoneE164 = number.Display, number.E164
+	}
+	if updateData.Email != "" {
+		donor.Email = updateData.Email
+	}
+	if updateData.Address != "" {
+		donor.Address = updateData.Address
+	}
+	if updateData.PreferredLanguage != "" {
+		donor.PreferredLanguage = updateData.PreferredLanguage
+	}
+	if updateData.Notes != "" {
+		donor.Notes = updateData.Notes
+	}
+
+	if fields := validation.Struct(&donor); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&donor).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ReliefSupply{}).Where("donor_id = ?", donor.ID).
+			Updates(map[string]interface{}{"donor_name": donor.Name, "donor_phone": donor.Phone, "donor_phone_e164": donor.PhoneE164}).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update donor")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(donor)
+
+}
+
+// DeleteDonor - DELETE
+// Donors with supplies or receipts are kept for the donation history
+func DeleteDonor(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var donor models.Donor
+	db := config.GetDB()
+
+	if err := db.First(&donor, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	var supplies, receiptCount int64
+	db.Model(&models.ReliefSupply{}).Where("donor_id = ?", donor.ID).Count(&supplies)
+	db.Model(&models.DonationReceipt{}).Where("donor_id = ?", donor.ID).Count(&receiptCount)
+	if supplies+receiptCount > 0 {
+		apierror.Write(w, http.StatusConflict, "Donor has donations on record and cannot be deleted")
+		return
+	}
+
+	if err := db.Delete(&donor).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete donor")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Donor deleted successfully"}`))
+
+}
+
+// GetDonorDonations - GET
+// Donation history of a donor with totals per item and per category
+// http://localhost:8081/api/v1/donors/{id}/donations
+func GetDonorDonations(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var donor models.Donor
+	db := config.GetDB()
+
+	if err := db.First(&donor, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	donations, err := donors.Donations(db, donor.ID, nil)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch donations")
+		return
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	response := map[string]interface{}{
+		"donor":     donor,
+		"totals":    donors.Summarize(donations, catalog),
+		"donations": donations,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// receiptRequest is the body of POST /donors/{id}/receipts
+type receiptRequest struct {
+	SupplyIDs []uint `json:"supply_ids"`                         // defaults to every donation without a receipt
+	Language  string `json:"language" validate:"oneof=en si ta"` // defaults to the donor's preferred language
+}
+
+// CreateDonationReceipt - POST
+// Issues the next numbered receipt for donations not yet acknowledged
+// http://localhost:8081/api/v1/donors/{id}/receipts
+func CreateDonationReceipt(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	// The body is optional
+	var body receiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	var donor models.Donor
+	db := config.GetDB()
+
+	if err := db.First(&donor, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	if body.Language == "" {
+		body.Language = donor.PreferredLanguage
+	}
+	if !receipts.IsLanguage(body.Language) {
+		apierror.Write(w, http.StatusBadRequest, "Invalid language. Use: en, si, ta")
+		return
+	}
+
+	var status int
+	var msg string
+	receipt := models.DonationReceipt{
+		DonorID:  donor.ID,
+		Language: body.Language,
+		IssuedBy: requestActor(r),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		donations, err := donors.Donations(tx, donor.ID, body.SupplyIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range donations {
+			if d.ReceiptNumber != "" {
+				if len(body.SupplyIDs) > 0 {
+					status, msg = http.StatusConflict, "Supply "+strconv.Itoa(int(d.ReliefSupplyID))+" is already on receipt "+d.ReceiptNumber
+					return gorm.ErrInvalidData
+				}
+				continue
+			}
+			receipt.Lines = append(receipt.Lines, models.DonationReceiptLine{
+				ReliefSupplyID: d.ReliefSupplyID,
+				ItemName:       d.ItemName,
+				Quantity:       d.Quantity,
+				Unit:           d.Unit,
+				ReceivedAt:     d.ReceivedAt,
+			})
+		}
+
+		if len(body.SupplyIDs) > 0 && len(donations) != len(body.SupplyIDs) {
+			status, msg = http.StatusBadRequest, "Some supplies are not donations from this donor"
+			return gorm.ErrInvalidData
+		}
+		if len(receipt.Lines) == 0 {
+			status, msg = http.StatusConflict, "No donations without a receipt"
+			return gorm.ErrInvalidData
+		}
+
+		year := time.Now().Year()
+		n, err := sequence.Next(tx, receipts.NumberSeries(year))
+		if err != nil {
+			return err
+		}
+		receipt.Number = receipts.FormatNumber(year, n)
+
+		return tx.Create(&receipt).Error
+	})
+	if err != nil {
+		if status != 0 {
+			apierror.Write(w, status, msg)
+			return
+		}
+		apierror.Write(w, http.StatusInternalServerError, "Failed to issue receipt")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+
+}
+
+// GetDonorReceipts - GET
+func GetDonorReceipts(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	receiptList := []models.DonationReceipt{}
+	db := config.GetDB()
+
+	if err := db.Preload("Lines").Where("donor_id = ?", id).Order("created_at DESC").Find(&receiptList).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch receipts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receiptList)
+
+}
+
+// GetDonationReceipt - GET
+// ?format=json (default), html or pdf; ?lang= prints a copy in another
+// language without changing the receipt. PDF copies are English only.
+// http://localhost:8081/api/v1/receipts/{id}?format=html&lang=si
+func GetDonationReceipt(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var receipt models.DonationReceipt
+	db := config.GetDB()
+
+	if err := db.Preload("Lines").First(&receipt, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Receipt not found")
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = receipt.Language
+	}
+	if !receipts.IsLanguage(lang) {
+		apierror.Write(w, http.StatusBadRequest, "Invalid language. Use: en, si, ta")
+		return
+	}
+
+	var donor models.Donor
+	if err := db.First(&donor, receipt.DonorID).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Donor not found")
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as JSON
+	var out bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(receipt)
+		return
+	case "html":
+		err = receipts.HTML(&out, &receipt, &donor, lang)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case "pdf":
+		err = receipts.PDF(&out, &receipt, &donor, lang)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+receipt.Number+`.pdf"`)
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: json, html, pdf")
+		return
+	}
+
+	if errors.Is(err, receipts.ErrPDFLanguage) {
+		w.Header().Del("Content-Disposition")
+		apierror.Write(w, http.StatusNotImplemented, "PDF receipts are only available in English. Use format=html for Sinhala and Tamil")
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		apierror.Write(w, http.StatusInternalServerError, "Failed to render receipt")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+
+}
@@ -2,14 +2,41 @@ package controllers
 
 import (
 	"encoding/json"
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/jobs"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// validateEmergencyContact checks the required fields and service type of a
+// contact and normalizes its phone number; used by create and by directory
+// imports. Hotline short codes such as 1990 are accepted.
+func validateEmergencyContact(contact *models.EmergencyContact) []apierror.FieldError {
+	if fields := validation.Struct(contact); fields != nil {
+		return fields
+	}
+
+	if len(contact.OrganizationName) > 100 {
+		return []apierror.FieldError{validation.Field("organization_name", "invalid", "Organization Name is too long")}
+	}
+
+	number, fields := phone.Field("phone", contact.Phone, true)
+	if fields != nil {
+		return fields
+	}
+	contact.Phone, contact.PhoneE164 = number.Display, number.E164
+
+	return nil
+}
+
 // CreateEmergencyContact - POST
 // http://localhost:8081/api/v1/emergency-contact
 func CreateEmergencyContact(w http.ResponseWriter, r *http.Request) {
@@ -19,30 +46,12 @@ func CreateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	// Decode
 	err := json.NewDecoder(r.Body).Decode(&contact)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
-		return
-	}
-
-	//Validation OrganizationName & Phone are required
-
-	if contact.OrganizationName == "" || contact.Phone == "" {
-		http.Error(w, `{"error":"Organization Name & Phone are required fields"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
 		return
 	}
 
-	// Validation ServiceType
-	validServiceTypes := []string{"Medical", "Rescue", "Food", "Shelter", "Plice", "Fire", "Other"}
-
-	isValidServiceType := false
-	for _, service := range validServiceTypes {
-		if contact.ServiceType == service {
-			isValidServiceType = true
-			break
-		}
-	}
-
-	if contact.ServiceType != "" && !isValidServiceType {
-		http.Error(w, `{"error":"Invalid Service Type"}`, http.StatusBadRequest)
+	if fields := validateEmergencyContact(&contact); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -51,11 +60,18 @@ func CreateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 		contact.IsActive = true
 	}
 
+	// New numbers start unverified; use the verify endpoint once someone has rung them
+	contact.VerificationStatus = "unverified"
+	contact.LastVerifiedAt = nil
+	contact.VerifiedBy = ""
+	contact.VerificationMethod = ""
+	contact.UnreachableAt = nil
+
 	// Save to DB
 	db := config.GetDB()
 	result := db.Create(&contact)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to create emergency contact"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create emergency contact")
 		return
 	}
 
@@ -72,9 +88,14 @@ func GetAllEmergencyContacts(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 
 	// Fetch all
-	result := db.Order("organization_name ASC").Find(&contacts)
+	query := db.Order("organization_name ASC")
+	if exportList(w, r, query, &models.EmergencyContact{}, "emergency-contacts") {
+		return
+	}
+
+	result := query.Find(&contacts)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to retrieve emergency contacts"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to retrieve emergency contacts")
 		return
 	}
 
@@ -100,7 +121,7 @@ func GetEmergencyContactByID(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -110,7 +131,7 @@ func GetEmergencyContactByID(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&contact, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Emergency contact not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Emergency contact not found")
 		return
 	}
 
@@ -128,7 +149,7 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -138,7 +159,7 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&existingContact, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Emergency contact not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Emergency contact not found")
 		return
 	}
 
@@ -146,7 +167,13 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	var updateData models.EmergencyContact
 	err = json.NewDecoder(r.Body).Decode(&updateData)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&updateData); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -163,7 +190,18 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 
 	if updateData.Phone != "" {
 
-		existingContact.Phone = updateData.Phone
+		number, fields := phone.Field("phone", updateData.Phone, true)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+
+		if number.Display != existingContact.Phone {
+			// A new number has not been verified yet
+			existingContact.VerificationStatus = "unverified"
+			existingContact.UnreachableAt = nil
+		}
+		existingContact.Phone, existingContact.PhoneE164 = number.Display, number.E164
 	}
 
 	if updateData.AlternatePhone != "" {
@@ -181,21 +219,12 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 		existingContact.Address = updateData.Address
 	}
 
-	if updateData.ServiceType != "" {
-		validServiceTypes := []string{"Medical", "Rescue", "Food", "Shelter", "Police", "Fire", "Other"}
-		isValid := false
-		for _, service := range validServiceTypes {
-			if updateData.ServiceType == service {
-				isValid = true
-				break
-			}
-		}
+	if updateData.District != "" {
 
-		if !isValid {
-			http.Error(w, `{"error":"Invalid Service type"}`, http.StatusBadRequest)
-			return
-		}
+		existingContact.District = updateData.District
+	}
 
+	if updateData.ServiceType != "" {
 		existingContact.ServiceType = updateData.ServiceType
 	}
 
@@ -204,7 +233,7 @@ func UpdateEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	// Save updated contact
 	result = db.Save(&existingContact)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Faild to update emergency contact data"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Faild to update emergency contact data")
 		return
 	}
 
@@ -222,7 +251,7 @@ func DeleteEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -232,14 +261,14 @@ func DeleteEmergencyContact(w http.ResponseWriter, r *http.Request) {
 	result := db.First(&contact, id)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Emergency contact not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Emergency contact not found")
 		return
 	}
 
 	//Delete contact
 	result = db.Delete(&contact)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to delete emergency contact"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete emergency contact")
 		return
 	}
 
@@ -251,13 +280,25 @@ func DeleteEmergencyContact(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetActiveContacts - GET
+// With ?verified=true only contacts verified within CONTACT_VERIFICATION_DAYS are listed
 func GetActiveContacts(w http.ResponseWriter, r *http.Request) {
 	var contacts []models.EmergencyContact
 	db := config.GetDB()
 
-	result := db.Where("is_active = ?", true).Order("organization_name ASC").Find(&contacts)
+	query := db.Where("is_active = ?", true)
+	if r.URL.Query().Get("verified") == "true" {
+		cutoff := time.Now().AddDate(0, 0, -jobs.VerificationDays())
+		query = query.Where("verification_status = ? AND last_verified_at >= ?", "verified", cutoff)
+	}
+
+	query = query.Order("organization_name ASC")
+	if exportList(w, r, query, &models.EmergencyContact{}, "active-emergency-contacts") {
+		return
+	}
+
+	result := query.Find(&contacts)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to retrive active emergency contacts"}`, http.StatusBadRequest)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to retrieve active emergency contacts")
 		return
 	}
 
@@ -295,7 +336,7 @@ func GetContactsByServiceType(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !isValid {
-		http.Error(w, `{"Error","Invalid  Service Type"}`, http.StatusBadRequest)
+		apierror.InvalidField(w, "service_type", "oneof", "service_type must be one of: "+strings.Join(validServiceTypes, ", "))
 		return
 
 	}
@@ -304,10 +345,15 @@ func GetContactsByServiceType(w http.ResponseWriter, r *http.Request) {
 	var contacts []models.EmergencyContact
 	db := config.GetDB()
 
-	result := db.Where("service_type = ? AND is_active = ?", serviceType, true).Order("organization_name ASC").Find(&contacts)
+	query := db.Where("service_type = ? AND is_active = ?", serviceType, true).Order("organization_name ASC")
+	if exportList(w, r, query, &models.EmergencyContact{}, strings.ToLower(serviceType)+"-emergency-contacts") {
+		return
+	}
+
+	result := query.Find(&contacts)
 
 	if result.Error != nil {
-		http.Error(w, `{"error","Failed to fetch contacts by service type"}`, http.StatusBadRequest)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch contacts by service type")
 		return
 	}
 
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/matching"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// containsString reports whether value is one of the allowed values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// syncPeopleRescued keeps RescueOperation.PeopleRescued equal to the number of registered evacuees
+func syncPeopleRescued(db *gorm.DB, operationID uint) error {
+	var count int64
+	if err := db.Model(&models.Evacuee{}).Where("rescue_operation_id = ?", operationID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	return db.Model(&models.RescueOperation{}).Where("id = ?", operationID).Update("people_rescued", count).Error
+}
+
+// CreateEvacuee - POST
+// http://localhost:8081/api/v1/rescue-operations/{id}/evacuees
+func CreateEvacuee(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	operationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	// The evacuee must belong to an existing operation
+	var operation models.RescueOperation
+	db := config.GetDB()
+
+	if err := db.First(&operation, operationID).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Rescue operation not found")
+		return
+	}
+
+	var evacuee models.Evacuee
+	if err := json.NewDecoder(r.Body).Decode(&evacuee); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&evacuee); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	evacuee.PhoneE164 = ""
+	if evacuee.Phone != "" {
+		number, fields := phone.Field("phone", evacuee.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		evacuee.Phone, evacuee.PhoneE164 = number.Display, number.E164
+	}
+
+	evacuee.RescueOperationID = operation.ID
+
+	// Shelter placement only happens through check-in
+	evacuee.ShelterID = nil
+	evacuee.CheckedInAt = nil
+	evacuee.CheckedOutAt = nil
+
+	// Save the evacuee and refresh the operation count together
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&evacuee).Error; err != nil {
+			return err
+		}
+		return syncPeopleRescued(tx, operation.ID)
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to register evacuee")
+		return
+	}
+
+	// A report filed earlier may be looking for this person
+	person := matching.Person{Name: evacuee.Name, Phone: evacuee.Phone, AgeBand: evacuee.AgeBand}
+	if err := matchNewPerson(db, "evacuee", evacuee.ID, person, evacuee.PhoneE164); err != nil {
+		log.Println("❌ Missing person matching failed:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(evacuee)
+
+}
+
+// GetOperationEvacuees - GET
+// http://localhost:8081/api/v1/rescue-operations/{id}/evacuees
+func GetOperationEvacuees(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	operationID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var evacuees []models.Evacuee
+	db := config.GetDB()
+
+	result := db.Where("rescue_operation_id = ?", operationID).Order("name ASC").Find(&evacuees)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch evacuees")
+		return
+	}
+
+	if len(evacuees) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evacuees)
+
+}
+
+// phoneSearch is the condition for a phone search: an exact match on the
+// E.164 form when q is a whole number, in whatever form it was typed, or a
+// match on part of the display form otherwise
+func phoneSearch(q string) (string, interface{}) {
+	if e164 := phone.E164(q); e164 != "" {
+		return "phone_e164 = ?", e164
+	}
+	return "phone LIKE ?", "%" + q + "%"
+}
+
+// SearchEvacuees - GET
+// http://localhost:8081/api/v1/evacuees?name=perera&phone=0771234567
+func SearchEvacuees(w http.ResponseWriter, r *http.Request) {
+
+	name := r.URL.Query().Get("name")
+	phoneQuery := r.URL.Query().Get("phone")
+
+	var evacuees []models.Evacuee
+	db := config.GetDB()
+
+	query := db.Order("created_at DESC")
+	if name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
+	if phoneQuery != "" {
+		query = query.Where(phoneSearch(phoneQuery))
+	}
+
+	if exportList(w, r, query, &models.Evacuee{}, "evacuees") {
+		return
+	}
+
+	if err := query.Find(&evacuees).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to search evacuees")
+		return
+	}
+
+	if len(evacuees) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evacuees)
+
+}
+
+// GetEvacueeByID - GET
+func GetEvacueeByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var evacuee models.Evacuee
+	db := config.GetDB()
+
+	if err := db.First(&evacuee, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Evacuee not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evacuee)
+
+}
+
+// UpdateEvacuee - PUT
+func UpdateEvacuee(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var existing models.Evacuee
+	db := config.GetDB()
+
+	if err := db.First(&existing, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Evacuee not found")
+		return
+	}
+
+	var update models.Evacuee
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Partial(&update); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	// Update fields if provided
+	if update.Name != "" {
+		existing.Name = update.Name
+	}
+	if update.AgeBand != "" {
+		existing.AgeBand = update.AgeBand
+	}
+	if update.Sex != "" {
+		existing.Sex = update.Sex
+	}
+	if update.IDNumber != "" {
+		existing.IDNumber = update.IDNumber
+	}
+	if update.Phone != "" {
+		number, fields := phone.Field("phone", update.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existing.Phone, existing.PhoneE164 = number.Display, number.E164
+	}
+	if update.MedicalCondition != "" {
+		existing.MedicalCondition = update.MedicalCondition
+	}
+	if update.DestinationType != "" {
+		existing.DestinationType = update.DestinationType
+	}
+	if update.DestinationName != "" {
+		existing.DestinationName = update.DestinationName
+	}
+
+	if err := db.Save(&existing).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update evacuee")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(existing)
+
+}
+
+// DeleteEvacuee - DELETE
+func DeleteEvacuee(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var evacuee models.Evacuee
+	db := config.GetDB()
+
+	if err := db.First(&evacuee, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Evacuee not found")
+		return
+	}
+
+	// Remove the evacuee and refresh the operation count together
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&evacuee).Error; err != nil {
+			return err
+		}
+		if evacuee.ShelterID != nil {
+			shelter := models.Shelter{ID: *evacuee.ShelterID}
+			if err := syncShelterOccupancy(tx, &shelter); err != nil {
+				return err
+			}
+		}
+		return syncPeopleRescued(tx, evacuee.RescueOperationID)
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete evacuee")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Evacuee deleted successfully"}`))
+
+}
+
+// GetHelpRequestEvacuees - GET
+// Incident totals: every evacuee rescued by operations linked to one help request
+// http://localhost:8081/api/v1/help-requests/{id}/evacuees
+func GetHelpRequestEvacuees(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var helpRequest models.HelpRequest
+	db := config.GetDB()
+
+	if err := db.First(&helpRequest, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Help request not found")
+		return
+	}
+
+	evacuees := []models.Evacuee{}
+	result := db.Joins("JOIN rescue_operations ON rescue_operations.id = evacuees.rescue_operation_id").
+		Where("rescue_operations.help_request_id = ?", helpRequest.ID).
+		Order("evacuees.name ASC").
+		Find(&evacuees)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch evacuees")
+		return
+	}
+
+	var operationCount int64
+	db.Model(&models.RescueOperation{}).Where("help_request_id = ?", helpRequest.ID).Count(&operationCount)
+
+	response := map[string]interface{}{
+		"help_request_id": helpRequest.ID,
+		"operations":      operationCount,
+		"people_rescued":  len(evacuees),
+		"evacuees":        evacuees,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/events"
+	middlewares "flood-relief-system/backend/middleware"
+	"flood-relief-system/backend/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// eventHeartbeat keeps idle streams open through proxies
+const eventHeartbeat = 25 * time.Second
+
+// maxEventReplay is how many missed events a reconnecting client is sent
+// before it is told to reload instead
+const maxEventReplay = 1000
+
+// eventUpgrader switches /events to WebSocket. Browsers do not apply CORS
+// to WebSocket, so the origin is checked here: pages served from this host
+// and origins named in CORS_ORIGIN may connect, clients that send no origin
+// (not browsers) may too, and a "*" in CORS_ORIGIN does not open it up.
+var eventUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return middlewares.OriginListed(origin)
+	},
+}
+
+// eventFilter reads ?entity=, ?priority= and ?area=. entity and priority
+// take comma-separated lists.
+func eventFilter(r *http.Request) (events.Filter, string) {
+	params := r.URL.Query()
+	var filter events.Filter
+
+	for _, name := range splitList(params.Get("entity")) {
+		entity, ok := events.EntityName(name)
+		if !ok {
+			return filter, "Invalid entity. Use: " + strings.Join(events.Entities(), ", ")
+		}
+		filter.Entities = append(filter.Entities, entity)
+	}
+	for _, priority := range splitList(params.Get("priority")) {
+		filter.Priorities = append(filter.Priorities, strings.ToLower(priority))
+	}
+	filter.Area = strings.TrimSpace(params.Get("area"))
+
+	return filter, ""
+}
+
+// splitList splits a comma-separated query value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// lastEventID is where a reconnecting client left off: the Last-Event-ID
+// header browsers send for Server-Sent Events, or ?last_event_id= for
+// WebSocket clients
+func lastEventID(r *http.Request) (uint, bool, string) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, ""
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, "Invalid Last-Event-ID"
+	}
+	return uint(id), true, ""
+}
+
+// eventSink is one way of sending events to a client
+type eventSink interface {
+	event(e *models.ChangeEvent) error
+	reset() error
+	heartbeat() error
+}
+
+// streamEvents replays what the client missed and then forwards live events
+// until the client goes away (done closes) or falls behind
+func streamEvents(sink eventSink, sub *events.Subscriber, replay []models.ChangeEvent, complete bool, done <-chan struct{}) {
+	if !complete {
+		if sink.reset() != nil {
+			return
+		}
+	}
+
+	replayed := make(map[uint]struct{}, len(replay))
+	for i := range replay {
+		if sink.event(&replay[i]) != nil {
+			return
+		}
+		replayed[replay[i].ID] = struct{}{}
+	}
+
+	ticker := time.NewTicker(eventHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if sink.heartbeat() != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				// Fell behind; the client reconnects and replays
+				return
+			}
+			if _, seen := replayed[e.ID]; seen {
+				continue
+			}
+			if sink.event(&e) != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseSink writes Server-Sent Events
+type sseSink struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseSink) write(format string, args ...interface{}) error {
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseSink) event(e *models.ChangeEvent) error {
+	payload, err := json.Marshal(events.MessageOf(e))
+	if err != nil {
+		return err
+	}
+	return s.write("id: %d\nevent: %s.%s\ndata: %s\n\n", e.ID, e.Entity, e.Action, payload)
+}
+
+func (s *sseSink) reset() error {
+	return s.write("event: reset\ndata: {\"type\":\"reset\"}\n\n")
+}
+
+func (s *sseSink) heartbeat() error {
+	return s.write(": ping\n\n")
+}
+
+// wsSink writes WebSocket text messages
+type wsSink struct {
+	conn *websocket.Conn
+}
+
+func (s *wsSink) event(e *models.ChangeEvent) error {
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return s.conn.WriteJSON(events.MessageOf(e))
+}
+
+func (s *wsSink) reset() error {
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return s.conn.WriteJSON(map[string]string{"type": "reset"})
+}
+
+func (s *wsSink) heartbeat() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+// StreamEvents - GET
+// Streams create, update and delete events for help requests, rescue
+// operations, volunteers, relief supplies and emergency contacts as
+// Server-Sent Events, or over WebSocket when the request asks to upgrade.
+// Filters: ?entity=help_request,rescue_operation ?priority=critical,high
+// ?area=Colombo (part of the location, or district for contacts).
+// A reconnecting client sends Last-Event-ID (browsers do this for SSE) or
+// ?last_event_id= and gets what it missed first; if that is too much or no
+// longer kept it gets a "reset" event and should reload its data. The
+// replay starts events.ReplayWindow IDs early, as events can commit out of
+// ID order, so clients skip IDs they have already handled.
+// http://localhost:8081/api/v1/events?entity=help_request&priority=critical
+func StreamEvents(w http.ResponseWriter, r *http.Request) {
+
+	filter, msg := eventFilter(r)
+	if msg != "" {
+		apierror.Write(w, http.StatusBadRequest, msg)
+		return
+	}
+	lastID, resuming, msg := lastEventID(r)
+	if msg != "" {
+		apierror.Write(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	// Subscribe before replaying so nothing falls between the two
+	sub := events.Subscribe(filter)
+	defer sub.Close()
+
+	var replay []models.ChangeEvent
+	complete := true
+	if resuming {
+		var err error
+		replay, complete, err = events.Since(config.GetDB(), lastID, filter, maxEventReplay)
+		if err != nil {
+			apierror.Write(w, http.StatusInternalServerError, "Failed to replay events")
+			return
+		}
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		conn, err := eventUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already answered the client
+			return
+		}
+		defer conn.Close()
+
+		// Clients only listen; reading is how a close or a dead peer shows up
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		streamEvents(&wsSink{conn: conn}, sub, replay, complete, done)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sink := &sseSink{w: w, rc: http.NewResponseController(w)}
+	// Ask browsers to reconnect after 3 seconds
+	if sink.write("retry: 3000\n\n") != nil {
+		return
+	}
+
+	streamEvents(sink, sub, replay, complete, r.Context().Done())
+
+}
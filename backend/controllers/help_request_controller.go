@@ -4,14 +4,46 @@ import (
 	"encoding/json" /*Clients send data as JSON
 	  Server responds with JSON*/
 
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/matching"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
+// validateHelpRequest checks a new help request and fills in the default
+// status and priority; used by create and by bulk imports
+func validateHelpRequest(helpRequest *models.HelpRequest) []apierror.FieldError {
+	// Validate required fields and the priority and status values
+	if fields := validation.Struct(helpRequest); len(fields) > 0 {
+		return fields
+	}
+
+	number, fields := phone.Field("phone", helpRequest.Phone, false)
+	if fields != nil {
+		return fields
+	}
+	helpRequest.Phone, helpRequest.PhoneE164 = number.Display, number.E164
+
+	// Set default status if not provided
+	if helpRequest.Status == "" {
+		helpRequest.Status = "pending"
+	}
+
+	//Set default priority if not provided
+	if helpRequest.Priority == "" {
+		helpRequest.Priority = "medium"
+	}
+
+	return nil
+}
+
 // CreateHelpRequest  POST/help-request
 //http://localhost:8081/api/v1/help-requests
 
@@ -27,39 +59,13 @@ func CreateHelperRequest(w http.ResponseWriter, r *http.Request) {
 	//If JSON is wrong → err != nil*/
 	if err != nil {
 
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Step 2: Validate required fields
-	if helpRequest.Name == "" || helpRequest.Phone == "" || helpRequest.Location == "" {
-		http.Error(w, `{"error":"Name, phone, and location are required"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
 		return
 	}
 
-	// Step 3: Set default status if not provided
-	if helpRequest.Status == "" {
-		helpRequest.Status = "pending"
-	}
-
-	//Set default priority if not provided
-	if helpRequest.Priority == "" {
-		helpRequest.Priority = "medium"
-	}
-
-	// NEW: Validate priority value
-	validPriorities := []string{"low", "medium", "high", "critical"}
-	isValidPriority := false
-
-	for _, priority := range validPriorities {
-		if helpRequest.Priority == priority {
-			isValidPriority = true
-			break
-		}
-	}
-
-	if !isValidPriority {
-		http.Error(w, `{"error":"Invalid priority. Use: low, medium, high, critical"}`, http.StatusBadRequest)
+	// Step 2 and 3: Validate and set defaults
+	if fields := validateHelpRequest(&helpRequest); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -67,10 +73,16 @@ func CreateHelperRequest(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 	result := db.Create(&helpRequest)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to create help request"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create help request")
 		return
 	}
 
+	// A report filed earlier may be looking for this person
+	person := matching.Person{Name: helpRequest.Name, Phone: helpRequest.Phone}
+	if err := matchNewPerson(db, "help_request", helpRequest.ID, person, helpRequest.PhoneE164); err != nil {
+		log.Println("❌ Missing person matching failed:", err)
+	}
+
 	// Step 5: Send success response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -89,11 +101,16 @@ func GetAllHelpRequests(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 
 	//Query database - fetch all records,orderd by newest first
-	result := db.Order("created_at DESC").Find(&helpRequests)
+	query := db.Order("created_at DESC")
+	if exportList(w, r, query, &models.HelpRequest{}, "help-requests") {
+		return
+	}
+
+	result := query.Find(&helpRequests)
 
 	// Check for database errors
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch help requests"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch help requests")
 		return
 	}
 
@@ -123,7 +140,7 @@ func GetHelpRequestByID(w http.ResponseWriter, r *http.Request) {
 	//Convert ID from string to integer
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -135,7 +152,8 @@ func GetHelpRequestByID(w http.ResponseWriter, r *http.Request) {
 
 	// Step 4: Check if record was found
 	if result.Error != nil {
-		http.Error(w, `{"error":"Help request not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Help request not found")
+		return
 	}
 
 	//Send success response with the found record
@@ -156,7 +174,7 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 	// Convert ID from string to integer
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID fromat"}`, http.StatusInternalServerError)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -166,7 +184,7 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&existingRequest, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Help request not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Help request not found")
 		return
 	}
 
@@ -174,7 +192,13 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 	var updateData models.HelpRequest
 	err = json.NewDecoder(r.Body).Decode(&updateData)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&updateData); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -185,7 +209,12 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateData.Phone != "" {
-		existingRequest.Phone = updateData.Phone
+		number, fields := phone.Field("phone", updateData.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existingRequest.Phone, existingRequest.PhoneE164 = number.Display, number.E164
 	}
 
 	if updateData.Location != "" {
@@ -197,27 +226,10 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateData.Priority != "" {
-		validPriorities := []string{"low", "medium", "high", "critical"}
-		isValidPriority := false
-		for _, priority := range validPriorities {
-			if updateData.Priority == priority {
-				isValidPriority = true
-				break
-			}
-		}
-
-		if !isValidPriority {
-			http.Error(w, `{"error","Invalid Status use"}`, http.StatusBadRequest)
-			return
-		}
+		existingRequest.Priority = updateData.Priority
 	}
 
 	if updateData.Status != "" {
-		// Validate status value
-		if updateData.Status != "pending" && updateData.Status != "in-progress" && updateData.Status != "completed" {
-			http.Error(w, `{"error":"Invalid status. Use ; pending, in-progress, or completed"}`, http.StatusBadRequest)
-			return
-		}
 		existingRequest.Status = updateData.Status
 	}
 
@@ -225,7 +237,7 @@ func UpdateHelpRequest(w http.ResponseWriter, r *http.Request) {
 	result = db.Save(&existingRequest)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to update help request"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update help request")
 		return
 	}
 
@@ -249,7 +261,7 @@ func DeleteHelpRequest(w http.ResponseWriter, r *http.Request) {
 	//Convert ID from string to integer
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -259,7 +271,7 @@ func DeleteHelpRequest(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&helpRequest, id) //Find the record with this ID.
 	if result.Error != nil {
-		http.Error(w, `{"error":"Help request not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Help request not found")
 		return
 		//If no record found → return 404 Not Found.
 
@@ -268,7 +280,7 @@ func DeleteHelpRequest(w http.ResponseWriter, r *http.Request) {
 	// Delete the help request from database
 	result = db.Delete(&helpRequest)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to delete help request"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete help request")
 		return
 	}
 
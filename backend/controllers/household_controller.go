@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/entitlements"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// duplicateHousehold returns the household, other than excludeID, that
+// already lists one of the ID numbers as its head or a member. Registering
+// a family twice is how over-collection starts.
+func duplicateHousehold(db *gorm.DB, household *models.Household, excludeID uint) (uint, error) {
+	ids := []string{}
+	if household.HeadIDNumber != "" {
+		ids = append(ids, household.HeadIDNumber)
+	}
+	for _, member := range household.Members {
+		if member.IDNumber != "" {
+			ids = append(ids, member.IDNumber)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var existing models.Household
+	err := db.Where("id <> ? AND head_id_number IN ?", excludeID, ids).First(&existing).Error
+	if err == nil {
+		return existing.ID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	var member models.HouseholdMember
+	err = db.Where("household_id <> ? AND id_number IN ?", excludeID, ids).First(&member).Error
+	if err == nil {
+		return member.HouseholdID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// CreateHousehold - POST
+// http://localhost:8081/api/v1/households
+func CreateHousehold(w http.ResponseWriter, r *http.Request) {
+
+	var household models.Household
+	if err := json.NewDecoder(r.Body).Decode(&household); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&household); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	household.PhoneE164 = ""
+	if household.Phone != "" {
+		number, fields := phone.Field("phone", household.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		household.Phone, household.PhoneE164 = number.Display, number.E164
+	}
+
+	for i := range household.Members {
+		household.Members[i].ID = 0
+	}
+
+	db := config.GetDB()
+	existingID, err := duplicateHousehold(db, &household, 0)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to check for duplicate households")
+		return
+	}
+	if existingID != 0 {
+		apierror.Write(w, http.StatusConflict, "An ID number is already registered to household #"+strconv.Itoa(int(existingID)))
+		return
+	}
+
+	if err := db.Create(&household).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to register household")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(household)
+
+}
+
+// GetAllHouseholds - GET
+// Optional filters: ?district= and ?q= (head name, ID number or phone)
+func GetAllHouseholds(w http.ResponseWriter, r *http.Request) {
+
+	households := []models.Household{}
+	db := config.GetDB()
+
+	query := db.Preload("Members").Order("head_name ASC")
+	if district := r.URL.Query().Get("district"); district != "" {
+		query = query.Where("district = ?", district)
+	}
+	if q := r.URL.Query().Get("q"); q != "" {
+		phoneWhere, phoneArg := phoneSearch(q)
+		query = query.Where("head_name ILIKE ? OR head_id_number = ? OR "+phoneWhere, "%"+q+"%", q, phoneArg)
+	}
+
+	if exportList(w, r, query, &models.Household{}, "households") {
+		return
+	}
+
+	if err := query.Find(&households).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch households")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(households)
+
+}
+
+// GetHouseholdByID - GET
+func GetHouseholdByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var household models.Household
+	db := config.GetDB()
+
+	if err := db.Preload("Members").First(&household, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Household not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(household)
+
+}
+
+// UpdateHousehold - PUT
+// Members, when given, replace the whole member list
+func UpdateHousehold(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var household models.Household
+	db := config.GetDB()
+
+	if err := db.Preload("Members").First(&household, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Household not found")
+		return
+	}
+
+	// Decode into a map as well so an empty member list can be told apart from missing
+	var updateData models.Household
+	var fields map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &updateData) != nil || json.Unmarshal(raw, &fields) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.HeadName != "" {
+		household.HeadName = updateData.HeadName
+	}
+	if updateData.HeadIDNumber != "" {
+		household.HeadIDNumber = updateData.HeadIDNumber
+	}
+	if updateData.Phone != "" {
+		number, fields := phone.Field("phone", updateData.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		household.Phone, household.PhoneE164 = number.Display, number.E164
+	}
+	if updateData.Address != "" {
+		household.Address = updateData.Address
+	}
+	if updateData.District != "" {
+		household.District = updateData.District
+	}
+	if updateData.Notes != "" {
+		household.Notes = updateData.Notes
+	}
+
+	_, replaceMembers := fields["members"]
+	if replaceMembers {
+		household.Members = updateData.Members
+		for i := range household.Members {
+			household.Members[i].ID = 0
+			household.Members[i].HouseholdID = household.ID
+		}
+	}
+
+	// The merged household, members included, must still be complete
+	if invalid := validation.Struct(&household); invalid != nil {
+		apierror.Invalid(w, invalid)
+		return
+	}
+
+	existingID, err := duplicateHousehold(db, &household, household.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to check for duplicate households")
+		return
+	}
+	if existingID != 0 {
+		apierror.Write(w, http.StatusConflict, "An ID number is already registered to household #"+strconv.Itoa(int(existingID)))
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Members").Save(&household).Error; err != nil {
+			return err
+		}
+		if !replaceMembers {
+			return nil
+		}
+
+		if err := tx.Where("household_id = ?", household.ID).Delete(&models.HouseholdMember{}).Error; err != nil {
+			return err
+		}
+		if len(household.Members) == 0 {
+			return nil
+		}
+		return tx.Create(&household.Members).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update household")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(household)
+
+}
+
+// DeleteHousehold - DELETE
+// Households that have received aid are kept for the distribution record
+func DeleteHousehold(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var household models.Household
+	db := config.GetDB()
+
+	if err := db.First(&household, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Household not found")
+		return
+	}
+
+	var distributions int64
+	db.Model(&models.Distribution{}).Where("household_id = ?", household.ID).Count(&distributions)
+	if distributions > 0 {
+		apierror.Write(w, http.StatusConflict, "Household has received aid and cannot be deleted")
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("household_id = ?", household.ID).Delete(&models.HouseholdMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&household).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete household")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Household deleted successfully"}`))
+
+}
+
+// GetHouseholdDistributions - GET
+// Everything a household has received, newest first
+// http://localhost:8081/api/v1/households/{id}/distributions
+func GetHouseholdDistributions(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	distributions := []models.Distribution{}
+	db := config.GetDB()
+
+	if err := db.Where("household_id = ?", id).Order("created_at DESC").Find(&distributions).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch distributions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(distributions)
+
+}
+
+// GetHouseholdEntitlements - GET
+// What the household may still collect under each active rule
+// http://localhost:8081/api/v1/households/{id}/entitlements
+func GetHouseholdEntitlements(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var household models.Household
+	db := config.GetDB()
+
+	if err := db.Preload("Members").First(&household, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Household not found")
+		return
+	}
+
+	status, err := entitlements.Status(db, &household)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute entitlements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+
+}
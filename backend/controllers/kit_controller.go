@@ -0,0 +1,545 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// validateKitComponents checks the bill of materials and stores catalogue
+// unit codes on it. Components are matched to lots of the same item and unit.
+func validateKitComponents(db *gorm.DB, components []models.KitComponent) (int, string) {
+	if len(components) == 0 {
+		return http.StatusBadRequest, "At least one component is required"
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to load unit catalogue"
+	}
+
+	seen := map[string]bool{}
+	for i := range components {
+		c := &components[i]
+		unit, err := catalog.Normalize(c.Unit)
+		if err != nil {
+			return http.StatusBadRequest, "Unknown unit. Use one of: " + strings.Join(catalog.Codes(), ", ")
+		}
+		c.Unit = unit.Code
+
+		key := strings.ToLower(c.ItemName) + "|" + c.Unit
+		if seen[key] {
+			return http.StatusBadRequest, "Each item and unit may only appear once in a kit"
+		}
+		seen[key] = true
+
+		c.ID = 0
+		c.KitTemplateID = 0
+	}
+
+	return 0, ""
+}
+
+// CreateKitTemplate - POST
+// {"name":"Family Dry Ration Kit","category":"Food","components":[{"item_name":"Rice","quantity":5,"unit":"kg"}]}
+// http://localhost:8081/api/v1/kits
+func CreateKitTemplate(w http.ResponseWriter, r *http.Request) {
+
+	var kit models.KitTemplate
+	if err := json.NewDecoder(r.Body).Decode(&kit); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&kit); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	if status, msg := validateKitComponents(db, kit.Components); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	var count int64
+	db.Model(&models.KitTemplate{}).Where("LOWER(name) = LOWER(?)", kit.Name).Count(&count)
+	if count > 0 {
+		apierror.Write(w, http.StatusConflict, "A kit with this name already exists")
+		return
+	}
+
+	kit.IsActive = true
+	if err := db.Create(&kit).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create kit template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(kit)
+
+}
+
+// GetAllKitTemplates - GET
+func GetAllKitTemplates(w http.ResponseWriter, r *http.Request) {
+
+	kits := []models.KitTemplate{}
+	db := config.GetDB()
+
+	query := db.Preload("Components").Order("name ASC")
+	if exportList(w, r, query, &models.KitTemplate{}, "kit-templates") {
+		return
+	}
+
+	if err := query.Find(&kits).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch kit templates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(kits)
+
+}
+
+// GetKitTemplateByID - GET
+func GetKitTemplateByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var kit models.KitTemplate
+	db := config.GetDB()
+
+	if err := db.Preload("Components").First(&kit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Kit template not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(kit)
+
+}
+
+// UpdateKitTemplate - PUT
+// Components, when given, replace the whole bill of materials. The kit name
+// is the item name of assembled kit lots, so it is fixed once kits exist.
+func UpdateKitTemplate(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var kit models.KitTemplate
+	db := config.GetDB()
+
+	if err := db.First(&kit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Kit template not found")
+		return
+	}
+
+	// Decode into a map as well so is_active=false can be told apart from missing
+	var updateData models.KitTemplate
+	var fields map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &updateData) != nil || json.Unmarshal(raw, &fields) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&updateData); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if updateData.Name != "" && updateData.Name != kit.Name {
+		var assemblies int64
+		db.Model(&models.KitAssembly{}).Where("kit_template_id = ?", kit.ID).Count(&assemblies)
+		if assemblies > 0 {
+			apierror.Write(w, http.StatusConflict, "Kits have been assembled under this name; create a new template instead")
+			return
+		}
+		kit.Name = updateData.Name
+	}
+
+	if updateData.Category != "" {
+		kit.Category = updateData.Category
+	}
+
+	if updateData.Description != "" {
+		kit.Description = updateData.Description
+	}
+
+	if _, ok := fields["is_active"]; ok {
+		kit.IsActive = updateData.IsActive
+	}
+
+	_, replaceComponents := fields["components"]
+	if replaceComponents {
+		if status, msg := validateKitComponents(db, updateData.Components); status != 0 {
+			apierror.Write(w, status, msg)
+			return
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Components").Save(&kit).Error; err != nil {
+			return err
+		}
+		if !replaceComponents {
+			return nil
+		}
+
+		if err := tx.Where("kit_template_id = ?", kit.ID).Delete(&models.KitComponent{}).Error; err != nil {
+			return err
+		}
+		for i := range updateData.Components {
+			updateData.Components[i].KitTemplateID = kit.ID
+		}
+		return tx.Create(&updateData.Components).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update kit template")
+		return
+	}
+
+	db.Preload("Components").First(&kit, kit.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(kit)
+
+}
+
+// DeleteKitTemplate - DELETE
+// Templates with assembled kits are kept; deactivate them instead
+func DeleteKitTemplate(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var kit models.KitTemplate
+	db := config.GetDB()
+
+	if err := db.First(&kit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Kit template not found")
+		return
+	}
+
+	var assemblies int64
+	db.Model(&models.KitAssembly{}).Where("kit_template_id = ?", kit.ID).Count(&assemblies)
+	if assemblies > 0 {
+		apierror.Write(w, http.StatusConflict, "Kits have been assembled from this template; set is_active to false instead")
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kit_template_id = ?", kit.ID).Delete(&models.KitComponent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&kit).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete kit template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Kit template deleted successfully"}`))
+
+}
+
+// componentStock is the unreserved stock of one kit component in a warehouse
+type componentStock struct {
+	ItemName  string `json:"item_name"`
+	Unit      string `json:"unit"`
+	PerKit    int    `json:"per_kit"`
+	Available int    `json:"available"`
+	Kits      int    `json:"kits"` // kits this component alone allows
+}
+
+// buildableKits is how many kits a warehouse can assemble right now
+type buildableKits struct {
+	WarehouseID uint             `json:"warehouse_id"`
+	Warehouse   string           `json:"warehouse"`
+	Buildable   int              `json:"buildable"`
+	LimitedBy   string           `json:"limited_by,omitempty"`
+	Components  []componentStock `json:"components"`
+}
+
+// kitsBuildable works out how many kits the unreserved stock in a warehouse covers
+func kitsBuildable(db *gorm.DB, kit *models.KitTemplate, warehouse *models.Warehouse) (buildableKits, error) {
+	result := buildableKits{WarehouseID: warehouse.ID, Warehouse: warehouse.Name, Components: []componentStock{}}
+
+	for i, c := range kit.Components {
+		available, err := inventory.AvailableForItem(db, c.ItemName, c.Unit, &warehouse.ID)
+		if err != nil {
+			return result, err
+		}
+
+		stock := componentStock{
+			ItemName:  c.ItemName,
+			Unit:      c.Unit,
+			PerKit:    c.Quantity,
+			Available: available,
+			Kits:      available / c.Quantity,
+		}
+		result.Components = append(result.Components, stock)
+
+		if i == 0 || stock.Kits < result.Buildable {
+			result.Buildable = stock.Kits
+			result.LimitedBy = c.ItemName
+		}
+	}
+
+	return result, nil
+}
+
+// GetBuildableKits - GET
+// How many kits each active warehouse (or ?warehouse_id=) can build now
+// http://localhost:8081/api/v1/kits/{id}/buildable
+func GetBuildableKits(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var kit models.KitTemplate
+	db := config.GetDB()
+
+	if err := db.Preload("Components").First(&kit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Kit template not found")
+		return
+	}
+
+	query := db.Where("is_active = ?", true).Order("name ASC")
+	if value := r.URL.Query().Get("warehouse_id"); value != "" {
+		warehouseID, err := strconv.Atoi(value)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid warehouse_id")
+			return
+		}
+		query = query.Where("id = ?", warehouseID)
+	}
+
+	var warehouses []models.Warehouse
+	if err := query.Find(&warehouses).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch warehouses")
+		return
+	}
+
+	results := []buildableKits{}
+	for _, warehouse := range warehouses {
+		result, err := kitsBuildable(db, &kit, &warehouse)
+		if err != nil {
+			apierror.Write(w, http.StatusInternalServerError, "Failed to compute buildable kits")
+			return
+		}
+		results = append(results, result)
+	}
+
+	response := map[string]interface{}{
+		"kit":        kit.Name,
+		"warehouses": results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// assembleRequest is the body of POST /kits/{id}/assemble
+type assembleRequest struct {
+	WarehouseID uint   `json:"warehouse_id" validate:"required"`
+	Quantity    int    `json:"quantity" validate:"required,min=1"`
+	Notes       string `json:"notes"`
+}
+
+// AssembleKits - POST
+// Issues the components first-expiry-first-out and receives the kits as a
+// new lot in the same warehouse, all in one transaction. The kit lot expires
+// with its earliest-expiring component.
+// http://localhost:8081/api/v1/kits/{id}/assemble
+func AssembleKits(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body assembleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	var kit models.KitTemplate
+	db := config.GetDB()
+
+	if err := db.Preload("Components").First(&kit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Kit template not found")
+		return
+	}
+	if !kit.IsActive {
+		apierror.Write(w, http.StatusBadRequest, "Kit template is not active")
+		return
+	}
+
+	var warehouse models.Warehouse
+	if err := db.First(&warehouse, body.WarehouseID).Error; err != nil || !warehouse.IsActive {
+		apierror.Write(w, http.StatusBadRequest, "Warehouse not found or not active")
+		return
+	}
+
+	var status int
+	var msg string
+	actor := requestActor(r)
+	assembly := models.KitAssembly{
+		KitTemplateID: kit.ID,
+		WarehouseID:   warehouse.ID,
+		Quantity:      body.Quantity,
+		Actor:         actor,
+		Notes:         body.Notes,
+	}
+	kitLot := models.ReliefSupply{
+		ItemName:    kit.Name,
+		Category:    kit.Category,
+		Quantity:    0,
+		Unit:        "kit",
+		Location:    warehouse.Name,
+		WarehouseID: &warehouse.ID,
+		Status:      "Available",
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&kitLot).Error; err != nil {
+			return err
+		}
+
+		assembly.ReliefSupplyID = kitLot.ID
+		if err := tx.Create(&assembly).Error; err != nil {
+			return err
+		}
+		reference := fmt.Sprintf("kit assembly #%d", assembly.ID)
+
+		var expiry *time.Time
+		for _, c := range kit.Components {
+			needed := c.Quantity * body.Quantity
+			reason := fmt.Sprintf("Assembled into %d x %s", body.Quantity, kit.Name)
+			lots, short, err := inventory.IssueLots(tx, c.ItemName, c.Unit, &warehouse.ID, needed, actor, reason, reference)
+			if err != nil {
+				return err
+			}
+			if short > 0 {
+				status, msg = http.StatusConflict, fmt.Sprintf("Not enough %s: %d %s short", c.ItemName, short, c.Unit)
+				return gorm.ErrInvalidData
+			}
+
+			for _, lot := range lots {
+				if lot.ExpiryDate != nil && (expiry == nil || lot.ExpiryDate.Before(*expiry)) {
+					expiry = lot.ExpiryDate
+				}
+			}
+		}
+
+		if expiry != nil {
+			kitLot.ExpiryDate = expiry
+			if err := tx.Model(&kitLot).Update("expiry_date", expiry).Error; err != nil {
+				return err
+			}
+		}
+
+		return inventory.Record(tx, &models.StockMovement{
+			ReliefSupplyID: kitLot.ID,
+			Type:           inventory.Receipt,
+			Quantity:       body.Quantity,
+			Unit:           kitLot.Unit,
+			Actor:          actor,
+			Reason:         "Kits assembled",
+			Reference:      reference,
+		})
+	})
+	if err != nil {
+		if status != 0 {
+			apierror.Write(w, status, msg)
+			return
+		}
+		status, msg := ledgerError(err)
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	kitLot.Quantity = body.Quantity
+	response := map[string]interface{}{
+		"assembly": assembly,
+		"kit_lot":  kitLot,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetKitAssemblies - GET
+func GetKitAssemblies(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	assemblies := []models.KitAssembly{}
+	db := config.GetDB()
+
+	if err := db.Where("kit_template_id = ?", id).Order("created_at DESC").Find(&assemblies).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch kit assemblies")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(assemblies)
+
+}
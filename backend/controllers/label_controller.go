@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/labels"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// labelType reads ?type=, defaulting to QR
+func labelType(r *http.Request) (string, bool) {
+	t := r.URL.Query().Get("type")
+	if t == "" {
+		return labels.QR, true
+	}
+	return t, labels.IsType(t)
+}
+
+// GetSupplyLabel - GET
+// One lot's label as a PNG image or a label-sized PDF
+// http://localhost:8081/api/v1/relief-supplies/{id}/label?format=png&type=qr
+func GetSupplyLabel(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	kind, ok := labelType(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid label type. Use: qr, code128")
+		return
+	}
+
+	var supply models.ReliefSupply
+	if err := config.GetDB().First(&supply, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+		return
+	}
+
+	// Render into a buffer so a failure can still be reported as JSON
+	var out bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "png":
+		err = labels.PNG(&out, supply.LabelCode, kind)
+		w.Header().Set("Content-Type", "image/png")
+	case "pdf":
+		err = labels.Single(&out, &supply, kind)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+supply.LabelCode+`.pdf"`)
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: png, pdf")
+		return
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		apierror.Write(w, http.StatusInternalServerError, "Failed to render label")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+
+}
+
+// GetSupplyLabelSheet - GET
+// A4 sheets of labels for the lots in ?ids=1,2,3 or in ?warehouse_id=
+// (lots with stock only), with optional ?type=qr|code128
+// http://localhost:8081/api/v1/relief-supplies/labels?warehouse_id=2
+func GetSupplyLabelSheet(w http.ResponseWriter, r *http.Request) {
+
+	kind, ok := labelType(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid label type. Use: qr, code128")
+		return
+	}
+
+	supplies := []models.ReliefSupply{}
+	db := config.GetDB()
+	query := db.Order("item_name, id")
+
+	if value := r.URL.Query().Get("ids"); value != "" {
+		ids := []int{}
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+				return
+			}
+			ids = append(ids, id)
+		}
+		query = query.Where("id IN ?", ids)
+	} else if value := r.URL.Query().Get("warehouse_id"); value != "" {
+		query = query.Where("warehouse_id = ? AND quantity > 0", value)
+	} else {
+		apierror.Write(w, http.StatusBadRequest, "ids or warehouse_id is required")
+		return
+	}
+
+	if err := query.Find(&supplies).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch relief supplies")
+		return
+	}
+	if len(supplies) == 0 {
+		apierror.Write(w, http.StatusNotFound, "No relief supplies found")
+		return
+	}
+
+	var out bytes.Buffer
+	if err := labels.Sheet(&out, supplies, kind); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to render labels")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="labels.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+
+}
+
+// supplyByCode finds the lot a scanned label belongs to. Scanners may send
+// the code in any case and with surrounding whitespace.
+func supplyByCode(db *gorm.DB, code string) (*models.ReliefSupply, error) {
+	var supply models.ReliefSupply
+	err := db.Where("label_code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&supply).Error
+	if err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
+
+// ScanLabel - GET
+// The lot behind a scanned label with its on-hand and available stock
+// http://localhost:8081/api/v1/scan/{code}
+func ScanLabel(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	supply, err := supplyByCode(db, mux.Vars(r)["code"])
+	if err != nil {
+		apierror.Write(w, http.StatusNotFound, "No relief supply has this label")
+		return
+	}
+
+	onHand, err := inventory.Balance(db, supply.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute availability")
+		return
+	}
+	reserved, err := inventory.Reserved(db, supply.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute availability")
+		return
+	}
+
+	warehouse := ""
+	if supply.WarehouseID != nil {
+		var found models.Warehouse
+		if err := db.First(&found, *supply.WarehouseID).Error; err == nil {
+			warehouse = found.Name
+		}
+	}
+
+	response := map[string]interface{}{
+		"supply":    supply,
+		"warehouse": warehouse,
+		"on_hand":   onHand,
+		"reserved":  reserved,
+		"available": onHand - reserved,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// scanRequest is the body of the scan issue, receive and transfer endpoints
+type scanRequest struct {
+	Quantity      int    `json:"quantity" validate:"required"`
+	Unit          string `json:"unit"`
+	ReportedBy    string `json:"reported_by"`
+	Reason        string `json:"reason"`
+	ToCode        string `json:"to_code"`         // transfers into another labelled lot
+	ToWarehouseID uint   `json:"to_warehouse_id"` // or into a warehouse
+}
+
+// readScan looks up the scanned lot and decodes the request body
+func readScan(w http.ResponseWriter, r *http.Request) (*models.ReliefSupply, *scanRequest, bool) {
+	supply, err := supplyByCode(config.GetDB(), mux.Vars(r)["code"])
+	if err != nil {
+		apierror.Write(w, http.StatusNotFound, "No relief supply has this label")
+		return nil, nil, false
+	}
+
+	var body scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return nil, nil, false
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return nil, nil, false
+	}
+	body.ReportedBy = reportedBy(r, body.ReportedBy)
+
+	return supply, &body, true
+}
+
+// scanMovement records an issue or receipt against the scanned lot
+func scanMovement(w http.ResponseWriter, r *http.Request, movementType string) {
+
+	supply, body, ok := readScan(w, r)
+	if !ok {
+		return
+	}
+
+	movements, status, msg := applyMovement(config.GetDB(), supply.ID, &movementRequest{
+		Type:       movementType,
+		Quantity:   body.Quantity,
+		Unit:       body.Unit,
+		ReportedBy: body.ReportedBy,
+		Reason:     body.Reason,
+		Actor:      requestActor(r),
+	})
+	if status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movements)
+
+}
+
+// ScanIssue - POST
+// {"quantity":10,"reason":"Issued at Kelaniya shelter"}
+// http://localhost:8081/api/v1/scan/{code}/issue
+func ScanIssue(w http.ResponseWriter, r *http.Request) {
+	scanMovement(w, r, inventory.Issue)
+}
+
+// ScanReceive - POST
+// {"quantity":25}
+// http://localhost:8081/api/v1/scan/{code}/receive
+func ScanReceive(w http.ResponseWriter, r *http.Request) {
+	scanMovement(w, r, inventory.Receipt)
+}
+
+// ScanTransfer - POST
+// Moves stock into the lot labelled to_code, or into the matching lot of
+// to_warehouse_id (created when the warehouse has none)
+// {"quantity":20,"to_warehouse_id":3}
+// http://localhost:8081/api/v1/scan/{code}/transfer
+func ScanTransfer(w http.ResponseWriter, r *http.Request) {
+
+	supply, body, ok := readScan(w, r)
+	if !ok {
+		return
+	}
+
+	if (body.ToCode == "") == (body.ToWarehouseID == 0) {
+		apierror.Write(w, http.StatusBadRequest, "Give either to_code or to_warehouse_id")
+		return
+	}
+
+	request := &movementRequest{
+		Type:       inventory.Transfer,
+		Quantity:   body.Quantity,
+		Unit:       body.Unit,
+		ReportedBy: body.ReportedBy,
+		Reason:     body.Reason,
+		Actor:      requestActor(r),
+	}
+
+	var movements []*models.StockMovement
+	status, msg := 0, ""
+	db := config.GetDB()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if body.ToCode != "" {
+			to, err := supplyByCode(tx, body.ToCode)
+			if err != nil {
+				status, msg = http.StatusNotFound, "No relief supply has the destination label"
+				return gorm.ErrInvalidData
+			}
+			request.ToSupplyID = to.ID
+		} else {
+			var warehouse models.Warehouse
+			if err := tx.First(&warehouse, body.ToWarehouseID).Error; err != nil || !warehouse.IsActive {
+				status, msg = http.StatusBadRequest, "Warehouse not found or not active"
+				return gorm.ErrInvalidData
+			}
+			if supply.WarehouseID != nil && *supply.WarehouseID == warehouse.ID {
+				status, msg = http.StatusBadRequest, "Lot is already in this warehouse"
+				return gorm.ErrInvalidData
+			}
+			lot, err := inventory.FindOrCreateLot(tx, supply, &warehouse)
+			if err != nil {
+				return err
+			}
+			request.ToSupplyID = lot.ID
+		}
+
+		movements, status, msg = applyMovement(tx, supply.ID, request)
+		if status != 0 {
+			return gorm.ErrInvalidData
+		}
+		return nil
+	})
+	if err != nil {
+		if status != 0 {
+			apierror.Write(w, status, msg)
+			return
+		}
+		status, msg := ledgerError(err)
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movements)
+
+}
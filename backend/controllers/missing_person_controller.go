@@ -0,0 +1,664 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/matching"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/notify"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// likelyMatch is the SQL condition that narrows the people compared in
+// matching: the same phone, or a name key with a word starting like a word
+// of name that also passes extra when it is given. Name keys are
+// transliterated, so a Sinhala or Tamil name is compared with its English
+// spelling. Everyone else scores below matching.Threshold, so they are not
+// loaded at all.
+func likelyMatch(name, e164, extra string, extraArgs ...interface{}) (string, []interface{}) {
+	initials := matching.Initials(name)
+
+	where, args := "FALSE", []interface{}{}
+	if len(initials) > 0 {
+		where = "name_key ~ ?"
+		args = append(args, "(^| )("+strings.Join(initials, "|")+")")
+		if extra != "" {
+			where = "(" + where + ") AND (" + extra + ")"
+			args = append(args, extraArgs...)
+		}
+	}
+	if e164 != "" {
+		where = "phone_e164 = ? OR (" + where + ")"
+		args = append([]interface{}{e164}, args...)
+	}
+	return "(" + where + ")", args
+}
+
+// queueMatch scores a candidate against a report and puts it in the review
+// queue when it reaches matching.Threshold. It returns nil otherwise.
+func queueMatch(db *gorm.DB, report *models.MissingPersonReport, kind string, id uint, person matching.Person) (*models.MissingPersonMatch, error) {
+	subject := matching.Person{Name: report.Name, Phone: report.Phone, Age: report.Age}
+	score, reasons := matching.Score(subject, person)
+	if score < matching.Threshold {
+		return nil, nil
+	}
+
+	match := models.MissingPersonMatch{
+		ReportID:      report.ID,
+		CandidateType: kind,
+		CandidateID:   id,
+		CandidateName: person.Name,
+		Score:         score,
+		Reasons:       strings.Join(reasons, ", "),
+		Status:        "pending",
+	}
+	if err := db.Create(&match).Error; err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// findMatches compares a report with the evacuees and help requests that
+// could be the same person and queues the new candidates that score above
+// matching.Threshold
+func findMatches(db *gorm.DB, report *models.MissingPersonReport) ([]models.MissingPersonMatch, error) {
+
+	type candidate struct {
+		kind   string
+		id     uint
+		person matching.Person
+	}
+	candidates := []candidate{}
+
+	// Evacuees are only registered with an age band; unknown bands are kept
+	ageFilter, ageArgs := "", []interface{}{}
+	if report.Age > 0 {
+		ageFilter = "age_band IN ? OR age_band = '' OR age_band IS NULL"
+		ageArgs = append(ageArgs, matching.Bands(report.Age))
+	}
+	where, args := likelyMatch(report.Name, report.PhoneE164, ageFilter, ageArgs...)
+
+	var evacuees []models.Evacuee
+	if err := db.Select("id", "name", "phone", "age_band").Where(where, args...).Find(&evacuees).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range evacuees {
+		candidates = append(candidates, candidate{"evacuee", e.ID, matching.Person{Name: e.Name, Phone: e.Phone, AgeBand: e.AgeBand}})
+	}
+
+	where, args = likelyMatch(report.Name, report.PhoneE164, "")
+	var helpRequests []models.HelpRequest
+	if err := db.Select("id", "name", "phone").Where(where, args...).Find(&helpRequests).Error; err != nil {
+		return nil, err
+	}
+	for _, h := range helpRequests {
+		candidates = append(candidates, candidate{"help_request", h.ID, matching.Person{Name: h.Name, Phone: h.Phone}})
+	}
+
+	// Candidates already in the queue (or already reviewed) are not added again
+	var existing []models.MissingPersonMatch
+	if err := db.Where("report_id = ?", report.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, m := range existing {
+		seen[fmt.Sprintf("%s:%d", m.CandidateType, m.CandidateID)] = true
+	}
+
+	matches := []models.MissingPersonMatch{}
+	for _, c := range candidates {
+		if seen[fmt.Sprintf("%s:%d", c.kind, c.id)] {
+			continue
+		}
+
+		match, err := queueMatch(db, report, c.kind, c.id, c.person)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+
+	return matches, nil
+}
+
+// matchNewPerson compares a newly registered evacuee or help request with
+// the open missing person reports, so a report filed earlier finds the
+// person as soon as they turn up
+func matchNewPerson(db *gorm.DB, kind string, id uint, person matching.Person, e164 string) error {
+	ageFilter, ageArgs := "", []interface{}{}
+	if lo, hi, ok := matching.BandAges(person.AgeBand); ok {
+		ageFilter = "age = 0 OR age BETWEEN ? AND ?"
+		ageArgs = append(ageArgs, lo, hi)
+	}
+	where, args := likelyMatch(person.Name, e164, ageFilter, ageArgs...)
+
+	var reports []models.MissingPersonReport
+	if err := db.Where("status = ?", "missing").Where(where, args...).Find(&reports).Error; err != nil {
+		return err
+	}
+
+	for i := range reports {
+		if _, err := queueMatch(db, &reports[i], kind, id, person); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reunificationMessage tells the reporter where the missing person was found
+func reunificationMessage(db *gorm.DB, report *models.MissingPersonReport, match *models.MissingPersonMatch) string {
+
+	msg := fmt.Sprintf("Flood Relief: %s (missing person report #%d) has been found.", report.Name, report.ID)
+
+	switch match.CandidateType {
+	case "evacuee":
+		var evacuee models.Evacuee
+		if db.First(&evacuee, match.CandidateID).Error == nil && evacuee.DestinationName != "" {
+			msg += fmt.Sprintf(" They were evacuated to %s.", evacuee.DestinationName)
+		} else {
+			msg += " They were rescued by a rescue team."
+		}
+	case "help_request":
+		var helpRequest models.HelpRequest
+		if db.First(&helpRequest, match.CandidateID).Error == nil {
+			msg += fmt.Sprintf(" They asked for help from %s.", helpRequest.Location)
+		}
+	}
+
+	return msg + " Please contact the relief coordination centre for details."
+}
+
+// CreateMissingPersonReport - POST
+// Saves the report and runs matching straight away
+// http://localhost:8081/api/v1/missing-persons
+func CreateMissingPersonReport(w http.ResponseWriter, r *http.Request) {
+
+	var report models.MissingPersonReport
+
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&report); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	report.Status = "missing"
+
+	report.PhoneE164 = ""
+	if report.Phone != "" {
+		number, fields := phone.Field("phone", report.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		report.Phone, report.PhoneE164 = number.Display, number.E164
+	}
+
+	number, fields := phone.Field("reporter_phone", report.ReporterPhone, false)
+	if fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	report.ReporterPhone, report.ReporterPhoneE164 = number.Display, number.E164
+
+	db := config.GetDB()
+	if err := db.Create(&report).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create missing person report")
+		return
+	}
+
+	matches, err := findMatches(db, &report)
+	if err != nil {
+		log.Println("❌ Missing person matching failed:", err)
+		matches = []models.MissingPersonMatch{}
+	}
+
+	response := map[string]interface{}{
+		"report":  report,
+		"matches": matches,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetAllMissingPersonReports - GET
+// Optional filter: ?status=missing
+func GetAllMissingPersonReports(w http.ResponseWriter, r *http.Request) {
+
+	var reports []models.MissingPersonReport
+	db := config.GetDB()
+
+	query := db.Order("created_at DESC")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if exportList(w, r, query, &models.MissingPersonReport{}, "missing-persons") {
+		return
+	}
+
+	if err := query.Find(&reports).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch missing person reports")
+		return
+	}
+
+	if len(reports) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+
+}
+
+// GetMissingPersonReportByID - GET
+func GetMissingPersonReportByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var report models.MissingPersonReport
+	db := config.GetDB()
+
+	if err := db.First(&report, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Missing person report not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+
+}
+
+// UpdateMissingPersonReport - PUT
+func UpdateMissingPersonReport(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var existing models.MissingPersonReport
+	db := config.GetDB()
+
+	if err := db.First(&existing, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Missing person report not found")
+		return
+	}
+
+	var update models.MissingPersonReport
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&update); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	// Update fields if provided
+	if update.Name != "" {
+		existing.Name = update.Name
+	}
+	if update.Age > 0 {
+		existing.Age = update.Age
+	}
+	if update.Sex != "" {
+		existing.Sex = update.Sex
+	}
+	if update.Phone != "" {
+		number, fields := phone.Field("phone", update.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existing.Phone, existing.PhoneE164 = number.Display, number.E164
+	}
+	if update.LastSeenLocation != "" {
+		existing.LastSeenLocation = update.LastSeenLocation
+	}
+	if update.LastSeenAt != nil {
+		existing.LastSeenAt = update.LastSeenAt
+	}
+	if update.Description != "" {
+		existing.Description = update.Description
+	}
+	if update.ReporterName != "" {
+		existing.ReporterName = update.ReporterName
+	}
+	if update.ReporterPhone != "" {
+		number, fields := phone.Field("reporter_phone", update.ReporterPhone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existing.ReporterPhone, existing.ReporterPhoneE164 = number.Display, number.E164
+	}
+	if update.Status != "" {
+		existing.Status = update.Status
+	}
+
+	if err := db.Save(&existing).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update missing person report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(existing)
+
+}
+
+// DeleteMissingPersonReport - DELETE
+func DeleteMissingPersonReport(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var report models.MissingPersonReport
+	db := config.GetDB()
+
+	if err := db.First(&report, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Missing person report not found")
+		return
+	}
+
+	// The report's candidate matches go with it
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.MissingPersonMatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&report).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete missing person report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Missing person report deleted successfully"}`))
+
+}
+
+// RunMissingPersonMatching - POST
+// Re-runs matching, e.g. after new evacuees were registered
+// http://localhost:8081/api/v1/missing-persons/{id}/match
+func RunMissingPersonMatching(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var report models.MissingPersonReport
+	db := config.GetDB()
+
+	if err := db.First(&report, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Missing person report not found")
+		return
+	}
+
+	if report.Status != "missing" {
+		apierror.Write(w, http.StatusConflict, "Report is no longer open")
+		return
+	}
+
+	matches, err := findMatches(db, &report)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to run matching")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matches)
+
+}
+
+// GetReportMatches - GET
+// All candidate matches for one report, best first
+func GetReportMatches(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var matches []models.MissingPersonMatch
+	db := config.GetDB()
+
+	if err := db.Where("report_id = ?", id).Order("score DESC").Find(&matches).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch matches")
+		return
+	}
+
+	if len(matches) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matches)
+
+}
+
+// GetMatchReviewQueue - GET
+// Pending candidate matches across all reports, best first
+func GetMatchReviewQueue(w http.ResponseWriter, r *http.Request) {
+
+	var matches []models.MissingPersonMatch
+	db := config.GetDB()
+
+	if err := db.Where("status = ?", "pending").Order("score DESC, created_at ASC").Find(&matches).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch review queue")
+		return
+	}
+
+	if len(matches) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matches)
+
+}
+
+// reviewRequest is the body of the confirm and reject endpoints
+type reviewRequest struct {
+	ReviewedBy string `json:"reviewed_by" validate:"required"`
+}
+
+// lockPendingMatch loads a match for update inside a review transaction and
+// checks nobody has reviewed it yet
+func lockPendingMatch(tx *gorm.DB, match *models.MissingPersonMatch, id int) (int, string) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(match, id).Error; err != nil {
+		return http.StatusNotFound, "Match not found"
+	}
+	if match.Status != "pending" {
+		return http.StatusConflict, "Match has already been reviewed"
+	}
+	return 0, ""
+}
+
+// ConfirmMatch - POST
+// Marks the person as found, closes the other candidates and notifies the reporter
+// http://localhost:8081/api/v1/missing-persons/matches/{id}/confirm
+func ConfirmMatch(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	var match models.MissingPersonMatch
+	var report models.MissingPersonReport
+	db := config.GetDB()
+
+	if err := db.First(&match, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Match not found")
+		return
+	}
+
+	now := time.Now()
+	status, msg := http.StatusOK, ""
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the report, then the match, so two reviewers confirming
+		// candidates for the same report go one after the other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, match.ReportID).Error; err != nil {
+			status, msg = http.StatusNotFound, "Missing person report not found"
+			return err
+		}
+		if status, msg = lockPendingMatch(tx, &match, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+
+		match.Status = "confirmed"
+		match.ReviewedBy = body.ReviewedBy
+		match.ReviewedAt = &now
+		if err := tx.Save(&match).Error; err != nil {
+			return err
+		}
+
+		report.Status = "found"
+		if err := tx.Save(&report).Error; err != nil {
+			return err
+		}
+
+		// The person has been found, so the other candidates are not them
+		return tx.Model(&models.MissingPersonMatch{}).
+			Where("report_id = ? AND id <> ? AND status = ?", report.ID, match.ID, "pending").
+			Updates(map[string]interface{}{"status": "rejected", "reviewed_by": body.ReviewedBy, "reviewed_at": now}).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to confirm match"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	// Notify the reporter; a failed SMS does not undo the confirmation
+	if err := notify.SendSMS(report.ReporterPhoneE164, reunificationMessage(db, &report, &match)); err != nil {
+		log.Println("❌ Failed to notify reporter:", err)
+	} else {
+		notifiedAt := time.Now()
+		match.NotifiedAt = &notifiedAt
+		db.Model(&match).Update("notified_at", notifiedAt)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(match)
+
+}
+
+// RejectMatch - POST
+// http://localhost:8081/api/v1/missing-persons/matches/{id}/reject
+func RejectMatch(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	var match models.MissingPersonMatch
+	db := config.GetDB()
+
+	status, msg := http.StatusOK, ""
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockPendingMatch(tx, &match, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+
+		now := time.Now()
+		match.Status = "rejected"
+		match.ReviewedBy = body.ReviewedBy
+		match.ReviewedAt = &now
+		return tx.Save(&match).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to reject match"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(match)
+
+}
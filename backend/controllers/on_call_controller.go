@@ -0,0 +1,654 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/oncall"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// emergencyServiceTypes are the service types of the emergency contact directory
+var emergencyServiceTypes = []string{"Medical", "Rescue", "Food", "Shelter", "Police", "Fire", "Other"}
+
+// GetOnCall - GET
+// Who to call right now for a service, with backups in calling order.
+// Optional: ?district=, ?organization= and ?at= (RFC 3339, default now)
+// http://localhost:8081/api/v1/emergency-contacts/on-call?service=Medical&district=Colombo
+func GetOnCall(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	service := query.Get("service")
+	if !containsString(emergencyServiceTypes, service) {
+		apierror.Write(w, http.StatusBadRequest, "Invalid service. Use: Medical, Rescue, Food, Shelter, Police, Fire, Other")
+		return
+	}
+
+	at := time.Now()
+	if value := query.Get("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid at. Use RFC 3339, e.g. 2025-11-30T03:00:00+05:30")
+			return
+		}
+		at = parsed
+	}
+
+	answer, err := oncall.Current(config.GetDB(), service, query.Get("district"), query.Get("organization"), at)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to look up on-call contact")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(answer)
+
+}
+
+// validateShift checks a shift and fills in the service type and
+// organization from its contact when they are left out
+func validateShift(db *gorm.DB, shift *models.OnCallShift) (int, string) {
+	var contact models.EmergencyContact
+	if err := db.First(&contact, shift.EmergencyContactID).Error; err != nil {
+		return http.StatusBadRequest, "Emergency contact not found"
+	}
+	if !contact.IsActive {
+		return http.StatusBadRequest, "Emergency contact is not active"
+	}
+
+	if shift.ServiceType == "" {
+		shift.ServiceType = contact.ServiceType
+	}
+	if shift.ServiceType == "" {
+		return http.StatusBadRequest, "service_type is required when the contact has none"
+	}
+	if shift.OrganizationName == "" {
+		shift.OrganizationName = contact.OrganizationName
+	}
+
+	if !shift.EndsAt.After(shift.StartsAt) {
+		return http.StatusBadRequest, "ends_at must be after starts_at"
+	}
+
+	return 0, ""
+}
+
+// CreateOnCallShift - POST
+// {"emergency_contact_id":4,"service_type":"Medical","district":"Colombo","starts_at":"2025-11-30T20:00:00+05:30","ends_at":"2025-12-01T08:00:00+05:30"}
+// http://localhost:8081/api/v1/on-call-shifts
+func CreateOnCallShift(w http.ResponseWriter, r *http.Request) {
+
+	var shift models.OnCallShift
+	if err := json.NewDecoder(r.Body).Decode(&shift); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	shift.ID = 0
+	if fields := validation.Struct(&shift); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	db := config.GetDB()
+	if status, msg := validateShift(db, &shift); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Omit("Contact").Create(&shift).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create on-call shift")
+		return
+	}
+	db.Preload("Contact").First(&shift, shift.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shift)
+
+}
+
+// GetOnCallShifts - GET
+// Optional filters: ?service=, ?district=, ?contact_id= and ?from=&to=
+// (RFC 3339) for shifts overlapping a period
+func GetOnCallShifts(w http.ResponseWriter, r *http.Request) {
+
+	shifts := []models.OnCallShift{}
+	db := config.GetDB()
+	params := r.URL.Query()
+
+	query := db.Preload("Contact").Order("starts_at ASC")
+	if service := params.Get("service"); service != "" {
+		query = query.Where("service_type = ?", service)
+	}
+	if district := params.Get("district"); district != "" {
+		query = query.Where("district = ?", district)
+	}
+	if contactID := params.Get("contact_id"); contactID != "" {
+		query = query.Where("emergency_contact_id = ?", contactID)
+	}
+	for _, bound := range []struct{ param, condition string }{
+		{"from", "ends_at > ?"},
+		{"to", "starts_at < ?"},
+	} {
+		value := params.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid "+bound.param+". Use RFC 3339")
+			return
+		}
+		query = query.Where(bound.condition, parsed)
+	}
+
+	if exportList(w, r, query, &models.OnCallShift{}, "on-call-shifts") {
+		return
+	}
+
+	if err := query.Find(&shifts).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch on-call shifts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shifts)
+
+}
+
+// UpdateOnCallShift - PUT
+func UpdateOnCallShift(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var shift models.OnCallShift
+	db := config.GetDB()
+
+	if err := db.First(&shift, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "On-call shift not found")
+		return
+	}
+
+	var updateData models.OnCallShift
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.EmergencyContactID != 0 {
+		shift.EmergencyContactID = updateData.EmergencyContactID
+	}
+	if updateData.ServiceType != "" {
+		shift.ServiceType = updateData.ServiceType
+	}
+	if updateData.OrganizationName != "" {
+		shift.OrganizationName = updateData.OrganizationName
+	}
+	if updateData.District != "" {
+		shift.District = updateData.District
+	}
+	if !updateData.StartsAt.IsZero() {
+		shift.StartsAt = updateData.StartsAt
+	}
+	if !updateData.EndsAt.IsZero() {
+		shift.EndsAt = updateData.EndsAt
+	}
+	if updateData.Notes != "" {
+		shift.Notes = updateData.Notes
+	}
+
+	if fields := validation.Struct(&shift); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if status, msg := validateShift(db, &shift); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Omit("Contact").Save(&shift).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update on-call shift")
+		return
+	}
+	db.Preload("Contact").First(&shift, shift.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shift)
+
+}
+
+// DeleteOnCallShift - DELETE
+func DeleteOnCallShift(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	db := config.GetDB()
+	result := db.Delete(&models.OnCallShift{}, id)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete on-call shift")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Write(w, http.StatusNotFound, "On-call shift not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"On-call shift deleted successfully"}`))
+
+}
+
+// validateChain checks the contacts of a chain and numbers its steps in the
+// order given,
+// once its fields have passed validation.Struct
+func validateChain(db *gorm.DB, chain *models.EscalationChain) (int, string) {
+	if chain.AckTimeoutMinutes == 0 {
+		chain.AckTimeoutMinutes = 10
+	}
+
+	seen := map[uint]bool{}
+	for i := range chain.Steps {
+		step := &chain.Steps[i]
+		if seen[step.EmergencyContactID] {
+			return http.StatusBadRequest, "A contact can only appear once in a chain"
+		}
+		seen[step.EmergencyContactID] = true
+
+		if err := db.First(&models.EmergencyContact{}, step.EmergencyContactID).Error; err != nil {
+			return http.StatusBadRequest, "Emergency contact #" + strconv.Itoa(int(step.EmergencyContactID)) + " not found"
+		}
+		step.ID = 0
+		step.EscalationChainID = chain.ID
+		step.Position = i + 1
+		step.Contact = models.EmergencyContact{}
+	}
+
+	return 0, ""
+}
+
+// loadChain fetches a chain with its steps and their contacts in order
+func loadChain(db *gorm.DB, chain *models.EscalationChain, id int) error {
+	return db.Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Steps.Contact").
+		First(chain, id).Error
+}
+
+// CreateEscalationChain - POST
+// Steps are called in the order given
+// {"name":"Colombo ambulance","service_type":"Medical","district":"Colombo","ack_timeout_minutes":5,"steps":[{"emergency_contact_id":4},{"emergency_contact_id":7}]}
+// http://localhost:8081/api/v1/escalation-chains
+func CreateEscalationChain(w http.ResponseWriter, r *http.Request) {
+
+	var chain models.EscalationChain
+	if err := json.NewDecoder(r.Body).Decode(&chain); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	chain.ID = 0
+	if fields := validation.Struct(&chain); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	db := config.GetDB()
+	if status, msg := validateChain(db, &chain); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Create(&chain).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create escalation chain")
+		return
+	}
+	loadChain(db, &chain, int(chain.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(chain)
+
+}
+
+// GetEscalationChains - GET
+// Optional filters: ?service= and ?district=
+func GetEscalationChains(w http.ResponseWriter, r *http.Request) {
+
+	chains := []models.EscalationChain{}
+	db := config.GetDB()
+
+	query := db.Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Steps.Contact").
+		Order("service_type, name")
+	if service := r.URL.Query().Get("service"); service != "" {
+		query = query.Where("service_type = ?", service)
+	}
+	if district := r.URL.Query().Get("district"); district != "" {
+		query = query.Where("district = ?", district)
+	}
+
+	if exportList(w, r, query, &models.EscalationChain{}, "escalation-chains") {
+		return
+	}
+
+	if err := query.Find(&chains).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch escalation chains")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chains)
+
+}
+
+// GetEscalationChainByID - GET
+func GetEscalationChainByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var chain models.EscalationChain
+	if err := loadChain(config.GetDB(), &chain, id); err != nil {
+		apierror.Write(w, http.StatusNotFound, "Escalation chain not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chain)
+
+}
+
+// UpdateEscalationChain - PUT
+// Steps, when given, replace the whole chain
+func UpdateEscalationChain(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var chain models.EscalationChain
+	db := config.GetDB()
+
+	if err := loadChain(db, &chain, id); err != nil {
+		apierror.Write(w, http.StatusNotFound, "Escalation chain not found")
+		return
+	}
+
+	// Decode into a map as well so is_active=false can be told apart from missing
+	var updateData models.EscalationChain
+	var fields map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &updateData) != nil || json.Unmarshal(raw, &fields) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.Name != "" {
+		chain.Name = updateData.Name
+	}
+	if updateData.ServiceType != "" {
+		chain.ServiceType = updateData.ServiceType
+	}
+	if updateData.OrganizationName != "" {
+		chain.OrganizationName = updateData.OrganizationName
+	}
+	if updateData.District != "" {
+		chain.District = updateData.District
+	}
+	if updateData.AckTimeoutMinutes != 0 {
+		chain.AckTimeoutMinutes = updateData.AckTimeoutMinutes
+	}
+	if _, ok := fields["is_active"]; ok {
+		chain.IsActive = updateData.IsActive
+	}
+
+	_, replaceSteps := fields["steps"]
+	if replaceSteps {
+		// Open call-outs track their place by position, so the chain is fixed while they run
+		var open int64
+		db.Model(&models.Escalation{}).Where("escalation_chain_id = ? AND status = ?", chain.ID, "open").Count(&open)
+		if open > 0 {
+			apierror.Write(w, http.StatusConflict, "Steps cannot change while a call-out on this chain is open")
+			return
+		}
+		chain.Steps = updateData.Steps
+	}
+
+	if fields := validation.Struct(&chain); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if status, msg := validateChain(db, &chain); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Steps").Save(&chain).Error; err != nil {
+			return err
+		}
+		if !replaceSteps {
+			return nil
+		}
+
+		if err := tx.Where("escalation_chain_id = ?", chain.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Contact").Create(&chain.Steps).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update escalation chain")
+		return
+	}
+	loadChain(db, &chain, int(chain.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chain)
+
+}
+
+// DeleteEscalationChain - DELETE
+// Chains with call-outs are kept for the record; deactivate them instead
+func DeleteEscalationChain(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var chain models.EscalationChain
+	db := config.GetDB()
+
+	if err := db.First(&chain, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Escalation chain not found")
+		return
+	}
+
+	var escalations int64
+	db.Model(&models.Escalation{}).Where("escalation_chain_id = ?", chain.ID).Count(&escalations)
+	if escalations > 0 {
+		apierror.Write(w, http.StatusConflict, "Escalation chain has call-outs and cannot be deleted; set is_active to false instead")
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("escalation_chain_id = ?", chain.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&chain).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete escalation chain")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Escalation chain deleted successfully"}`))
+
+}
+
+// TriggerEscalation - POST
+// Starts a call-out: the first step is texted now and the escalation job
+// moves on to the next step when nobody acknowledges in time
+// {"message":"Boat capsized at Kaduwela, 6 people in water"}
+// http://localhost:8081/api/v1/escalation-chains/{id}/trigger
+func TriggerEscalation(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var chain models.EscalationChain
+	db := config.GetDB()
+
+	if err := db.First(&chain, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Escalation chain not found")
+		return
+	}
+	if !chain.IsActive {
+		apierror.Write(w, http.StatusConflict, "Escalation chain is not active")
+		return
+	}
+
+	var body struct {
+		Message string `json:"message" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	escalation, err := oncall.Start(db, &chain, body.Message, requestActor(r))
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to start escalation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(escalation)
+
+}
+
+// GetEscalations - GET
+// Optional filter: ?status=open
+func GetEscalations(w http.ResponseWriter, r *http.Request) {
+
+	escalations := []models.Escalation{}
+	db := config.GetDB()
+
+	query := db.Order("created_at DESC")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if exportList(w, r, query, &models.Escalation{}, "escalations") {
+		return
+	}
+
+	if err := query.Find(&escalations).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch escalations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(escalations)
+
+}
+
+// closeEscalation ends an open escalation with the given status
+func closeEscalation(w http.ResponseWriter, r *http.Request, status string) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var escalation models.Escalation
+	db := config.GetDB()
+
+	if err := db.First(&escalation, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Escalation not found")
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "closed_at": &now}
+	if status == "acknowledged" {
+		updates["acknowledged_by"] = requestActor(r)
+		updates["acknowledged_at"] = &now
+	}
+
+	// Conditional so a late acknowledgement cannot reopen an exhausted call-out
+	result := db.Model(&escalation).Where("status = ?", "open").Updates(updates)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update escalation")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Write(w, http.StatusConflict, "Escalation is no longer open")
+		return
+	}
+	db.First(&escalation, escalation.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(escalation)
+
+}
+
+// AcknowledgeEscalation - POST
+// Stops the escalation; the acknowledging person is taken from the login
+// http://localhost:8081/api/v1/escalations/{id}/acknowledge
+func AcknowledgeEscalation(w http.ResponseWriter, r *http.Request) {
+	closeEscalation(w, r, "acknowledged")
+}
+
+// CancelEscalation - POST
+// http://localhost:8081/api/v1/escalations/{id}/cancel
+func CancelEscalation(w http.ResponseWriter, r *http.Request) {
+	closeEscalation(w, r, "cancelled")
+}
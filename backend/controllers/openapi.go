@@ -0,0 +1,706 @@
+package controllers
+
+import (
+	"flood-relief-system/backend/donors"
+	"flood-relief-system/backend/entitlements"
+	"flood-relief-system/backend/forecast"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/oncall"
+	"flood-relief-system/backend/openapi"
+	"flood-relief-system/backend/stats"
+	"net/http"
+	"sort"
+)
+
+// The OpenAPI annotations of every handler. Routes come from the router, so
+// only what a handler reads and answers is written down here; a handler
+// that is routed without an entry fails the openapi -check build step.
+
+var supplyCategories = []string{"Food", "Medical", "Clothing", "Shelter", "Other"}
+
+// messageResponse is the answer of deletes and resets
+var messageResponse = openapi.Fields{"message": ""}
+
+func param(name string, schema *openapi.Schema, description string) openapi.Param {
+	return openapi.Param{Name: name, Schema: schema, Description: description}
+}
+
+func requiredParam(name string, schema *openapi.Schema, description string) openapi.Param {
+	return openapi.Param{Name: name, Schema: schema, Description: description, Required: true}
+}
+
+// Parameters shared by several operations
+var (
+	warehouseParam = param("warehouse_id", openapi.ID(), "Only this warehouse")
+	serviceParam   = param("service", openapi.Enum(emergencyServiceTypes...), "Only this service type")
+	districtParam  = param("district", openapi.String(), "Only this district")
+	periodParams   = []openapi.Param{
+		param("from", openapi.String(), "Start, RFC 3339 or YYYY-MM-DD in APP_TIMEZONE"),
+		param("to", openapi.String(), "End, RFC 3339 or YYYY-MM-DD in APP_TIMEZONE"),
+	}
+	sitRepFormatParam = param("format", openapi.Enum("json", "md", "markdown", "html", "pdf"), "json (the default), or the rendered report")
+	importParams      = []openapi.Param{
+		param("format", openapi.Enum("csv", "xlsx"), "File format; taken from the file name or content type when left out"),
+		param("mapping", openapi.String(), `JSON object of field name to column header, e.g. {"name":"Full Name"}`),
+		param("dry_run", openapi.Boolean(), "Validate without saving"),
+		param("mode", openapi.Enum("all-or-nothing", "skip-invalid"), "all-or-nothing (the default) saves nothing when a row is invalid"),
+	}
+	sheetUploads  = []string{"text/csv", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+	sitRepAnswers = []string{"text/markdown", "text/html", "application/pdf"}
+)
+
+// statsMetrics are the metric names of the series endpoint, in order
+func statsMetrics() []string {
+	names := make([]string, 0, len(stats.Metrics))
+	for name := range stats.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Operations annotates the handlers routed in SetupRoutes
+var Operations = []openapi.Op{
+	// Help Requests
+	{Handler: CreateHelperRequest, Tag: "Help Requests", Summary: "Create a help request",
+		Body:   models.HelpRequest{},
+		Status: http.StatusCreated, Response: models.HelpRequest{}},
+	{Handler: GetAllHelpRequests, Tag: "Help Requests", Summary: "List help requests",
+		Response: []models.HelpRequest{}, Export: true},
+	{Handler: ImportHelpRequests, Tag: "Help Requests", Summary: "Import help requests from a sheet",
+		Description: "Same options as the volunteer import. 201 when rows were created, 422 with the report when nothing was saved.",
+		Query:       importParams, Upload: sheetUploads, Status: http.StatusCreated, Response: bulkImportReport{}},
+	{Handler: GetHelpRequestByID, Tag: "Help Requests", Summary: "Get a help request",
+		Response: models.HelpRequest{}},
+	{Handler: UpdateHelpRequest, Tag: "Help Requests", Summary: "Update a help request",
+		Body: models.HelpRequest{}, Response: models.HelpRequest{}},
+	{Handler: DeleteHelpRequest, Tag: "Help Requests", Summary: "Delete a help request",
+		Response: messageResponse},
+
+	// Volunteers
+	{Handler: CreateVolunteer, Tag: "Volunteers", Summary: "Register a volunteer",
+		Body:   models.Volunteer{},
+		Status: http.StatusCreated, Response: models.Volunteer{}},
+	{Handler: GetAllVolunteers, Tag: "Volunteers", Summary: "List volunteers",
+		Response: []models.Volunteer{}, Export: true},
+	{Handler: ImportVolunteers, Tag: "Volunteers", Summary: "Import volunteers from a sign-up sheet",
+		Description: "The sheet is the request body or the file field of a form. Unmapped fields use the column of the same name. " +
+			"201 when rows were created, 422 with the report when nothing was saved.",
+		Query: importParams, Upload: sheetUploads, Status: http.StatusCreated, Response: bulkImportReport{}},
+	{Handler: GetVolunteerByID, Tag: "Volunteers", Summary: "Get a volunteer",
+		Response: models.Volunteer{}},
+	{Handler: UpdateVolunteer, Tag: "Volunteers", Summary: "Update a volunteer",
+		Body: models.Volunteer{}, Response: models.Volunteer{}},
+	{Handler: DeleteVolunteer, Tag: "Volunteers", Summary: "Delete a volunteer",
+		Response: messageResponse},
+
+	// Relief Supplies
+	{Handler: CreateReliefSupply, Tag: "Relief Supplies", Summary: "Receive a lot of relief supplies",
+		Description: "The quantity is booked as the lot's first receipt in the stock ledger.",
+		Body:        models.ReliefSupply{},
+		Response:    models.ReliefSupply{}},
+	{Handler: GetAllReliefSupplies, Tag: "Relief Supplies", Summary: "List relief supplies",
+		Response: []models.ReliefSupply{}, Export: true},
+	{Handler: GetExpiringSupplies, Tag: "Relief Supplies", Summary: "List lots that expire soon",
+		Query:    []openapi.Param{param("days", openapi.Integer(), "Days ahead, 7 by default")},
+		Response: []models.ReliefSupply{}},
+	{Handler: RunExpiryCheck, Tag: "Relief Supplies", Summary: "Run the expiry job now",
+		Response: openapi.Fields{"expired_lots": 0}},
+	{Handler: GetSupplyLabelSheet, Tag: "Relief Supplies", Summary: "Print A4 sheets of lot labels",
+		Description: "For the lots in ids, or the lots with stock in warehouse_id.",
+		Query: []openapi.Param{
+			param("ids", openapi.String(), "Comma-separated lot IDs, e.g. 1,2,3"),
+			warehouseParam,
+			param("type", openapi.Enum("qr", "code128"), "Barcode type, qr by default"),
+		},
+		Produces: []string{"application/pdf"}},
+	{Handler: ImportReliefSupplies, Tag: "Relief Supplies", Summary: "Import relief supplies from a sheet",
+		Description: "Same options as the volunteer import. Each row becomes a lot with an initial receipt. " +
+			"201 when rows were created, 422 with the report when nothing was saved.",
+		Query: importParams, Upload: sheetUploads, Status: http.StatusCreated, Response: bulkImportReport{}},
+	{Handler: GetReliefSupplyById, Tag: "Relief Supplies", Summary: "Get a relief supply",
+		Response: models.ReliefSupply{}},
+	{Handler: UpdateReliefSupply, Tag: "Relief Supplies", Summary: "Update a relief supply",
+		Description: "A changed quantity is booked as an adjustment. The donor cannot change once a receipt acknowledges the supply.",
+		Body:        models.ReliefSupply{}, Response: models.ReliefSupply{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteReliefSupply, Tag: "Relief Supplies", Summary: "Delete a relief supply without stock on hand",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetSuppliesByCategory, Tag: "Relief Supplies", Summary: "List supplies in a category",
+		Path:     []openapi.Param{param("category", openapi.Enum(supplyCategories...), "")},
+		Response: []models.ReliefSupply{}, Export: true},
+	{Handler: GetAvailableSupplies, Tag: "Relief Supplies", Summary: "List supplies by availability",
+		Path:     []openapi.Param{param("available", openapi.Boolean(), "true for available lots")},
+		Response: []models.ReliefSupply{}, Export: true},
+	{Handler: CreateStockMovement, Tag: "Relief Supplies", Summary: "Record a stock movement",
+		Description: "Quantities are positive except for adjustments, which may be negative. " +
+			"A transfer moves stock into the lot given by to_supply_id.",
+		Body:   movementRequest{},
+		Status: http.StatusCreated, Response: []*models.StockMovement{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetStockMovements, Tag: "Relief Supplies", Summary: "List a lot's movements",
+		Response: []models.StockMovement{}},
+	{Handler: GetSupplyBalance, Tag: "Relief Supplies", Summary: "Get a lot's on-hand balance from the ledger",
+		Response: openapi.Fields{"relief_supply_id": uint(0), "item_name": "", "unit": "", "on_hand": 0}},
+	{Handler: GetSupplyAvailability, Tag: "Relief Supplies", Summary: "Get a lot's on-hand, reserved and available stock",
+		Response: openapi.Fields{"relief_supply_id": uint(0), "unit": "", "on_hand": 0, "reserved": 0, "available": 0, "status": ""}},
+	{Handler: GetSupplyLabel, Tag: "Relief Supplies", Summary: "Get a lot's label",
+		Query: []openapi.Param{
+			param("format", openapi.Enum("png", "pdf"), "png (the default) or a label-sized pdf"),
+			param("type", openapi.Enum("qr", "code128"), "Barcode type, qr by default"),
+		},
+		Produces: []string{"image/png", "application/pdf"}},
+
+	// Label Scans
+	{Handler: ScanLabel, Tag: "Label Scans", Summary: "Look up the lot behind a scanned label",
+		Path:     []openapi.Param{param("code", openapi.String(), "Label code")},
+		Response: openapi.Fields{"supply": &models.ReliefSupply{}, "warehouse": "", "on_hand": 0, "reserved": 0, "available": 0},
+		Errors:   []int{http.StatusNotFound}},
+	{Handler: ScanIssue, Tag: "Label Scans", Summary: "Issue stock from a scanned lot",
+		Path:   []openapi.Param{param("code", openapi.String(), "Label code")},
+		Body:   scanRequest{},
+		Status: http.StatusCreated, Response: []*models.StockMovement{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+	{Handler: ScanReceive, Tag: "Label Scans", Summary: "Receive stock into a scanned lot",
+		Path:   []openapi.Param{param("code", openapi.String(), "Label code")},
+		Body:   scanRequest{},
+		Status: http.StatusCreated, Response: []*models.StockMovement{}, Errors: []int{http.StatusNotFound}},
+	{Handler: ScanTransfer, Tag: "Label Scans", Summary: "Transfer stock out of a scanned lot",
+		Description: "Into the lot labelled to_code, or into the matching lot of to_warehouse_id, created when the warehouse has none.",
+		Path:        []openapi.Param{param("code", openapi.String(), "Label code")},
+		Body:        scanRequest{},
+		Status:      http.StatusCreated, Response: []*models.StockMovement{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+
+	// Supply Allocations
+	{Handler: CreateSupplyAllocation, Tag: "Supply Allocations", Summary: "Reserve stock of a lot",
+		Description: "For a help request or a rescue operation.",
+		Body:        models.SupplyAllocation{},
+		Status:      http.StatusCreated, Response: models.SupplyAllocation{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+	{Handler: GetAllSupplyAllocations, Tag: "Supply Allocations", Summary: "List allocations",
+		Query: []openapi.Param{
+			param("help_request_id", openapi.ID(), ""),
+			param("rescue_operation_id", openapi.ID(), ""),
+			param("relief_supply_id", openapi.ID(), ""),
+			param("status", openapi.Enum("reserved", "picked", "partially-delivered", "delivered", "cancelled"), ""),
+		},
+		Response: []models.SupplyAllocation{}, Export: true},
+	{Handler: SuggestAllocationLots, Tag: "Supply Allocations", Summary: "Suggest lots first-expiry-first-out",
+		Query: []openapi.Param{
+			requiredParam("item_name", openapi.String(), ""),
+			requiredParam("quantity", openapi.ID(), ""),
+			requiredParam("unit", openapi.String(), "Catalogue unit code or alias; only lots in this unit are used"),
+			warehouseParam,
+		},
+		Response: openapi.Fields{"item_name": "", "unit": "", "requested": 0, "shortfall": 0, "lots": []inventory.LotSuggestion{}}},
+	{Handler: CreateFEFOAllocations, Tag: "Supply Allocations", Summary: "Reserve an item across lots first-expiry-first-out",
+		Description: "One allocation per lot. Nothing is reserved when stock is short.",
+		Body:        fefoAllocationRequest{},
+		Status:      http.StatusCreated, Response: []models.SupplyAllocation{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+	{Handler: GetSupplyAllocationByID, Tag: "Supply Allocations", Summary: "Get an allocation",
+		Response: models.SupplyAllocation{}},
+	{Handler: PickSupplyAllocation, Tag: "Supply Allocations", Summary: "Pick reserved goods off the shelf",
+		Description: "The goods leave the ledger as an issue. Without a body the whole reservation is picked.",
+		Body:        quantityRequest{}, Response: models.SupplyAllocation{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeliverSupplyAllocation, Tag: "Supply Allocations", Summary: "Record goods handed over",
+		Description: "Deliveries may be partial. Without a body everything picked is delivered.",
+		Body:        quantityRequest{}, Response: models.SupplyAllocation{}, Errors: []int{http.StatusConflict}},
+	{Handler: CancelSupplyAllocation, Tag: "Supply Allocations", Summary: "Cancel an allocation",
+		Description: "Releases the unpicked reservation and returns picked but undelivered goods to stock.",
+		Response:    models.SupplyAllocation{}, Errors: []int{http.StatusConflict}},
+
+	// Units of Measure
+	{Handler: CreateUnit, Tag: "Units", Summary: "Add a unit",
+		Body:   models.Unit{},
+		Status: http.StatusCreated, Response: models.Unit{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetUnits, Tag: "Units", Summary: "List units",
+		Response: []models.Unit{}},
+	{Handler: ConvertQuantity, Tag: "Units", Summary: "Convert a quantity between units",
+		Query: []openapi.Param{
+			requiredParam("quantity", openapi.Number(), ""),
+			requiredParam("from", openapi.String(), "Unit code or alias"),
+			requiredParam("to", openapi.String(), "Unit code or alias"),
+			param("item_name", openapi.String(), "Use the item's own conversions as well"),
+		},
+		Response: openapi.Fields{"item_name": "", "quantity": 0.0, "from": "", "to": "", "result": 0.0},
+		Errors:   []int{http.StatusUnprocessableEntity}},
+	{Handler: UpdateUnit, Tag: "Units", Summary: "Update a unit",
+		Description: "The code and dimension are fixed because stored stock refers to them.",
+		Body:        models.Unit{}, Response: models.Unit{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteUnit, Tag: "Units", Summary: "Delete an unused unit",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: CreateUnitConversion, Tag: "Units", Summary: "Add an item-specific conversion",
+		Body:   models.UnitConversion{},
+		Status: http.StatusCreated, Response: models.UnitConversion{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetUnitConversions, Tag: "Units", Summary: "List item-specific conversions",
+		Query:    []openapi.Param{param("item_name", openapi.String(), "")},
+		Response: []models.UnitConversion{}},
+	{Handler: DeleteUnitConversion, Tag: "Units", Summary: "Delete a conversion",
+		Response: messageResponse},
+	{Handler: GetCategoryUnits, Tag: "Units", Summary: "List the unit each category is totalled in",
+		Response: []models.CategoryUnit{}},
+	{Handler: SetCategoryUnit, Tag: "Units", Summary: "Set the unit a category is totalled in",
+		Path: []openapi.Param{param("category", openapi.Enum(supplyCategories...), "")},
+		Body: models.CategoryUnit{}, Required: []string{"unit_code"}, Response: models.CategoryUnit{}},
+
+	// Donors
+	{Handler: CreateDonor, Tag: "Donors", Summary: "Add a donor",
+		Body:   models.Donor{},
+		Status: http.StatusCreated, Response: models.Donor{}},
+	{Handler: GetAllDonors, Tag: "Donors", Summary: "List donors",
+		Query: []openapi.Param{
+			param("type", openapi.Enum("individual", "organization"), ""),
+			param("q", openapi.String(), "Part of the name, part of the phone or a whole phone number in any form"),
+		},
+		Response: []models.Donor{}, Export: true},
+	{Handler: GetDonorByID, Tag: "Donors", Summary: "Get a donor",
+		Response: models.Donor{}},
+	{Handler: UpdateDonor, Tag: "Donors", Summary: "Update a donor",
+		Description: "Name and phone changes are copied onto the donor's supplies.",
+		Body:        models.Donor{}, Response: models.Donor{}},
+	{Handler: DeleteDonor, Tag: "Donors", Summary: "Delete a donor without donations",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetDonorDonations, Tag: "Donors", Summary: "Get a donor's donation history",
+		Response: openapi.Fields{"donor": models.Donor{}, "totals": donors.Summary{}, "donations": []donors.Donation{}}},
+	{Handler: CreateDonationReceipt, Tag: "Donors", Summary: "Issue a receipt for unacknowledged donations",
+		Description: "Without supply_ids every donation without a receipt is included.",
+		Body:        receiptRequest{}, Status: http.StatusCreated, Response: models.DonationReceipt{},
+		Errors: []int{http.StatusConflict}},
+	{Handler: GetDonorReceipts, Tag: "Donors", Summary: "List a donor's receipts",
+		Response: []models.DonationReceipt{}},
+	{Handler: GetDonationReceipt, Tag: "Donors", Summary: "Get a receipt",
+		Query: []openapi.Param{
+			param("format", openapi.Enum("json", "html", "pdf"), "json by default"),
+			param("lang", openapi.Enum("en", "si", "ta"), "Print a copy in another language. PDF copies are English only"),
+		},
+		Response: models.DonationReceipt{}, Produces: []string{"text/html", "application/pdf"},
+		Errors: []int{http.StatusNotImplemented}},
+
+	// Relief Kits
+	{Handler: CreateKitTemplate, Tag: "Relief Kits", Summary: "Add a kit template",
+		Body:   models.KitTemplate{},
+		Status: http.StatusCreated, Response: models.KitTemplate{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetAllKitTemplates, Tag: "Relief Kits", Summary: "List kit templates",
+		Response: []models.KitTemplate{}, Export: true},
+	{Handler: GetKitTemplateByID, Tag: "Relief Kits", Summary: "Get a kit template",
+		Response: models.KitTemplate{}},
+	{Handler: UpdateKitTemplate, Tag: "Relief Kits", Summary: "Update a kit template",
+		Description: "Components, when given, replace the whole bill of materials. The name is fixed once kits exist.",
+		Body:        models.KitTemplate{}, Response: models.KitTemplate{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteKitTemplate, Tag: "Relief Kits", Summary: "Delete a kit template without assemblies",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetBuildableKits, Tag: "Relief Kits", Summary: "How many kits each warehouse can build",
+		Query:    []openapi.Param{warehouseParam},
+		Response: openapi.Fields{"kit": "", "warehouses": []buildableKits{}}},
+	{Handler: AssembleKits, Tag: "Relief Kits", Summary: "Assemble kits",
+		Description: "Issues the components first-expiry-first-out and receives the kits as a new lot in the same warehouse.",
+		Body:        assembleRequest{}, Status: http.StatusCreated,
+		Response: openapi.Fields{"assembly": models.KitAssembly{}, "kit_lot": models.ReliefSupply{}},
+		Errors:   []int{http.StatusConflict}},
+	{Handler: GetKitAssemblies, Tag: "Relief Kits", Summary: "List a kit's assemblies",
+		Response: []models.KitAssembly{}},
+
+	// Households and Distributions
+	{Handler: CreateHousehold, Tag: "Households and Distributions", Summary: "Register a household",
+		Body:   models.Household{},
+		Status: http.StatusCreated, Response: models.Household{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetAllHouseholds, Tag: "Households and Distributions", Summary: "List households",
+		Query: []openapi.Param{
+			districtParam,
+			param("q", openapi.String(), "Head name, ID number, part of the phone or a whole phone number in any form"),
+		},
+		Response: []models.Household{}, Export: true},
+	{Handler: GetHouseholdByID, Tag: "Households and Distributions", Summary: "Get a household",
+		Response: models.Household{}},
+	{Handler: UpdateHousehold, Tag: "Households and Distributions", Summary: "Update a household",
+		Description: "Members, when given, replace the whole member list.",
+		Body:        models.Household{}, Response: models.Household{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteHousehold, Tag: "Households and Distributions", Summary: "Delete a household that has received no aid",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetHouseholdDistributions, Tag: "Households and Distributions", Summary: "List what a household has received",
+		Response: []models.Distribution{}},
+	{Handler: GetHouseholdEntitlements, Tag: "Households and Distributions", Summary: "What a household may still collect",
+		Response: []entitlements.Entitlement{}},
+	{Handler: CreateDistributionEvent, Tag: "Households and Distributions", Summary: "Open a distribution event",
+		Body:   models.DistributionEvent{},
+		Status: http.StatusCreated, Response: models.DistributionEvent{}},
+	{Handler: GetAllDistributionEvents, Tag: "Households and Distributions", Summary: "List distribution events",
+		Query:    []openapi.Param{param("status", openapi.Enum("open", "closed"), "")},
+		Response: []models.DistributionEvent{}, Export: true},
+	{Handler: GetDistributionEventByID, Tag: "Households and Distributions", Summary: "Get an event with its totals",
+		Response: openapi.Fields{
+			"event":      models.DistributionEvent{},
+			"households": int64(0),
+			"totals": []struct {
+				ItemName string `json:"item_name"`
+				Unit     string `json:"unit"`
+				Quantity int    `json:"quantity"`
+			}{},
+		}},
+	{Handler: CloseDistributionEvent, Tag: "Households and Distributions", Summary: "Close a distribution event",
+		Response: models.DistributionEvent{}, Errors: []int{http.StatusConflict}},
+	{Handler: CreateDistribution, Tag: "Households and Distributions", Summary: "Record a handout to a household",
+		Description: "Items that break a block rule refuse the whole handout with 409 and the violations; warn rules let it through with warnings.",
+		Body:        handoutRequest{}, Status: http.StatusCreated,
+		Response: openapi.Fields{"distributions": []models.Distribution{}, "warnings": []entitlements.Violation{}},
+		Errors:   []int{http.StatusConflict}},
+	{Handler: GetEventDistributions, Tag: "Households and Distributions", Summary: "List an event's handouts",
+		Response: []models.Distribution{}},
+	{Handler: CreateEntitlementRule, Tag: "Households and Distributions", Summary: "Add an entitlement rule",
+		Body:   models.EntitlementRule{},
+		Status: http.StatusCreated, Response: models.EntitlementRule{}},
+	{Handler: GetEntitlementRules, Tag: "Households and Distributions", Summary: "List entitlement rules",
+		Response: []models.EntitlementRule{}, Export: true},
+	{Handler: UpdateEntitlementRule, Tag: "Households and Distributions", Summary: "Update an entitlement rule",
+		Body: models.EntitlementRule{}, Response: models.EntitlementRule{}},
+	{Handler: DeleteEntitlementRule, Tag: "Households and Distributions", Summary: "Delete an entitlement rule",
+		Response: messageResponse},
+
+	// Warehouses
+	{Handler: CreateWarehouse, Tag: "Warehouses", Summary: "Add a warehouse",
+		Body:   models.Warehouse{},
+		Status: http.StatusCreated, Response: models.Warehouse{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetAllWarehouses, Tag: "Warehouses", Summary: "List warehouses",
+		Response: []models.Warehouse{}, Export: true},
+	{Handler: GetWarehouseByID, Tag: "Warehouses", Summary: "Get a warehouse",
+		Response: models.Warehouse{}},
+	{Handler: UpdateWarehouse, Tag: "Warehouses", Summary: "Update a warehouse",
+		Body: models.Warehouse{}, Response: models.Warehouse{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteWarehouse, Tag: "Warehouses", Summary: "Delete an empty warehouse",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetWarehouseStock, Tag: "Warehouses", Summary: "Stock on hand in a warehouse, per item",
+		Response: openapi.Fields{"warehouse": models.Warehouse{}, "stock": []stockLevel{}}},
+	{Handler: GetStockSummary, Tag: "Warehouses", Summary: "Stock on hand, grouped",
+		Description: "In the canonical unit of each category. Lots without a warehouse are reported under \"Unassigned\".",
+		Query:       []openapi.Param{param("by", openapi.Enum("warehouse", "category", "item"), "category by default")},
+		Response:    []stockLevel{}},
+	{Handler: GetStockForecast, Tag: "Warehouses", Summary: "Stock, demand and days of cover per reorder threshold",
+		Query:    []openapi.Param{warehouseParam},
+		Response: []forecast.Forecast{}},
+	{Handler: GetShortageReport, Tag: "Warehouses", Summary: "Categories with items to reorder",
+		Query:    []openapi.Param{warehouseParam},
+		Response: []forecast.CategoryShortage{}},
+	{Handler: CreateReorderThreshold, Tag: "Warehouses", Summary: "Add a reorder threshold",
+		Body:   models.ReorderThreshold{},
+		Status: http.StatusCreated, Response: models.ReorderThreshold{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetReorderThresholds, Tag: "Warehouses", Summary: "List reorder thresholds",
+		Query:    []openapi.Param{warehouseParam},
+		Response: []models.ReorderThreshold{}, Export: true},
+	{Handler: UpdateReorderThreshold, Tag: "Warehouses", Summary: "Update a reorder threshold",
+		Body: models.ReorderThreshold{}, Response: models.ReorderThreshold{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteReorderThreshold, Tag: "Warehouses", Summary: "Delete a reorder threshold",
+		Response: messageResponse},
+
+	// Transfer Orders
+	{Handler: CreateTransferOrder, Tag: "Transfer Orders", Summary: "Create a draft transfer order",
+		Body:   models.TransferOrder{},
+		Status: http.StatusCreated, Response: models.TransferOrder{}},
+	{Handler: GetAllTransferOrders, Tag: "Transfer Orders", Summary: "List transfer orders",
+		Query: []openapi.Param{
+			param("status", openapi.Enum("draft", "dispatched", "in-transit", "received", "cancelled"), ""),
+			param("warehouse_id", openapi.ID(), "Orders from or to this warehouse"),
+		},
+		Response: []models.TransferOrder{}, Export: true},
+	{Handler: GetTransferOrderByID, Tag: "Transfer Orders", Summary: "Get a transfer order",
+		Response: models.TransferOrder{}},
+	{Handler: DispatchTransferOrder, Tag: "Transfer Orders", Summary: "Dispatch an order",
+		Description: "Takes the goods out of the source warehouse ledger.",
+		Response:    models.TransferOrder{}, Errors: []int{http.StatusConflict}},
+	{Handler: MarkTransferInTransit, Tag: "Transfer Orders", Summary: "Mark an order in transit",
+		Response: models.TransferOrder{}, Errors: []int{http.StatusConflict}},
+	{Handler: ReceiveTransferOrder, Tag: "Transfer Orders", Summary: "Receive an order",
+		Description: "Books the goods into lots at the destination warehouse. Without a body every line is received in full.",
+		Body:        receiveRequest{}, Response: models.TransferOrder{}, Errors: []int{http.StatusConflict}},
+	{Handler: CancelTransferOrder, Tag: "Transfer Orders", Summary: "Cancel a draft order",
+		Response: models.TransferOrder{}, Errors: []int{http.StatusConflict}},
+
+	// Rescue Operations
+	{Handler: CreateRescueOperation, Tag: "Rescue Operations", Summary: "Start a rescue operation",
+		Body:   models.RescueOperation{},
+		Status: http.StatusCreated, Response: models.RescueOperation{}},
+	{Handler: GetAllRescueOperations, Tag: "Rescue Operations", Summary: "List rescue operations",
+		Response: []models.RescueOperation{}, Export: true},
+	{Handler: GetRescueOperationByID, Tag: "Rescue Operations", Summary: "Get a rescue operation",
+		Response: models.RescueOperation{}},
+	{Handler: UpdateRescueOperation, Tag: "Rescue Operations", Summary: "Update a rescue operation",
+		Body: models.RescueOperation{}, Response: models.RescueOperation{}},
+	{Handler: DeleteRescueOperation, Tag: "Rescue Operations", Summary: "Delete a rescue operation",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetActiveOperations, Tag: "Rescue Operations", Summary: "List active operations",
+		Response: []models.RescueOperation{}, Export: true},
+	{Handler: GetOperationsByPriority, Tag: "Rescue Operations", Summary: "List operations of a priority",
+		Path:     []openapi.Param{param("priority", openapi.Enum("low", "medium", "high", "critical"), "")},
+		Response: []models.RescueOperation{}, Export: true},
+
+	// Evacuees
+	{Handler: CreateEvacuee, Tag: "Evacuees", Summary: "Record a person rescued by an operation",
+		Body:   models.Evacuee{},
+		Status: http.StatusCreated, Response: models.Evacuee{}},
+	{Handler: GetOperationEvacuees, Tag: "Evacuees", Summary: "List an operation's evacuees",
+		Response: []models.Evacuee{}},
+	{Handler: GetHelpRequestEvacuees, Tag: "Evacuees", Summary: "Everyone rescued for a help request",
+		Response: openapi.Fields{"help_request_id": uint(0), "operations": int64(0), "people_rescued": 0, "evacuees": []models.Evacuee{}}},
+	{Handler: SearchEvacuees, Tag: "Evacuees", Summary: "Search evacuees",
+		Query: []openapi.Param{
+			param("name", openapi.String(), "Part of the name"),
+			param("phone", openapi.String(), "Part of the phone or a whole phone number in any form"),
+		},
+		Response: []models.Evacuee{}, Export: true},
+	{Handler: GetEvacueeByID, Tag: "Evacuees", Summary: "Get an evacuee",
+		Response: models.Evacuee{}},
+	{Handler: UpdateEvacuee, Tag: "Evacuees", Summary: "Update an evacuee",
+		Body: models.Evacuee{}, Response: models.Evacuee{}},
+	{Handler: DeleteEvacuee, Tag: "Evacuees", Summary: "Delete an evacuee",
+		Response: messageResponse},
+
+	// Shelters
+	{Handler: CreateShelter, Tag: "Shelters", Summary: "Add a shelter",
+		Body:   models.Shelter{},
+		Status: http.StatusCreated, Response: models.Shelter{}},
+	{Handler: GetAllShelters, Tag: "Shelters", Summary: "List shelters",
+		Response: []models.Shelter{}, Export: true},
+	{Handler: GetAvailableShelters, Tag: "Shelters", Summary: "Open shelters with free places",
+		Query:    []openapi.Param{param("near", openapi.String(), "lat,lng to sort nearest first; shelters without coordinates are left out")},
+		Response: []availableShelter{}},
+	{Handler: GetNearCapacityShelters, Tag: "Shelters", Summary: "Open shelters at or above SHELTER_ALERT_PERCENT occupancy",
+		Response: []models.Shelter{}, Export: true},
+	{Handler: GetShelterByID, Tag: "Shelters", Summary: "Get a shelter",
+		Response: models.Shelter{}},
+	{Handler: UpdateShelter, Tag: "Shelters", Summary: "Update a shelter",
+		Body: models.Shelter{}, Response: models.Shelter{}},
+	{Handler: DeleteShelter, Tag: "Shelters", Summary: "Delete an empty shelter",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: GetShelterEvacuees, Tag: "Shelters", Summary: "Evacuees checked in to a shelter",
+		Response: []models.Evacuee{}},
+	{Handler: CheckInEvacuee, Tag: "Shelters", Summary: "Check an evacuee in",
+		Body: struct {
+			EvacueeID uint `json:"evacuee_id" validate:"required"`
+		}{},
+		Response: openapi.Fields{"shelter": models.Shelter{}, "evacuee": models.Evacuee{}, "near_capacity": false},
+		Errors:   []int{http.StatusConflict}},
+	{Handler: CheckOutEvacuee, Tag: "Shelters", Summary: "Check an evacuee out",
+		Body: struct {
+			EvacueeID uint `json:"evacuee_id" validate:"required"`
+		}{},
+		Response: openapi.Fields{"shelter": models.Shelter{}, "evacuee": models.Evacuee{}},
+		Errors:   []int{http.StatusConflict}},
+
+	// Missing Persons
+	{Handler: CreateMissingPersonReport, Tag: "Missing Persons", Summary: "File a missing person report",
+		Description: "Matching runs straight away.",
+		Body:        models.MissingPersonReport{},
+		Status:      http.StatusCreated,
+		Response:    openapi.Fields{"report": models.MissingPersonReport{}, "matches": []models.MissingPersonMatch{}}},
+	{Handler: GetAllMissingPersonReports, Tag: "Missing Persons", Summary: "List missing person reports",
+		Query:    []openapi.Param{param("status", openapi.Enum("missing", "found", "closed"), "")},
+		Response: []models.MissingPersonReport{}, Export: true},
+	{Handler: GetMatchReviewQueue, Tag: "Missing Persons", Summary: "Pending candidate matches, best first",
+		Response: []models.MissingPersonMatch{}},
+	{Handler: ConfirmMatch, Tag: "Missing Persons", Summary: "Confirm a match",
+		Description: "Marks the person as found, closes the other candidates and notifies the reporter.",
+		Body:        reviewRequest{},
+		Response:    models.MissingPersonMatch{}, Errors: []int{http.StatusConflict}},
+	{Handler: RejectMatch, Tag: "Missing Persons", Summary: "Reject a match",
+		Body:     reviewRequest{},
+		Response: models.MissingPersonMatch{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetMissingPersonReportByID, Tag: "Missing Persons", Summary: "Get a missing person report",
+		Response: models.MissingPersonReport{}},
+	{Handler: UpdateMissingPersonReport, Tag: "Missing Persons", Summary: "Update a missing person report",
+		Body: models.MissingPersonReport{}, Response: models.MissingPersonReport{}},
+	{Handler: DeleteMissingPersonReport, Tag: "Missing Persons", Summary: "Delete a missing person report",
+		Response: messageResponse},
+	{Handler: RunMissingPersonMatching, Tag: "Missing Persons", Summary: "Re-run matching for a report",
+		Response: []models.MissingPersonMatch{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetReportMatches, Tag: "Missing Persons", Summary: "Candidate matches of a report, best first",
+		Response: []models.MissingPersonMatch{}},
+
+	// Emergency Contacts
+	{Handler: CreateEmergencyContact, Tag: "Emergency Contacts", Summary: "Add an emergency contact",
+		Body:   models.EmergencyContact{},
+		Status: http.StatusCreated, Response: models.EmergencyContact{}},
+	{Handler: GetAllEmergencyContacts, Tag: "Emergency Contacts", Summary: "List emergency contacts",
+		Response: []models.EmergencyContact{}, Export: true},
+	{Handler: GetOnCall, Tag: "Emergency Contacts", Summary: "Who to call now for a service",
+		Description: "With backups in calling order.",
+		Query: []openapi.Param{
+			requiredParam("service", openapi.Enum(emergencyServiceTypes...), ""),
+			districtParam,
+			param("organization", openapi.String(), ""),
+			param("at", &openapi.Schema{Type: "string", Format: "date-time"}, "RFC 3339, now by default"),
+		},
+		Response: &oncall.Answer{}},
+	{Handler: GetVerificationDueContacts, Tag: "Emergency Contacts", Summary: "Active contacts due for verification",
+		Description: "Unreachable first, then never verified, then the longest since verification.",
+		Query:       []openapi.Param{serviceParam, districtParam},
+		Response:    []models.EmergencyContact{}},
+	{Handler: ExportEmergencyContacts, Tag: "Emergency Contacts", Summary: "Export the directory as vCard or CSV",
+		Query: []openapi.Param{
+			param("format", openapi.Enum("vcf", "vcard", "csv"), "vCard 4.0 by default"),
+			serviceParam,
+			districtParam,
+			param("active", openapi.Boolean(), ""),
+			param("verified", openapi.Boolean(), ""),
+		},
+		Produces: []string{"text/vcard", "text/csv"}},
+	{Handler: ImportEmergencyContacts, Tag: "Emergency Contacts", Summary: "Import contacts from CSV or vCard",
+		Description: "Invalid rows and likely duplicates are skipped. 201 when contacts were created.",
+		Query: []openapi.Param{
+			param("format", openapi.Enum("csv", "vcf"), "Taken from the file name or content type when left out"),
+			param("dry_run", openapi.Boolean(), "Report without saving"),
+			param("allow_duplicates", openapi.Boolean(), "Import likely duplicates anyway"),
+		},
+		Upload: []string{"text/csv", "text/vcard"}, Status: http.StatusCreated, Response: importReport{}},
+	{Handler: GetEmergencyContactByID, Tag: "Emergency Contacts", Summary: "Get an emergency contact",
+		Response: models.EmergencyContact{}},
+	{Handler: UpdateEmergencyContact, Tag: "Emergency Contacts", Summary: "Update an emergency contact",
+		Body: models.EmergencyContact{}, Response: models.EmergencyContact{}},
+	{Handler: DeleteEmergencyContact, Tag: "Emergency Contacts", Summary: "Delete an emergency contact",
+		Response: messageResponse},
+	{Handler: VerifyEmergencyContact, Tag: "Emergency Contacts", Summary: "Record a successful verification",
+		Body: verificationRequest{}, Response: models.EmergencyContact{}},
+	{Handler: MarkContactUnreachable, Tag: "Emergency Contacts", Summary: "Record a failed verification",
+		Description: "Unreachable contacts are skipped by the on-call lookup and escalations until verified again.",
+		Body:        verificationRequest{}, Response: models.EmergencyContact{}},
+	{Handler: GetEmergencyContactVCard, Tag: "Emergency Contacts", Summary: "Get a contact as a vCard",
+		Produces: []string{"text/vcard"}},
+	{Handler: GetActiveContacts, Tag: "Emergency Contacts", Summary: "List active contacts",
+		Query:    []openapi.Param{param("verified", openapi.Boolean(), "Only contacts verified within CONTACT_VERIFICATION_DAYS")},
+		Response: []models.EmergencyContact{}, Export: true},
+	{Handler: GetContactsByServiceType, Tag: "Emergency Contacts", Summary: "List contacts of a service type",
+		Path:     []openapi.Param{param("service_type", openapi.Enum(emergencyServiceTypes...), "")},
+		Response: []models.EmergencyContact{}, Export: true},
+
+	// On-call and Escalation
+	{Handler: CreateOnCallShift, Tag: "On-call and Escalation", Summary: "Add an on-call shift",
+		Body: models.OnCallShift{}, Required: []string{"ends_at"},
+		Status: http.StatusCreated, Response: models.OnCallShift{}},
+	{Handler: GetOnCallShifts, Tag: "On-call and Escalation", Summary: "List on-call shifts",
+		Query: append([]openapi.Param{
+			serviceParam,
+			districtParam,
+			param("contact_id", openapi.ID(), ""),
+		}, periodParams...),
+		Response: []models.OnCallShift{}, Export: true},
+	{Handler: UpdateOnCallShift, Tag: "On-call and Escalation", Summary: "Update an on-call shift",
+		Body: models.OnCallShift{}, Response: models.OnCallShift{}},
+	{Handler: DeleteOnCallShift, Tag: "On-call and Escalation", Summary: "Delete an on-call shift",
+		Response: messageResponse},
+	{Handler: CreateEscalationChain, Tag: "On-call and Escalation", Summary: "Add an escalation chain",
+		Description: "Steps are called in the order given.",
+		Body:        models.EscalationChain{},
+		Status:      http.StatusCreated, Response: models.EscalationChain{}},
+	{Handler: GetEscalationChains, Tag: "On-call and Escalation", Summary: "List escalation chains",
+		Query:    []openapi.Param{serviceParam, districtParam},
+		Response: []models.EscalationChain{}, Export: true},
+	{Handler: GetEscalationChainByID, Tag: "On-call and Escalation", Summary: "Get an escalation chain",
+		Response: models.EscalationChain{}},
+	{Handler: UpdateEscalationChain, Tag: "On-call and Escalation", Summary: "Update an escalation chain",
+		Description: "Steps, when given, replace the whole chain.",
+		Body:        models.EscalationChain{}, Response: models.EscalationChain{}, Errors: []int{http.StatusConflict}},
+	{Handler: DeleteEscalationChain, Tag: "On-call and Escalation", Summary: "Delete an escalation chain without call-outs",
+		Response: messageResponse, Errors: []int{http.StatusConflict}},
+	{Handler: TriggerEscalation, Tag: "On-call and Escalation", Summary: "Start a call-out",
+		Description: "The first step is texted now; the escalation job moves on when nobody acknowledges in time.",
+		Body: struct {
+			Message string `json:"message" validate:"required"`
+		}{},
+		Status: http.StatusCreated, Response: &models.Escalation{}, Errors: []int{http.StatusConflict}},
+	{Handler: GetEscalations, Tag: "On-call and Escalation", Summary: "List escalations",
+		Query:    []openapi.Param{param("status", openapi.Enum("open", "acknowledged", "exhausted", "cancelled"), "")},
+		Response: []models.Escalation{}, Export: true},
+	{Handler: AcknowledgeEscalation, Tag: "On-call and Escalation", Summary: "Acknowledge an escalation",
+		Description: "The acknowledging person is taken from the login.",
+		Response:    models.Escalation{}, Errors: []int{http.StatusConflict}},
+	{Handler: CancelEscalation, Tag: "On-call and Escalation", Summary: "Cancel an escalation",
+		Response: models.Escalation{}, Errors: []int{http.StatusConflict}},
+
+	// Change Stream
+	{Handler: StreamEvents, Tag: "Change Stream", Summary: "Stream entity changes",
+		Description: "Server-Sent Events, or WebSocket when the request asks to upgrade. A reconnecting client sends " +
+			"Last-Event-ID or last_event_id and gets what it missed first, or a reset event. The replay starts " +
+			"a little before that ID, as events can commit out of order; skip IDs already handled.",
+		Query: []openapi.Param{
+			param("entity", openapi.String(), "Comma-separated, e.g. help_request,rescue_operation"),
+			param("priority", openapi.String(), "Comma-separated, e.g. critical,high"),
+			param("area", openapi.String(), "Part of the location, or district for contacts"),
+			param("last_event_id", openapi.String(), ""),
+		},
+		Produces: []string{"text/event-stream"}},
+
+	// Webhooks
+	{Handler: CreateWebhook, Tag: "Webhooks", Summary: "Subscribe a partner URL to change events",
+		Description: "event_types is comma-separated, e.g. \"help_request.*,rescue_operation.updated\" or \"*\". " +
+			"A secret is generated unless one is given; it is only shown in this answer.",
+		Body: struct {
+			Name       string `json:"name" validate:"required"`
+			URL        string `json:"url" validate:"required"`
+			EventTypes string `json:"event_types" validate:"required"`
+			Secret     string `json:"secret"`
+		}{},
+		Status: http.StatusCreated, Response: webhookWithSecret{}},
+	{Handler: GetWebhooks, Tag: "Webhooks", Summary: "List webhook subscriptions",
+		Response: []models.WebhookSubscription{}},
+	{Handler: GetWebhookByID, Tag: "Webhooks", Summary: "Get a webhook subscription",
+		Response: models.WebhookSubscription{}},
+	{Handler: UpdateWebhook, Tag: "Webhooks", Summary: "Update a webhook subscription",
+		Description: "Turning a disabled subscription back on clears its failure count.",
+		Body:        models.WebhookSubscription{}, Response: models.WebhookSubscription{}},
+	{Handler: DeleteWebhook, Tag: "Webhooks", Summary: "Delete a subscription and its delivery log",
+		Response: messageResponse},
+	{Handler: RotateWebhookSecret, Tag: "Webhooks", Summary: "Replace the signing secret",
+		Response: webhookWithSecret{}},
+	{Handler: GetWebhookDeliveries, Tag: "Webhooks", Summary: "The delivery log, newest first",
+		Query: []openapi.Param{
+			param("status", openapi.Enum("pending", "delivered", "failed"), ""),
+			param("limit", openapi.ID(), "100 by default"),
+		},
+		Response: []models.WebhookDelivery{}},
+	{Handler: GetWebhookDelivery, Tag: "Webhooks", Summary: "One delivery with its payload",
+		Response: webhookDeliveryDetail{}},
+	{Handler: RedeliverWebhook, Tag: "Webhooks", Summary: "Send a delivery's payload again",
+		Description: "As a new delivery, with the next pass of the webhook job.",
+		Status:      http.StatusAccepted, Response: models.WebhookDelivery{}, Errors: []int{http.StatusConflict}},
+
+	// Dashboard Statistics
+	{Handler: GetStats, Tag: "Dashboard Statistics", Summary: "Counts by status, priority, category and service type",
+		Response: statsOverview{}},
+	{Handler: GetStatsSeries, Tag: "Dashboard Statistics", Summary: "A time series for one metric",
+		Description: "Buckets line up with the clock in APP_TIMEZONE; the period defaults to the last 24 buckets.",
+		Path:        []openapi.Param{param("metric", openapi.Enum(statsMetrics()...), "")},
+		Query: append([]openapi.Param{
+			param("bucket", openapi.String(), "15m, 1h (the default), 6h, 1d, 1w, or minute, hour, day, week"),
+			param("status", openapi.String(), ""),
+			param("priority", openapi.String(), ""),
+			param("category", openapi.String(), ""),
+		}, periodParams...),
+		Response: statsSeries{}},
+
+	// Situation Reports
+	{Handler: CreateSitRep, Tag: "Situation Reports", Summary: "Generate and archive a SitRep",
+		Description: "For the last 24 hours unless from and to are given.",
+		Query:       periodParams, Status: http.StatusCreated, Response: sitRepResponse{}},
+	{Handler: GetSitReps, Tag: "Situation Reports", Summary: "The SitRep archive, newest first",
+		Query:    []openapi.Param{param("limit", openapi.ID(), "50 by default")},
+		Response: []models.SitRep{}},
+	{Handler: PreviewSitRep, Tag: "Situation Reports", Summary: "Generate a SitRep without archiving it",
+		Query:    append([]openapi.Param{sitRepFormatParam}, periodParams...),
+		Response: sitRepResponse{}, Produces: sitRepAnswers},
+	{Handler: GetSitRep, Tag: "Situation Reports", Summary: "An archived SitRep as it was generated",
+		Query:    []openapi.Param{sitRepFormatParam},
+		Response: sitRepResponse{}, Produces: sitRepAnswers},
+	{Handler: GetSitRepTemplate, Tag: "Situation Reports", Summary: "Get the md or html template",
+		Description: "custom is false for the built-in template.",
+		Path:        []openapi.Param{param("format", openapi.Enum("md", "html"), "")},
+		Response: struct {
+			models.SitRepTemplate
+			Custom bool `json:"custom"`
+		}{}},
+	{Handler: UpdateSitRepTemplate, Tag: "Situation Reports", Summary: "Replace the md or html template",
+		Description: "Go template syntax over the report. The template is tried against sample figures first. " +
+			"Archived SitReps keep the text they were generated with.",
+		Path: []openapi.Param{param("format", openapi.Enum("md", "html"), "")},
+		Body: struct {
+			Body string `json:"body" validate:"required"`
+		}{},
+		Required: []string{"body"}, Response: models.SitRepTemplate{}},
+	{Handler: ResetSitRepTemplate, Tag: "Situation Reports", Summary: "Go back to the built-in template",
+		Path:     []openapi.Param{param("format", openapi.Enum("md", "html"), "")},
+		Response: messageResponse},
+}
@@ -2,60 +2,117 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
-// CreateReliefSupply - POSt
-// http://localhost:8081/api/v1/relief-supplies
-func CreateReliefSupply(w http.ResponseWriter, r *http.Request) {
+// prepareReliefSupply validates a new lot, stores its unit as the catalogue
+// code and links its donor; used by create and by bulk imports. Field
+// errors come back on their own, other failures as a status and message.
+func prepareReliefSupply(db *gorm.DB, supply *models.ReliefSupply) (int, string, []apierror.FieldError) {
 
-	var supply models.ReliefSupply
+	// Label codes are always generated, and the E.164 form comes from the donor phone
+	supply.LabelCode = ""
+	supply.DonorPhoneE164 = ""
 
-	err := json.NewDecoder(r.Body).Decode(&supply)
-	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
-		return
+	// Lots kept in a warehouse take the warehouse name as their location
+	if supply.WarehouseID != nil {
+		var warehouse models.Warehouse
+		if err := db.First(&warehouse, *supply.WarehouseID).Error; err != nil || !warehouse.IsActive {
+			return http.StatusBadRequest, "Warehouse not found or not active", nil
+		}
+		if supply.Location == "" {
+			supply.Location = warehouse.Name
+		}
 	}
 
-	//validation
-	if supply.ItemName == "" || supply.Category == "" || supply.Quantity <= 0 || supply.Unit == "" || supply.Location == "" {
-		http.Error(w, `{"error":"Item name, category, quantity, unit and location are required fields"}`, http.StatusBadRequest)
-		return
+	// Required fields, a quantity of at least one, and the category and status values
+	if fields := validation.Struct(supply); fields != nil {
+		return http.StatusBadRequest, "", fields
+	}
+
+	// Store the catalogue code so "Kgs" and "kg" are the same unit
+	unit, status, msg := normalizeUnit(db, supply.Unit)
+	if status != 0 {
+		return status, msg, nil
 	}
+	supply.Unit = unit
 
-	// Validate category
-	validCategories := []string{"Food", "Medical", "Clothing", "Shelter", "Other"}
+	if supply.Status == "" {
+		supply.Status = "Available"
+	}
 
-	isValidCategory := false
-	for _, cat := range validCategories { //cat represents each value ("Food", "Medical", etc.)
-		if supply.Category == cat {
-			isValidCategory = true
-			break
-		}
+	// "Distributed" is derived from allocations, never set by hand
+	if supply.Status == "Distributed" {
+		return http.StatusBadRequest, "", []apierror.FieldError{validation.Field("status", "oneof",
+			"Status Distributed is set automatically when allocations are delivered")}
 	}
 
-	if !isValidCategory {
-		http.Error(w, `{"error":"Invalid category. Use: Food, Medical, Clothing, Shelter, Other"}`, http.StatusBadRequest)
-		return
+	status, msg = linkDonor(db, supply)
+	return status, msg, nil
+}
+
+// createReliefSupply saves a prepared lot and records its opening quantity
+// in the ledger as a receipt
+func createReliefSupply(tx *gorm.DB, supply *models.ReliefSupply, actor string) error {
+	if err := tx.Create(supply).Error; err != nil {
+		return err
 	}
 
-	if supply.Status != "Available" &&
-		supply.Status != "Distributed" &&
-		supply.Status != "Expired" {
-		http.Error(w, `{"error":"Invalid Status"}`, http.StatusBadRequest)
+	receipt := &models.StockMovement{
+		ReliefSupplyID: supply.ID,
+		Type:           inventory.Receipt,
+		Quantity:       supply.Quantity,
+		Unit:           supply.Unit,
+		Actor:          actor,
+		Reason:         "Initial receipt",
+	}
+	return inventory.Record(tx, receipt)
+}
+
+// CreateReliefSupply - POSt
+// http://localhost:8081/api/v1/relief-supplies
+func CreateReliefSupply(w http.ResponseWriter, r *http.Request) {
+
+	var supply models.ReliefSupply
+
+	err := json.NewDecoder(r.Body).Decode(&supply)
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
 		return
 	}
 
-	// Save to database
+	// Prepare in the same transaction so a new donor record is not left
+	// behind when the supply fails to save; the opening quantity goes
+	// through the ledger as a receipt
 	db := config.GetDB()
-	result := db.Create(&supply)
-	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to create relief supply"}`, http.StatusInternalServerError)
+	var fields []apierror.FieldError
+	status, msg := http.StatusOK, ""
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg, fields = prepareReliefSupply(tx, &supply); fields != nil || status != 0 {
+			return gorm.ErrInvalidData
+		}
+		return createReliefSupply(tx, &supply, requestActor(r))
+	})
+	if fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to create relief supply"
+		}
+		apierror.Write(w, status, msg)
 		return
 	}
 
@@ -75,9 +132,15 @@ func GetAllReliefSupplies(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 
 	// Fetch all supplies ordered by newest first
-	result := db.Order("created_at DESC").Find(&supplies)
+	query := db.Order("created_at DESC")
+	if exportList(w, r, query, &models.ReliefSupply{}, "relief-supplies") {
+		return
+	}
+
+	result := query.Find(&supplies)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch relief supplies"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch relief supplies")
+		return
 	}
 
 	// return empty array if not any records
@@ -104,7 +167,7 @@ func GetReliefSupplyById(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -114,7 +177,8 @@ func GetReliefSupplyById(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&supply, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Relief supply not found"}`, http.StatusBadRequest)
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+		return
 	}
 
 	//// Send success response
@@ -131,7 +195,7 @@ func UpdateReliefSupply(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"]) // id came by string and convert to  Int
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -141,7 +205,7 @@ func UpdateReliefSupply(w http.ResponseWriter, r *http.Request) {
 	result := db.First(&existingSupply, id)
 
 	if result.Error != nil { //nil → means NO error
-		http.Error(w, `{"error":"Relief supply not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
 		return
 	}
 
@@ -150,7 +214,13 @@ func UpdateReliefSupply(w http.ResponseWriter, r *http.Request) {
 	err = json.NewDecoder(r.Body).Decode(&updateData) //json -> go struct
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&updateData); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -160,47 +230,74 @@ func UpdateReliefSupply(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateData.Category != "" {
-		validCategories := []string{"Food", "Medical", "Clothing", "Shelter", "Other"}
-		isValid := false
-		for _, item := range validCategories {
-			if updateData.Category == item {
-				isValid = true
-				break
-			}
+		existingSupply.Category = updateData.Category
+	}
 
+	if updateData.Unit != "" && updateData.Unit != existingSupply.Unit {
+		unit, status, msg := normalizeUnit(db, updateData.Unit)
+		if status != 0 {
+			apierror.Write(w, status, msg)
+			return
 		}
-
-		if !isValid {
-			http.Error(w, `{"error":"Invalid category"}`, http.StatusBadRequest)
+		if unit != existingSupply.Unit {
+			// Changing the unit would reinterpret every movement in the ledger
+			apierror.Write(w, http.StatusBadRequest, "Unit cannot be changed once stock is recorded")
 			return
 		}
-		existingSupply.Category = updateData.Category
 	}
 
-	if updateData.Quantity > 0 {
-		existingSupply.Quantity = updateData.Quantity
+	// A new donor relinks the supply, unless a receipt already thanked the old one
+	donorChanged := updateData.DonorID != nil && (existingSupply.DonorID == nil || *existingSupply.DonorID != *updateData.DonorID)
+	if updateData.DonorID == nil {
+		donorChanged = (updateData.DonorName != "" && updateData.DonorName != existingSupply.DonorName) ||
+			(updateData.DonorPhone != "" && updateData.DonorPhone != existingSupply.DonorPhone)
 	}
 
-	if updateData.Unit != "" {
-		existingSupply.Unit = updateData.Unit
-	}
-
-	if updateData.DonorName != "" {
-		existingSupply.DonorName = updateData.DonorName
-	}
+	if donorChanged {
+		var receipted int64
+		db.Model(&models.DonationReceiptLine{}).Where("relief_supply_id = ?", existingSupply.ID).Count(&receipted)
+		if receipted > 0 {
+			apierror.Write(w, http.StatusConflict, "Supply is already acknowledged on a donation receipt")
+			return
+		}
 
-	if updateData.DonorPhone != "" {
-		existingSupply.DonorPhone = updateData.DonorPhone
+		existingSupply.DonorID = updateData.DonorID
+		if updateData.DonorName != "" {
+			existingSupply.DonorName = updateData.DonorName
+		}
+		if updateData.DonorPhone != "" {
+			existingSupply.DonorPhone = updateData.DonorPhone
+		}
 	}
 
 	if updateData.Location != "" {
 		existingSupply.Location = updateData.Location
 	}
 
-	if updateData.Status != "" {
-		if updateData.Status != "Available" &&
-			updateData.Status != "Distributed" &&
-			updateData.Status != "Expired" {
+	// Stock changes warehouse through transfer orders; only unassigned lots can be placed directly
+	if updateData.WarehouseID != nil && (existingSupply.WarehouseID == nil || *existingSupply.WarehouseID != *updateData.WarehouseID) {
+		if existingSupply.WarehouseID != nil && existingSupply.Quantity > 0 {
+			apierror.Write(w, http.StatusBadRequest, "Use a transfer order to move stock between warehouses")
+			return
+		}
+
+		var warehouse models.Warehouse
+		if err := db.First(&warehouse, *updateData.WarehouseID).Error; err != nil || !warehouse.IsActive {
+			apierror.Write(w, http.StatusBadRequest, "Warehouse not found or not active")
+			return
+		}
+		existingSupply.WarehouseID = &warehouse.ID
+		if updateData.Location == "" {
+			existingSupply.Location = warehouse.Name
+		}
+	}
+
+	statusChanged := updateData.Status != "" && updateData.Status != existingSupply.Status
+	if statusChanged {
+		// "Distributed" is derived from allocations, never set by hand
+		if updateData.Status == "Distributed" {
+			apierror.InvalidField(w, "status", "oneof", "Status Distributed is set automatically when allocations are delivered")
+			return
 		}
 		existingSupply.Status = updateData.Status
 	}
@@ -213,10 +310,48 @@ func UpdateReliefSupply(w http.ResponseWriter, r *http.Request) {
 		existingSupply.ExpiryDate = updateData.ExpiryDate
 	}
 
-	// Save updates
-	result = db.Save(&existingSupply)
-	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to update relief supply"}`, http.StatusInternalServerError)
+	// Save updates; a new quantity is recorded as an adjustment, never overwritten
+	status, msg := http.StatusOK, ""
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if donorChanged {
+			if status, msg = linkDonor(tx, &existingSupply); status != 0 {
+				return gorm.ErrInvalidData
+			}
+		}
+
+		if updateData.Quantity > 0 && updateData.Quantity != existingSupply.Quantity {
+			adjustment := &models.StockMovement{
+				ReliefSupplyID: existingSupply.ID,
+				Type:           inventory.Adjustment,
+				Quantity:       updateData.Quantity - existingSupply.Quantity,
+				Actor:          requestActor(r),
+				Reason:         "Quantity corrected via update",
+			}
+			if err := inventory.Record(tx, adjustment); err != nil {
+				return err
+			}
+			existingSupply.Quantity = adjustment.BalanceAfter
+		}
+
+		// Recording the adjustment refreshes the status from the new
+		// balance; only a status the client changed overrides it
+		omit := []string{"quantity"}
+		if !statusChanged {
+			omit = append(omit, "status")
+		}
+		if err := tx.Omit(omit...).Save(&existingSupply).Error; err != nil {
+			return err
+		}
+		return tx.First(&existingSupply, existingSupply.ID).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to update relief supply"
+			if errors.Is(err, inventory.ErrNegativeStock) || errors.Is(err, inventory.ErrReservedStock) {
+				status, msg = ledgerError(err)
+			}
+		}
+		apierror.Write(w, status, msg)
 		return
 	}
 
@@ -236,7 +371,7 @@ func DeleteReliefSupply(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -247,14 +382,20 @@ func DeleteReliefSupply(w http.ResponseWriter, r *http.Request) {
 	//Store the database result inside result variable
 	result := db.First(&supply, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Relief supply not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+		return
+	}
+
+	// Stock on hand has to leave through the ledger first
+	if supply.Quantity > 0 {
+		apierror.Write(w, http.StatusConflict, "Relief supply still has stock on hand. Issue or write it off first")
 		return
 	}
 
 	// Delete supply
 	result = db.Delete(&supply)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to delete relief supply"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete relief supply")
 		return
 	}
 
@@ -274,10 +415,15 @@ func GetSuppliesByCategory(w http.ResponseWriter, r *http.Request) {
 	var supplies []models.ReliefSupply
 	db := config.GetDB()
 
-	result := db.Where("category = ?", category).Order("created_at DESC").Find(&supplies)
+	query := db.Where("category = ?", category).Order("created_at DESC")
+	if exportList(w, r, query, &models.ReliefSupply{}, strings.ToLower(category)+"-relief-supplies") {
+		return
+	}
+
+	result := query.Find(&supplies)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch supplies by category"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch supplies by category")
 		return
 	}
 
@@ -302,9 +448,14 @@ func GetAvailableSupplies(w http.ResponseWriter, r *http.Request) {
 	var supplies []models.ReliefSupply
 	db := config.GetDB()
 
-	result := db.Where("status = ?", "Available").Order("created_at DESC").Find(&supplies)
+	query := db.Where("status = ?", "Available").Order("created_at DESC")
+	if exportList(w, r, query, &models.ReliefSupply{}, "available-relief-supplies") {
+		return
+	}
+
+	result := query.Find(&supplies)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch available supplies"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch available supplies")
 		return
 	}
 
@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/forecast"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// validateThreshold checks a reorder threshold whose fields have been
+// checked, stores the catalogue unit code and fills in the category from
+// existing lots of the item
+func validateThreshold(db *gorm.DB, t *models.ReorderThreshold) (int, string) {
+	if t.TargetQuantity <= t.ReorderPoint {
+		return http.StatusBadRequest, "target_quantity must be greater than reorder_point"
+	}
+
+	unit, status, msg := normalizeUnit(db, t.Unit)
+	if status != 0 {
+		return status, msg
+	}
+	t.Unit = unit
+
+	if t.Category == "" {
+		var lot models.ReliefSupply
+		if err := db.Where("item_name = ?", t.ItemName).Order("id DESC").First(&lot).Error; err == nil {
+			t.Category = lot.Category
+		}
+	}
+	if t.Category == "" {
+		return http.StatusBadRequest, "category is required for an item with no stock on record"
+	}
+
+	if t.WarehouseID != nil {
+		if err := db.First(&models.Warehouse{}, *t.WarehouseID).Error; err != nil {
+			return http.StatusBadRequest, "Warehouse not found"
+		}
+	}
+
+	// One threshold per item, unit and warehouse
+	query := db.Model(&models.ReorderThreshold{}).Where("item_name = ? AND unit = ? AND id <> ?", t.ItemName, t.Unit, t.ID)
+	if t.WarehouseID != nil {
+		query = query.Where("warehouse_id = ?", *t.WarehouseID)
+	} else {
+		query = query.Where("warehouse_id IS NULL")
+	}
+	var count int64
+	query.Count(&count)
+	if count > 0 {
+		return http.StatusConflict, "A threshold already exists for this item and warehouse"
+	}
+
+	return 0, ""
+}
+
+// CreateReorderThreshold - POST
+// {"item_name":"Drinking Water","unit":"L","warehouse_id":2,"reorder_point":500,"target_quantity":3000,"daily_per_person":3}
+// http://localhost:8081/api/v1/reorder-thresholds
+func CreateReorderThreshold(w http.ResponseWriter, r *http.Request) {
+
+	var threshold models.ReorderThreshold
+	if err := json.NewDecoder(r.Body).Decode(&threshold); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	threshold.ID = 0
+	threshold.AlertedAt = nil
+
+	if fields := validation.Struct(&threshold); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	if status, msg := validateThreshold(db, &threshold); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Create(&threshold).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create reorder threshold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(threshold)
+
+}
+
+// GetReorderThresholds - GET
+// Optional filter: ?warehouse_id=
+func GetReorderThresholds(w http.ResponseWriter, r *http.Request) {
+
+	thresholds := []models.ReorderThreshold{}
+	db := config.GetDB()
+
+	query := db.Order("category, item_name")
+	if value := r.URL.Query().Get("warehouse_id"); value != "" {
+		query = query.Where("warehouse_id = ?", value)
+	}
+
+	if exportList(w, r, query, &models.ReorderThreshold{}, "reorder-thresholds") {
+		return
+	}
+
+	if err := query.Find(&thresholds).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch reorder thresholds")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(thresholds)
+
+}
+
+// UpdateReorderThreshold - PUT
+func UpdateReorderThreshold(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var threshold models.ReorderThreshold
+	db := config.GetDB()
+
+	if err := db.First(&threshold, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Reorder threshold not found")
+		return
+	}
+
+	var updateData models.ReorderThreshold
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.Category != "" {
+		threshold.Category = updateData.Category
+	}
+	if updateData.ReorderPoint != 0 {
+		threshold.ReorderPoint = updateData.ReorderPoint
+	}
+	if updateData.TargetQuantity != 0 {
+		threshold.TargetQuantity = updateData.TargetQuantity
+	}
+	if updateData.DailyPerPerson != 0 {
+		threshold.DailyPerPerson = updateData.DailyPerPerson
+	}
+
+	if fields := validation.Struct(&threshold); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if status, msg := validateThreshold(db, &threshold); status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	if err := db.Save(&threshold).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update reorder threshold")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(threshold)
+
+}
+
+// DeleteReorderThreshold - DELETE
+func DeleteReorderThreshold(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	db := config.GetDB()
+	result := db.Delete(&models.ReorderThreshold{}, id)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete reorder threshold")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Write(w, http.StatusNotFound, "Reorder threshold not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Reorder threshold deleted successfully"}`))
+
+}
+
+// thresholdWarehouse reads the optional ?warehouse_id= filter
+func thresholdWarehouse(r *http.Request) (*uint, bool) {
+	value := r.URL.Query().Get("warehouse_id")
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, false
+	}
+	id := uint(parsed)
+	return &id, true
+}
+
+// GetStockForecast - GET
+// Stock, demand and days of cover for every reorder threshold
+// http://localhost:8081/api/v1/stock/forecast?warehouse_id=2
+func GetStockForecast(w http.ResponseWriter, r *http.Request) {
+
+	warehouseID, ok := thresholdWarehouse(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid warehouse_id")
+		return
+	}
+
+	forecasts, err := forecast.All(config.GetDB(), warehouseID, forecast.LoadSettings())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute stock forecast")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(forecasts)
+
+}
+
+// GetShortageReport - GET
+// Categories with items to reorder, most urgent category first
+// http://localhost:8081/api/v1/stock/shortages
+func GetShortageReport(w http.ResponseWriter, r *http.Request) {
+
+	warehouseID, ok := thresholdWarehouse(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid warehouse_id")
+		return
+	}
+
+	forecasts, err := forecast.All(config.GetDB(), warehouseID, forecast.LoadSettings())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute stock forecast")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(forecast.Shortages(forecasts))
+
+}
@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/config"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,13 +22,13 @@ func CreateRescueOperation(w http.ResponseWriter, r *http.Request) {
 	// Frontend ---> JSON ---> Backend ---> Decode ---> Go struct
 	err := json.NewDecoder(r.Body).Decode(&operation)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
 		return
 	}
 
-	// Required fields
-	if operation.OperationName == "" || operation.TeamSize <= 0 || operation.Location == "" {
-		http.Error(w, `{"error":"Missing required fields"}`, http.StatusBadRequest)
+	// Required fields and the status and priority values
+	if fields := validation.Struct(&operation); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -35,36 +37,11 @@ func CreateRescueOperation(w http.ResponseWriter, r *http.Request) {
 		operation.HelpRequestID = 1
 	}
 
-	//validation status
-	validStatuses := []string{"initiated", "in-progress", "completed", "failed"}
-	isValidStatus := false
-	for _, status := range validStatuses {
-		if operation.Status == status {
-			isValidStatus = true
-			break
-		}
-	}
 	if operation.Status == "" {
 		operation.Status = "initiated"
-	} else if !isValidStatus {
-		http.Error(w, `{"error":"Invalid status value"}`, http.StatusBadRequest)
-		return
 	}
-
-	//validation priority
-	validPriorities := []string{"low", "medium", "high", "critical"}
-	isValidPriority := false
-	for _, priority := range validPriorities {
-		if operation.Priority == priority {
-			isValidPriority = true
-			break
-		}
-	}
-
 	if operation.Priority == "" {
 		operation.Priority = "medium" // set default
-	} else if !isValidPriority {
-		http.Error(w, `{"error":"Invalid priority value"}`, http.StatusBadRequest)
 	}
 
 	// Set start time if not provided
@@ -76,7 +53,7 @@ func CreateRescueOperation(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 	result := db.Create(&operation) //db.Create() = Add new record to database
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to create rescue operation"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create rescue operation")
 		return
 	}
 
@@ -98,11 +75,16 @@ func GetAllRescueOperations(w http.ResponseWriter, r *http.Request) {
 	var operations []models.RescueOperation
 
 	db := config.GetDB()
-	result := db.Order("CASE WHEN priority = 'critical' THEN 1 WHEN priority = 'high' THEN 2 WHEN priority = 'medium' THEN 3 ELSE 4 END, start_time DESC").Find(&operations)
+	query := db.Order("CASE WHEN priority = 'critical' THEN 1 WHEN priority = 'high' THEN 2 WHEN priority = 'medium' THEN 3 ELSE 4 END, start_time DESC")
+	if exportList(w, r, query, &models.RescueOperation{}, "rescue-operations") {
+		return
+	}
+
+	result := query.Find(&operations)
 
 	// Fetch all operations ordered by priority (critical first) then start time
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch rescue operations"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch rescue operations")
 		return
 
 	}
@@ -132,7 +114,7 @@ func GetRescueOperationByID(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -142,7 +124,7 @@ func GetRescueOperationByID(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&operation, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Rescue operation not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Rescue operation not found")
 		return
 	}
 
@@ -157,7 +139,7 @@ func UpdateRescueOperation(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -165,13 +147,19 @@ func UpdateRescueOperation(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 
 	if err := db.First(&existing, id).Error; err != nil {
-		http.Error(w, `{"error":"Rescue operation not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Rescue operation not found")
 		return
 	}
 
 	var update models.RescueOperation
 	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Check the values given; fields left out keep their stored value
+	if fields := validation.Partial(&update); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -189,18 +177,7 @@ func UpdateRescueOperation(w http.ResponseWriter, r *http.Request) {
 		existing.Location = update.Location
 	}
 
-	// Validate status
 	if update.Status != "" {
-		valid := map[string]bool{
-			"initiated":   true,
-			"in-progress": true,
-			"completed":   true,
-			"failed":      true,
-		}
-		if !valid[update.Status] {
-			http.Error(w, `{"error":"Invalid status value"}`, http.StatusBadRequest)
-			return
-		}
 		existing.Status = update.Status
 
 		if (update.Status == "completed" || update.Status == "failed") && existing.EndTime == nil {
@@ -209,22 +186,16 @@ func UpdateRescueOperation(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Validate priority
 	if update.Priority != "" {
-		valid := map[string]bool{
-			"low":      true,
-			"medium":   true,
-			"high":     true,
-			"critical": true,
-		}
-		if !valid[update.Priority] {
-			http.Error(w, `{"error":"Invalid priority"}`, http.StatusBadRequest)
-			return
-		}
 		existing.Priority = update.Priority
 	}
 
-	if update.PeopleRescued >= 0 {
+	// Once evacuees are registered the count comes from the registry
+	var evacueeCount int64
+	db.Model(&models.Evacuee{}).Where("rescue_operation_id = ?", existing.ID).Count(&evacueeCount)
+	if evacueeCount > 0 {
+		existing.PeopleRescued = int(evacueeCount)
+	} else if update.PeopleRescued >= 0 {
 		existing.PeopleRescued = update.PeopleRescued
 	}
 
@@ -233,7 +204,7 @@ func UpdateRescueOperation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := db.Save(&existing).Error; err != nil {
-		http.Error(w, `{"error":"Failed to update rescue operation"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update rescue operation")
 		return
 	}
 
@@ -250,7 +221,7 @@ func DeleteRescueOperation(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -260,14 +231,22 @@ func DeleteRescueOperation(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&operation, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to find rescue operation"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Failed to find rescue operation")
+		return
+	}
+
+	//Evacuees cannot outlive the operation that rescued them
+	var evacueeCount int64
+	db.Model(&models.Evacuee{}).Where("rescue_operation_id = ?", operation.ID).Count(&evacueeCount)
+	if evacueeCount > 0 {
+		apierror.Write(w, http.StatusConflict, "Rescue operation has registered evacuees")
 		return
 	}
 
 	//Delete operation
 	result = db.Delete(&operation)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to delete rescue operation"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete rescue operation")
 		return
 	}
 
@@ -287,10 +266,15 @@ func GetActiveOperations(w http.ResponseWriter, r *http.Request) {
 	  IN - Means “is this value inside this list?”
 	*/
 	//db eke initiated and in-progress kyna values thynwad balanawa status eke thynwa nan
-	result := db.Where("status IN ?", []string{"initiated", "in-progress"}).Order("priority DESC, start_time DESC").Find(&operations)
+	query := db.Where("status IN ?", []string{"initiated", "in-progress"}).Order("priority DESC, start_time DESC")
+	if exportList(w, r, query, &models.RescueOperation{}, "active-rescue-operations") {
+		return
+	}
+
+	result := query.Find(&operations)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch active rescue operations"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch active rescue operations")
 		return
 	}
 
@@ -326,16 +310,21 @@ func GetOperationsByPriority(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !isValid {
-		http.Error(w, `{"error":"Invalid priority"}`, http.StatusBadRequest)
+		apierror.Write(w, http.StatusBadRequest, "Invalid priority")
 		return
 	}
 
 	var operations []models.RescueOperation
 	db := config.GetDB()
-	result := db.Where("priority = ?", priority).Order("start_time DESC").Find(&operations)
+	query := db.Where("priority = ?", priority).Order("start_time DESC")
+	if exportList(w, r, query, &models.RescueOperation{}, priority+"-priority-rescue-operations") {
+		return
+	}
+
+	result := query.Find(&operations)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failes to fetch operations by priority"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failes to fetch operations by priority")
 		return
 	}
 
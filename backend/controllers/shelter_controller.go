@@ -0,0 +1,633 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/notify"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shelterAlertPercent is the occupancy level (percent of capacity) that counts as near capacity
+func shelterAlertPercent() float64 {
+	percent, err := strconv.ParseFloat(os.Getenv("SHELTER_ALERT_PERCENT"), 64)
+	if err != nil || percent <= 0 {
+		return 90
+	}
+	return percent
+}
+
+// isNearCapacity reports whether the shelter has reached the alert level
+func isNearCapacity(shelter *models.Shelter) bool {
+	if shelter.Capacity <= 0 {
+		return false
+	}
+	return float64(shelter.CurrentOccupancy)*100/float64(shelter.Capacity) >= shelterAlertPercent()
+}
+
+// syncShelterOccupancy recounts evacuees currently checked in to the shelter
+func syncShelterOccupancy(db *gorm.DB, shelter *models.Shelter) error {
+	var count int64
+	err := db.Model(&models.Evacuee{}).
+		Where("shelter_id = ? AND checked_in_at IS NOT NULL AND checked_out_at IS NULL", shelter.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	shelter.CurrentOccupancy = int(count)
+	return db.Model(shelter).Update("current_occupancy", count).Error
+}
+
+// sendShelterAlert tells the shelter manager, or SHELTER_ALERT_PHONE when the
+// shelter has no manager phone, that the shelter is near capacity. Without
+// either the alert is only logged.
+func sendShelterAlert(shelter *models.Shelter) {
+	message := fmt.Sprintf("Flood Relief: shelter %s is near capacity with %d of %d places taken.",
+		shelter.Name, shelter.CurrentOccupancy, shelter.Capacity)
+
+	to := shelter.ManagerPhoneE164
+	if to == "" {
+		to = phone.Key(os.Getenv("SHELTER_ALERT_PHONE"))
+	}
+
+	if to == "" {
+		log.Println("⚠️ ", message)
+	} else if err := notify.SendSMS(to, message); err != nil {
+		log.Println("❌ Shelter capacity alert failed:", err)
+	}
+}
+
+// distanceKm returns the great-circle distance between two points (haversine formula)
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371.0
+
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// validateShelter checks the coordinates of a shelter, which the validate
+// tags cannot express
+func validateShelter(shelter *models.Shelter) []apierror.FieldError {
+	var fields []apierror.FieldError
+	if shelter.Latitude < -90 || shelter.Latitude > 90 {
+		fields = append(fields, validation.Field("latitude", "invalid", "latitude must be between -90 and 90"))
+	}
+	if shelter.Longitude < -180 || shelter.Longitude > 180 {
+		fields = append(fields, validation.Field("longitude", "invalid", "longitude must be between -180 and 180"))
+	}
+	return fields
+}
+
+// CreateShelter - POST
+// http://localhost:8081/api/v1/shelters
+func CreateShelter(w http.ResponseWriter, r *http.Request) {
+
+	var shelter models.Shelter
+
+	if err := json.NewDecoder(r.Body).Decode(&shelter); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	fields := append(validation.Struct(&shelter), validateShelter(&shelter)...)
+	if fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if shelter.Status == "" {
+		shelter.Status = "open"
+	}
+
+	shelter.ManagerPhoneE164 = ""
+	if shelter.ManagerPhone != "" {
+		number, fields := phone.Field("manager_phone", shelter.ManagerPhone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		shelter.ManagerPhone, shelter.ManagerPhoneE164 = number.Display, number.E164
+	}
+
+	// Occupancy is driven by check-in and check-out only
+	shelter.CurrentOccupancy = 0
+
+	db := config.GetDB()
+	if err := db.Create(&shelter).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create shelter")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shelter)
+
+}
+
+// GetAllShelters - GET
+func GetAllShelters(w http.ResponseWriter, r *http.Request) {
+
+	var shelters []models.Shelter
+	db := config.GetDB()
+
+	query := db.Order("name ASC")
+	if exportList(w, r, query, &models.Shelter{}, "shelters") {
+		return
+	}
+
+	if err := query.Find(&shelters).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch shelters")
+		return
+	}
+
+	if len(shelters) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shelters)
+
+}
+
+// GetShelterByID - GET
+func GetShelterByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var shelter models.Shelter
+	db := config.GetDB()
+
+	if err := db.First(&shelter, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Shelter not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shelter)
+
+}
+
+// UpdateShelter - PUT
+func UpdateShelter(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var existing models.Shelter
+	db := config.GetDB()
+
+	if err := db.First(&existing, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Shelter not found")
+		return
+	}
+
+	// Decode into a map as well so has_water=false can be told apart from missing
+	var update models.Shelter
+	var present map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &update) != nil || json.Unmarshal(raw, &present) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	fields := append(validation.Partial(&update), validateShelter(&update)...)
+	if fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	// Update fields if provided
+	if update.Name != "" {
+		existing.Name = update.Name
+	}
+	if update.Address != "" {
+		existing.Address = update.Address
+	}
+	if update.District != "" {
+		existing.District = update.District
+	}
+	if update.Latitude != 0 || update.Longitude != 0 {
+		existing.Latitude = update.Latitude
+		existing.Longitude = update.Longitude
+	}
+	if update.Capacity > 0 {
+		if update.Capacity < existing.CurrentOccupancy {
+			apierror.Write(w, http.StatusBadRequest, "Capacity cannot be lower than current occupancy")
+			return
+		}
+		existing.Capacity = update.Capacity
+	}
+	if update.ManagerName != "" {
+		existing.ManagerName = update.ManagerName
+	}
+	if update.ManagerPhone != "" {
+		number, fields := phone.Field("manager_phone", update.ManagerPhone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existing.ManagerPhone, existing.ManagerPhoneE164 = number.Display, number.E164
+	}
+	if update.Status != "" {
+		existing.Status = update.Status
+	}
+
+	if _, ok := present["has_water"]; ok {
+		existing.HasWater = update.HasWater
+	}
+	if _, ok := present["has_toilets"]; ok {
+		existing.HasToilets = update.HasToilets
+	}
+	if _, ok := present["has_power"]; ok {
+		existing.HasPower = update.HasPower
+	}
+	if _, ok := present["has_medical"]; ok {
+		existing.HasMedical = update.HasMedical
+	}
+
+	if err := db.Save(&existing).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update shelter")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(existing)
+
+}
+
+// DeleteShelter - DELETE
+func DeleteShelter(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var shelter models.Shelter
+	db := config.GetDB()
+
+	if err := db.First(&shelter, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Shelter not found")
+		return
+	}
+
+	if shelter.CurrentOccupancy > 0 {
+		apierror.Write(w, http.StatusConflict, "Shelter still has checked-in evacuees")
+		return
+	}
+
+	if err := db.Delete(&shelter).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete shelter")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Shelter deleted successfully"}`))
+
+}
+
+// CheckInEvacuee - POST
+// http://localhost:8081/api/v1/shelters/{id}/check-in  {"evacuee_id": 12}
+func CheckInEvacuee(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body struct {
+		EvacueeID uint `json:"evacuee_id" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	var shelter models.Shelter
+	var evacuee models.Evacuee
+	wasNearCapacity := false
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the shelter row so two check-ins cannot take the last place
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shelter, id).Error; err != nil {
+			status, msg = http.StatusNotFound, "Shelter not found"
+			return err
+		}
+		// and the evacuee row so they cannot be checked in to two shelters
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&evacuee, body.EvacueeID).Error; err != nil {
+			status, msg = http.StatusNotFound, "Evacuee not found"
+			return err
+		}
+
+		if shelter.Status != "open" {
+			status, msg = http.StatusConflict, "Shelter is closed"
+			return gorm.ErrInvalidData
+		}
+		if evacuee.ShelterID != nil && evacuee.CheckedOutAt == nil && evacuee.CheckedInAt != nil {
+			status, msg = http.StatusConflict, "Evacuee is already checked in to a shelter"
+			return gorm.ErrInvalidData
+		}
+		if shelter.CurrentOccupancy >= shelter.Capacity {
+			status, msg = http.StatusConflict, "Shelter is full"
+			return gorm.ErrInvalidData
+		}
+		wasNearCapacity = isNearCapacity(&shelter)
+
+		now := time.Now()
+		evacuee.ShelterID = &shelter.ID
+		evacuee.CheckedInAt = &now
+		evacuee.CheckedOutAt = nil
+		evacuee.DestinationType = "shelter"
+		evacuee.DestinationName = shelter.Name
+		if err := tx.Save(&evacuee).Error; err != nil {
+			status, msg = http.StatusInternalServerError, "Failed to check in evacuee"
+			return err
+		}
+
+		return syncShelterOccupancy(tx, &shelter)
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to check in evacuee"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	// Alert once, on the check-in that reaches the alert level
+	if !wasNearCapacity && isNearCapacity(&shelter) {
+		sendShelterAlert(&shelter)
+	}
+
+	response := map[string]interface{}{
+		"shelter":       shelter,
+		"evacuee":       evacuee,
+		"near_capacity": isNearCapacity(&shelter),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// CheckOutEvacuee - POST
+// http://localhost:8081/api/v1/shelters/{id}/check-out  {"evacuee_id": 12}
+func CheckOutEvacuee(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body struct {
+		EvacueeID uint `json:"evacuee_id" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	var shelter models.Shelter
+	var evacuee models.Evacuee
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shelter, id).Error; err != nil {
+			status, msg = http.StatusNotFound, "Shelter not found"
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&evacuee, body.EvacueeID).Error; err != nil {
+			status, msg = http.StatusNotFound, "Evacuee not found"
+			return err
+		}
+
+		if evacuee.ShelterID == nil || *evacuee.ShelterID != shelter.ID || evacuee.CheckedOutAt != nil {
+			status, msg = http.StatusConflict, "Evacuee is not checked in to this shelter"
+			return gorm.ErrInvalidData
+		}
+
+		now := time.Now()
+		evacuee.CheckedOutAt = &now
+		if err := tx.Save(&evacuee).Error; err != nil {
+			status, msg = http.StatusInternalServerError, "Failed to check out evacuee"
+			return err
+		}
+
+		return syncShelterOccupancy(tx, &shelter)
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to check out evacuee"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	response := map[string]interface{}{
+		"shelter": shelter,
+		"evacuee": evacuee,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetShelterEvacuees - GET
+// Evacuees currently checked in to the shelter
+func GetShelterEvacuees(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var evacuees []models.Evacuee
+	db := config.GetDB()
+
+	result := db.Where("shelter_id = ? AND checked_in_at IS NOT NULL AND checked_out_at IS NULL", id).
+		Order("name ASC").
+		Find(&evacuees)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch shelter evacuees")
+		return
+	}
+
+	if len(evacuees) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evacuees)
+
+}
+
+// availableShelter is an open shelter with free places and its distance from the caller
+type availableShelter struct {
+	models.Shelter
+	FreePlaces   int      `json:"free_places"`
+	NearCapacity bool     `json:"near_capacity"`
+	DistanceKm   *float64 `json:"distance_km,omitempty"`
+}
+
+// GetAvailableShelters - GET
+// Open shelters with free places, nearest first when ?near=lat,lng is given
+// http://localhost:8081/api/v1/shelters/available?near=6.6828,80.3992
+func GetAvailableShelters(w http.ResponseWriter, r *http.Request) {
+
+	near := r.URL.Query().Get("near")
+
+	var lat, lng float64
+	hasPoint := false
+	if near != "" {
+		parts := strings.Split(near, ",")
+		if len(parts) != 2 {
+			apierror.Write(w, http.StatusBadRequest, "Invalid near value. Use: near=latitude,longitude")
+			return
+		}
+
+		var errLat, errLng error
+		lat, errLat = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLng != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid near value. Use: near=latitude,longitude")
+			return
+		}
+		hasPoint = true
+	}
+
+	var shelters []models.Shelter
+	db := config.GetDB()
+
+	result := db.Where("status = ? AND current_occupancy < capacity", "open").Find(&shelters)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch available shelters")
+		return
+	}
+
+	available := []availableShelter{}
+	for i := range shelters {
+		// Shelters without coordinates cannot be ranked by distance
+		if hasPoint && shelters[i].Latitude == 0 && shelters[i].Longitude == 0 {
+			continue
+		}
+		item := availableShelter{
+			Shelter:      shelters[i],
+			FreePlaces:   shelters[i].Capacity - shelters[i].CurrentOccupancy,
+			NearCapacity: isNearCapacity(&shelters[i]),
+		}
+		if hasPoint {
+			d := distanceKm(lat, lng, shelters[i].Latitude, shelters[i].Longitude)
+			item.DistanceKm = &d
+		}
+		available = append(available, item)
+	}
+
+	// Nearest first, otherwise the most free places first
+	sort.Slice(available, func(i, j int) bool {
+		if hasPoint {
+			return *available[i].DistanceKm < *available[j].DistanceKm
+		}
+		return available[i].FreePlaces > available[j].FreePlaces
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(available)
+
+}
+
+// GetNearCapacityShelters - GET
+// Open shelters at or above the SHELTER_ALERT_PERCENT occupancy level
+func GetNearCapacityShelters(w http.ResponseWriter, r *http.Request) {
+
+	var shelters []models.Shelter
+	db := config.GetDB()
+
+	query := db.Where("status = ? AND capacity > 0 AND current_occupancy * 100.0 / capacity >= ?", "open", shelterAlertPercent()).
+		Order("current_occupancy * 1.0 / capacity DESC")
+	if exportList(w, r, query, &models.Shelter{}, "near-capacity-shelters") {
+		return
+	}
+
+	result := query.Find(&shelters)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch shelter alerts")
+		return
+	}
+
+	if len(shelters) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shelters)
+
+}
@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/forecast"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/sitrep"
+	"flood-relief-system/backend/tabular"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// sitRepResponse is an archived SitRep with its figures
+type sitRepResponse struct {
+	models.SitRep
+	Report *sitrep.Report `json:"report"`
+}
+
+// sitRepTime reads ?from= or ?to= as RFC 3339 or as a date in APP_TIMEZONE.
+// A date in ?to= means the end of that day.
+func sitRepTime(value string, endOfDay bool, loc *time.Location) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, true
+}
+
+// sitRepWindow is the period of a SitRep, the last 24 hours by default
+func sitRepWindow(r *http.Request) (time.Time, time.Time, string) {
+	loc := tabular.Location()
+	to := time.Now()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, ok := sitRepTime(value, true, loc)
+		if !ok {
+			return time.Time{}, time.Time{}, "Invalid to. Use RFC 3339 or YYYY-MM-DD"
+		}
+		to = parsed
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, ok := sitRepTime(value, false, loc)
+		if !ok {
+			return time.Time{}, time.Time{}, "Invalid from. Use RFC 3339 or YYYY-MM-DD"
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, "from must be before to"
+	}
+	return from, to, ""
+}
+
+// buildSitRep gathers the figures for a period. Statuses, stock and
+// shortages are as they stand now; counts of new, closed, started and
+// finished records are for the period.
+func buildSitRep(db *gorm.DB, from, to time.Time) (*sitrep.Report, error) {
+	loc := tabular.Location()
+	report := &sitrep.Report{
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+		TimeZone:    loc.String(),
+		Stock:       []sitrep.StockLevel{},
+	}
+
+	helpRequests := db.Model(&models.HelpRequest{}).Where("created_at < ?", to)
+	counts := []struct {
+		query *gorm.DB
+		into  *int
+	}{
+		{db.Model(&models.HelpRequest{}).Where("created_at >= ? AND created_at < ?", from, to), &report.HelpRequests.New},
+		{db.Model(&models.HelpRequest{}).Where("status = ? AND updated_at >= ? AND updated_at < ?", "completed", from, to), &report.HelpRequests.Closed},
+		{helpRequests.Session(&gorm.Session{}).Where("status <> ?", "completed"), &report.HelpRequests.Open},
+		{db.Model(&models.RescueOperation{}).Where("start_time >= ? AND start_time < ?", from, to), &report.Rescues.Started},
+		{db.Model(&models.RescueOperation{}).Where("status = ? AND end_time >= ? AND end_time < ?", "completed", from, to), &report.Rescues.Completed},
+		{db.Model(&models.RescueOperation{}).Where("status = ? AND end_time >= ? AND end_time < ?", "failed", from, to), &report.Rescues.Failed},
+		{db.Model(&models.RescueOperation{}).Where("status IN ? AND start_time < ?", []string{"initiated", "in-progress"}, to), &report.Rescues.Active},
+		{db.Model(&models.Volunteer{}).Where("status = ?", "active"), &report.Volunteers.Active},
+	}
+	for _, c := range counts {
+		var n int64
+		if err := c.query.Count(&n).Error; err != nil {
+			return nil, err
+		}
+		*c.into = int(n)
+	}
+
+	if err := helpRequests.Session(&gorm.Session{}).
+		Select("status AS key, COUNT(*) AS count").Group("status").Order("status").
+		Scan(&report.HelpRequests.ByStatus).Error; err != nil {
+		return nil, err
+	}
+	if err := helpRequests.Session(&gorm.Session{}).Where("status <> ?", "completed").
+		Select("priority AS key, COUNT(*) AS count").Group("priority").
+		Order("CASE WHEN priority = 'critical' THEN 1 WHEN priority = 'high' THEN 2 WHEN priority = 'medium' THEN 3 ELSE 4 END").
+		Scan(&report.HelpRequests.ByPriority).Error; err != nil {
+		return nil, err
+	}
+
+	// People rescued by operations that finished in the period
+	if err := db.Model(&models.RescueOperation{}).
+		Where("end_time >= ? AND end_time < ?", from, to).
+		Select("COALESCE(SUM(people_rescued), 0)").
+		Scan(&report.Rescues.PeopleRescued).Error; err != nil {
+		return nil, err
+	}
+
+	// Volunteers leading an operation that ran at some point in the period
+	if err := db.Model(&models.RescueOperation{}).
+		Where("volunteer_id <> 0 AND start_time < ? AND (end_time IS NULL OR end_time >= ?)", to, from).
+		Select("COUNT(DISTINCT volunteer_id)").
+		Scan(&report.Volunteers.OnDuty).Error; err != nil {
+		return nil, err
+	}
+
+	rows, err := fetchStockRows(db, nil)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := units.Load(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range summarizeStock(rows, "category", catalog) {
+		report.Stock = append(report.Stock, sitrep.StockLevel{
+			Category: level.Category,
+			Unit:     level.Unit,
+			Quantity: level.Quantity,
+			Lots:     level.Lots,
+		})
+	}
+
+	forecasts, err := forecast.All(db, nil, forecast.LoadSettings())
+	if err != nil {
+		return nil, err
+	}
+	report.Shortages = forecast.Shortages(forecasts)
+
+	return report, nil
+}
+
+// sitRepTemplate is the edited template for a format, or the built-in one
+func sitRepTemplate(db *gorm.DB, format string) (string, error) {
+	var custom models.SitRepTemplate
+	err := db.Where("format = ?", format).First(&custom).Error
+	if err == nil {
+		return custom.Body, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return sitrep.DefaultTemplate(format)
+}
+
+// renderSitRep fills in the Markdown and HTML of a SitRep from the current
+// templates
+func renderSitRep(db *gorm.DB, record *models.SitRep, report *sitrep.Report) error {
+	for _, target := range []struct {
+		format string
+		into   *string
+	}{
+		{sitrep.Markdown, &record.Markdown},
+		{sitrep.HTML, &record.HTML},
+	} {
+		body, err := sitRepTemplate(db, target.format)
+		if err != nil {
+			return err
+		}
+		if *target.into, err = sitrep.RenderString(target.format, body, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSitRep answers with the SitRep as ?format=json (default), md, html
+// or pdf
+func writeSitRep(w http.ResponseWriter, r *http.Request, record *models.SitRep, report *sitrep.Report) {
+
+	filename := "sitrep-" + report.To.In(tabular.Location()).Format("2006-01-02")
+
+	var out bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sitRepResponse{SitRep: *record, Report: report})
+		return
+	case "md", "markdown":
+		out.WriteString(record.Markdown)
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="`+filename+`.md"`)
+	case "html":
+		out.WriteString(record.HTML)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case "pdf":
+		if err := sitrep.PDF(&out, record.Markdown); err != nil {
+			apierror.Write(w, http.StatusInternalServerError, "Failed to render SitRep PDF")
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+filename+`.pdf"`)
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: json, md, html, pdf")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+
+}
+
+// CreateSitRep - POST
+// Generates a SitRep for ?from= to ?to= (RFC 3339 or YYYY-MM-DD in
+// APP_TIMEZONE; the last 24 hours by default) and archives it
+// http://localhost:8081/api/v1/sitreps?from=2025-11-27&to=2025-11-27
+func CreateSitRep(w http.ResponseWriter, r *http.Request) {
+
+	from, to, msg := sitRepWindow(r)
+	if msg != "" {
+		apierror.Write(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	db := config.GetDB()
+	report, err := buildSitRep(db, from, to)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to gather SitRep figures")
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to generate SitRep")
+		return
+	}
+	record := models.SitRep{PeriodStart: from, PeriodEnd: to, Data: string(data), GeneratedBy: requestActor(r)}
+	if err := renderSitRep(db, &record, report); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to render SitRep templates")
+		return
+	}
+
+	if err := db.Create(&record).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to archive SitRep")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sitRepResponse{SitRep: record, Report: report})
+
+}
+
+// PreviewSitRep - GET
+// Generates a SitRep without archiving it. Same window as CreateSitRep;
+// ?format=json (default), md, html or pdf.
+// http://localhost:8081/api/v1/sitreps/preview?format=html
+func PreviewSitRep(w http.ResponseWriter, r *http.Request) {
+
+	from, to, msg := sitRepWindow(r)
+	if msg != "" {
+		apierror.Write(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	db := config.GetDB()
+	report, err := buildSitRep(db, from, to)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to gather SitRep figures")
+		return
+	}
+
+	record := models.SitRep{PeriodStart: from, PeriodEnd: to, GeneratedBy: requestActor(r)}
+	if err := renderSitRep(db, &record, report); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to render SitRep templates")
+		return
+	}
+
+	writeSitRep(w, r, &record, report)
+
+}
+
+// GetSitReps - GET
+// The archive, newest first. Optional ?limit= (default 50).
+// http://localhost:8081/api/v1/sitreps
+func GetSitReps(w http.ResponseWriter, r *http.Request) {
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			apierror.Write(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	sitReps := []models.SitRep{}
+	if err := config.GetDB().Omit("data", "markdown", "html").Order("period_end DESC, id DESC").Limit(limit).Find(&sitReps).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch SitReps")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sitReps)
+
+}
+
+// GetSitRep - GET
+// An archived SitRep as it was generated; ?format=json (default), md, html
+// or pdf
+// http://localhost:8081/api/v1/sitreps/{id}?format=pdf
+func GetSitRep(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var record models.SitRep
+	if err := config.GetDB().First(&record, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "SitRep not found")
+		return
+	}
+
+	report := &sitrep.Report{}
+	if err := json.Unmarshal([]byte(record.Data), report); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Archived SitRep data is unreadable")
+		return
+	}
+
+	writeSitRep(w, r, &record, report)
+
+}
+
+// sitRepTemplateFormat reads {format} from the path
+func sitRepTemplateFormat(r *http.Request) (string, bool) {
+	format := mux.Vars(r)["format"]
+	_, err := sitrep.DefaultTemplate(format)
+	return format, err == nil
+}
+
+// GetSitRepTemplate - GET
+// The template used for md or html SitReps; custom is false for the
+// built-in template
+// http://localhost:8081/api/v1/sitrep-templates/md
+func GetSitRepTemplate(w http.ResponseWriter, r *http.Request) {
+
+	format, ok := sitRepTemplateFormat(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: md, html")
+		return
+	}
+
+	response := struct {
+		models.SitRepTemplate
+		Custom bool `json:"custom"`
+	}{}
+	err := config.GetDB().Where("format = ?", format).First(&response.SitRepTemplate).Error
+	switch {
+	case err == nil:
+		response.Custom = true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Format = format
+		response.Body, _ = sitrep.DefaultTemplate(format)
+	default:
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch SitRep template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// UpdateSitRepTemplate - PUT
+// Replaces the md or html template. Body: {"body": "..."} in Go template
+// syntax over the report (see the built-in template). The template is
+// tried against sample figures first and refused if it does not render.
+// Archived SitReps keep the text they were generated with.
+// http://localhost:8081/api/v1/sitrep-templates/html
+func UpdateSitRepTemplate(w http.ResponseWriter, r *http.Request) {
+
+	format, ok := sitRepTemplateFormat(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: md, html")
+		return
+	}
+
+	var input struct {
+		Body string `json:"body" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+	if fields := validation.Struct(&input); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if err := sitrep.Check(format, input.Body); err != nil {
+		apierror.InvalidField(w, "body", "invalid", "Template does not render: "+err.Error())
+		return
+	}
+
+	db := config.GetDB()
+	var template models.SitRepTemplate
+	err := db.Where("format = ?", format).First(&template).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch SitRep template")
+		return
+	}
+
+	template.Format = format
+	template.Body = input.Body
+	template.UpdatedBy = requestActor(r)
+	if err := db.Save(&template).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to save SitRep template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(template)
+
+}
+
+// ResetSitRepTemplate - DELETE
+// Goes back to the built-in template for the format
+// http://localhost:8081/api/v1/sitrep-templates/md
+func ResetSitRepTemplate(w http.ResponseWriter, r *http.Request) {
+
+	format, ok := sitRepTemplateFormat(r)
+	if !ok {
+		apierror.Write(w, http.StatusBadRequest, "Invalid format. Use: md, html")
+		return
+	}
+
+	if err := config.GetDB().Where("format = ?", format).Delete(&models.SitRepTemplate{}).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to reset SitRep template")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"SitRep template reset to the built-in template"}`))
+
+}
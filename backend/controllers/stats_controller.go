@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/stats"
+	"flood-relief-system/backend/tabular"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// statsCache holds dashboard aggregates for STATS_CACHE_SECONDS
+var statsCache = stats.NewCache()
+
+// statGroups are the counts in the overview: for each entity, the columns
+// its records are counted by. The first column also gives the total.
+var statGroups = []struct {
+	entity  string
+	columns []string
+}{
+	{"help_requests", []string{"status", "priority"}},
+	{"rescue_operations", []string{"status", "priority"}},
+	{"volunteers", []string{"status", "availability"}},
+	{"relief_supplies", []string{"category", "status"}},
+	{"emergency_contacts", []string{"service_type", "verification_status", "district"}},
+}
+
+// entityStats are the counts for one entity
+type entityStats struct {
+	Total         int64                    `json:"total"`
+	By            map[string][]stats.Count `json:"by"`
+	PeopleRescued *int64                   `json:"people_rescued,omitempty"`
+}
+
+// statsOverview is the answer of GET /stats
+type statsOverview struct {
+	Entities    map[string]*entityStats `json:"entities"`
+	GeneratedAt time.Time               `json:"generated_at"`
+}
+
+// statsSeries is the answer of GET /stats/series/{metric}
+type statsSeries struct {
+	Metric      string            `json:"metric"`
+	Bucket      string            `json:"bucket"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	TimeZone    string            `json:"time_zone"`
+	Filters     map[string]string `json:"filters,omitempty"`
+	Total       int64             `json:"total"`
+	Points      []stats.Point     `json:"points"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// buildStatsOverview counts every entity by its stat columns
+func buildStatsOverview(db *gorm.DB) (*statsOverview, error) {
+	overview := &statsOverview{Entities: map[string]*entityStats{}, GeneratedAt: time.Now()}
+
+	for _, group := range statGroups {
+		entity := &entityStats{By: map[string][]stats.Count{}}
+		for i, column := range group.columns {
+			counts, total, err := stats.Group(db, group.entity, column)
+			if err != nil {
+				return nil, err
+			}
+			entity.By[column] = counts
+			if i == 0 {
+				entity.Total = total
+			}
+		}
+		overview.Entities[group.entity] = entity
+	}
+
+	var rescued int64
+	if err := db.Table("rescue_operations").Select("COALESCE(SUM(people_rescued), 0)").Scan(&rescued).Error; err != nil {
+		return nil, err
+	}
+	overview.Entities["rescue_operations"].PeopleRescued = &rescued
+
+	return overview, nil
+}
+
+// writeStats answers with a cached aggregate and tells the client how long it
+// may keep it
+func writeStats(w http.ResponseWriter, value interface{}, ttl time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(ttl.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(value)
+}
+
+// GetStats - GET
+// Counts of help requests, rescue operations, volunteers, relief supplies
+// and emergency contacts by status, priority, category and service type,
+// computed in the database and cached for STATS_CACHE_SECONDS
+// http://localhost:8081/api/v1/stats
+func GetStats(w http.ResponseWriter, r *http.Request) {
+
+	ttl := stats.CacheTTL()
+	overview, err := statsCache.Get(r.URL.Path, ttl, func() (interface{}, error) {
+		return buildStatsOverview(config.GetDB())
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute statistics")
+		return
+	}
+
+	writeStats(w, overview, ttl)
+
+}
+
+// GetStatsSeries - GET
+// A time series for one metric: help_requests, help_requests_closed,
+// rescue_operations, people_rescued, volunteers or relief_supplies.
+// ?bucket= is the bucket size: 15m, 1h (default), 6h, 1d, 1w, or minute,
+// hour, day, week. Buckets line up with the clock in APP_TIMEZONE.
+// ?from= and ?to= (RFC 3339 or YYYY-MM-DD) default to the last 24 buckets.
+// ?status=, ?priority= and ?category= narrow the metrics that have them.
+// http://localhost:8081/api/v1/stats/series/people_rescued?bucket=1d&from=2025-11-20
+func GetStatsSeries(w http.ResponseWriter, r *http.Request) {
+
+	name := mux.Vars(r)["metric"]
+	metric, ok := stats.Metrics[name]
+	if !ok {
+		apierror.Write(w, http.StatusNotFound, "Unknown metric. Use: help_requests, help_requests_closed, rescue_operations, people_rescued, volunteers, relief_supplies")
+		return
+	}
+
+	params := r.URL.Query()
+	bucketName := params.Get("bucket")
+	if bucketName == "" {
+		bucketName = "1h"
+	}
+	bucket, err := stats.ParseBucket(bucketName)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loc := tabular.Location()
+	to := time.Now()
+	if value := params.Get("to"); value != "" {
+		if to, ok = sitRepTime(value, true, loc); !ok {
+			apierror.Write(w, http.StatusBadRequest, "Invalid to. Use RFC 3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	from := to.Add(-24 * bucket)
+	if value := params.Get("from"); value != "" {
+		if from, ok = sitRepTime(value, false, loc); !ok {
+			apierror.Write(w, http.StatusBadRequest, "Invalid from. Use RFC 3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	if !from.Before(to) {
+		apierror.Write(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	filters := map[string]string{}
+	for _, column := range metric.Filters {
+		if value := params.Get(column); value != "" {
+			filters[column] = value
+		}
+	}
+
+	ttl := stats.CacheTTL()
+	origin := stats.Origin(from, bucket, loc)
+	series, err := statsCache.Get(r.URL.Path+"?"+params.Encode(), ttl, func() (interface{}, error) {
+		points, err := stats.Series(config.GetDB(), metric, origin, to, bucket, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		series := &statsSeries{
+			Metric:      name,
+			Bucket:      bucketName,
+			From:        origin,
+			To:          to.In(loc),
+			TimeZone:    loc.String(),
+			Filters:     filters,
+			Points:      points,
+			GeneratedAt: time.Now(),
+		}
+		for _, p := range points {
+			series.Total += p.Value
+		}
+		return series, nil
+	})
+	if errors.Is(err, stats.ErrTooMany) {
+		apierror.Write(w, http.StatusBadRequest, err.Error()+"; use a larger bucket or a shorter period")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute statistics")
+		return
+	}
+
+	writeStats(w, series, ttl)
+
+}
@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	middlewares "flood-relief-system/backend/middleware"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// requestActor names who made a change. Only the authenticated user is
+// trusted; without a login the change is recorded as unauthenticated.
+func requestActor(r *http.Request) string {
+	if userID, ok := middlewares.GetUserIDFromContext(r.Context()); ok {
+		return fmt.Sprintf("user:%d", int(userID))
+	}
+	return "unauthenticated"
+}
+
+// reportedBy is the name the client gives for who made a change, from the
+// request body or the X-Actor header. It is kept next to the actor for
+// reference and never used in place of it.
+func reportedBy(r *http.Request, name string) string {
+	if name != "" {
+		return name
+	}
+	return r.Header.Get("X-Actor")
+}
+
+// ledgerError maps inventory errors to an HTTP status and message
+func ledgerError(err error) (int, string) {
+	switch {
+	case errors.Is(err, inventory.ErrNegativeStock):
+		return http.StatusConflict, "Insufficient stock: movement would make stock negative"
+	case errors.Is(err, inventory.ErrReservedStock):
+		return http.StatusConflict, "Insufficient available stock: the rest is reserved for allocations"
+	case errors.Is(err, inventory.ErrInvalidType):
+		return http.StatusBadRequest, "Invalid movement type. Use: receipt, issue, adjustment, transfer, write-off, expiry"
+	case errors.Is(err, inventory.ErrInvalidQuantity):
+		return http.StatusBadRequest, "Quantity must not be zero"
+	case errors.Is(err, inventory.ErrUnitMismatch):
+		return http.StatusBadRequest, "Unit does not match the supply unit"
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "Relief supply not found"
+	}
+	return http.StatusInternalServerError, "Failed to record stock movement"
+}
+
+// movementRequest is the body of POST /relief-supplies/{id}/movements
+type movementRequest struct {
+	Type       string `json:"type" validate:"required,oneof=receipt issue adjustment transfer write-off expiry"`
+	Quantity   int    `json:"quantity" validate:"required"`
+	Unit       string `json:"unit"`
+	ReportedBy string `json:"reported_by"` // who the client says made it
+	Reason     string `json:"reason"`
+	ToSupplyID uint   `json:"to_supply_id"` // transfers only
+	Actor      string `json:"-"`            // from requestActor
+}
+
+// CreateStockMovement - POST
+// Quantities are positive except for adjustments, which may be negative.
+// A transfer moves stock into the lot given by to_supply_id.
+// http://localhost:8081/api/v1/relief-supplies/{id}/movements
+func CreateStockMovement(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var body movementRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	body.Actor = requestActor(r)
+	body.ReportedBy = reportedBy(r, body.ReportedBy)
+
+	movements, status, msg := applyMovement(config.GetDB(), uint(id), &body)
+	if status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movements)
+
+}
+
+// applyMovement validates and records a movement request against a supply.
+// On failure it returns the status and message to send back.
+func applyMovement(db *gorm.DB, supplyID uint, body *movementRequest) ([]*models.StockMovement, int, string) {
+
+	if !inventory.IsValidType(body.Type) {
+		status, msg := ledgerError(inventory.ErrInvalidType)
+		return nil, status, msg
+	}
+
+	if body.Type != inventory.Adjustment && body.Quantity <= 0 {
+		return nil, http.StatusBadRequest, "Quantity must be greater than zero"
+	}
+
+	// Corrections and losses must say why
+	if body.Reason == "" && (body.Type == inventory.Adjustment || body.Type == inventory.WriteOff || body.Type == inventory.Expiry) {
+		return nil, http.StatusBadRequest, "Reason is required for adjustments, write-offs and expiries"
+	}
+
+	var err error
+	movements := []*models.StockMovement{}
+
+	// Accept aliases such as "Kgs"; unknown units are left for the ledger to reject
+	if body.Unit != "" {
+		if unit, status, _ := normalizeUnit(db, body.Unit); status == 0 {
+			body.Unit = unit
+		}
+	}
+
+	if body.Type == inventory.Transfer {
+		if body.ToSupplyID == 0 || body.ToSupplyID == supplyID {
+			return nil, http.StatusBadRequest, "to_supply_id is required and must be a different supply"
+		}
+
+		var from, to models.ReliefSupply
+		if err := db.First(&from, supplyID).Error; err != nil {
+			return nil, http.StatusNotFound, "Relief supply not found"
+		}
+		if err := db.First(&to, body.ToSupplyID).Error; err != nil {
+			return nil, http.StatusNotFound, "Destination supply not found"
+		}
+		if from.ItemName != to.ItemName || from.Unit != to.Unit {
+			return nil, http.StatusBadRequest, "Transfers must be between lots of the same item and unit"
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			out, in, err := inventory.Move(tx, from.ID, to.ID, body.Quantity, body.Actor, body.ReportedBy, body.Reason)
+			if err != nil {
+				return err
+			}
+			movements = append(movements, out, in)
+			return nil
+		})
+	} else {
+		movement := &models.StockMovement{
+			ReliefSupplyID: supplyID,
+			Type:           body.Type,
+			Quantity:       inventory.Signed(body.Type, body.Quantity),
+			Unit:           body.Unit,
+			Actor:          body.Actor,
+			ReportedBy:     body.ReportedBy,
+			Reason:         body.Reason,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return inventory.Record(tx, movement)
+		})
+		movements = append(movements, movement)
+	}
+
+	if err != nil {
+		status, msg := ledgerError(err)
+		return nil, status, msg
+	}
+
+	return movements, 0, ""
+}
+
+// GetStockMovements - GET
+// Movement history for one supply, newest first
+// http://localhost:8081/api/v1/relief-supplies/{id}/movements
+func GetStockMovements(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var movements []models.StockMovement
+	db := config.GetDB()
+
+	if err := db.Where("relief_supply_id = ?", id).Order("created_at DESC, id DESC").Find(&movements).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch stock movements")
+		return
+	}
+
+	if len(movements) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(movements)
+
+}
+
+// GetSupplyBalance - GET
+// On-hand balance computed from the ledger
+// http://localhost:8081/api/v1/relief-supplies/{id}/balance
+func GetSupplyBalance(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var supply models.ReliefSupply
+	db := config.GetDB()
+
+	if err := db.First(&supply, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+		return
+	}
+
+	balance, err := inventory.Balance(db, supply.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute balance")
+		return
+	}
+
+	response := map[string]interface{}{
+		"relief_supply_id": supply.ID,
+		"item_name":        supply.ItemName,
+		"unit":             supply.Unit,
+		"on_hand":          balance,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
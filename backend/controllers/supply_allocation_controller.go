@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// allocationReference describes what an allocation is for, for the ledger
+func allocationReference(allocation *models.SupplyAllocation) string {
+	if allocation.HelpRequestID != nil {
+		return fmt.Sprintf("allocation #%d for help request #%d", allocation.ID, *allocation.HelpRequestID)
+	}
+	return fmt.Sprintf("allocation #%d for rescue operation #%d", allocation.ID, *allocation.RescueOperationID)
+}
+
+// quantityRequest is the body of the pick and deliver endpoints.
+// A zero quantity means "everything that is left".
+type quantityRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// decodeQuantity reads an optional quantity body
+func decodeQuantity(r *http.Request) (int, error) {
+	var body quantityRequest
+	if r.ContentLength == 0 {
+		return 0, nil
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	return body.Quantity, err
+}
+
+// CreateSupplyAllocation - POST
+// Reserves stock for a help request or a rescue operation
+// http://localhost:8081/api/v1/allocations
+func CreateSupplyAllocation(w http.ResponseWriter, r *http.Request) {
+
+	var allocation models.SupplyAllocation
+
+	if err := json.NewDecoder(r.Body).Decode(&allocation); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&allocation); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	// Exactly one target
+	if (allocation.HelpRequestID == nil) == (allocation.RescueOperationID == nil) {
+		apierror.Write(w, http.StatusBadRequest, "Give either help_request_id or rescue_operation_id")
+		return
+	}
+
+	db := config.GetDB()
+
+	if allocation.HelpRequestID != nil {
+		if err := db.First(&models.HelpRequest{}, *allocation.HelpRequestID).Error; err != nil {
+			apierror.Write(w, http.StatusNotFound, "Help request not found")
+			return
+		}
+	} else {
+		if err := db.First(&models.RescueOperation{}, *allocation.RescueOperationID).Error; err != nil {
+			apierror.Write(w, http.StatusNotFound, "Rescue operation not found")
+			return
+		}
+	}
+
+	allocation.ID = 0
+	allocation.PickedQuantity = 0
+	allocation.DeliveredQuantity = 0
+	allocation.Status = "reserved"
+	allocation.Actor = requestActor(r)
+
+	status, msg := http.StatusOK, ""
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the lot so two reservations cannot both take the last units
+		var supply models.ReliefSupply
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&supply, allocation.ReliefSupplyID).Error; err != nil {
+			status, msg = http.StatusNotFound, "Relief supply not found"
+			return err
+		}
+		if supply.Status == "Expired" {
+			status, msg = http.StatusConflict, "Relief supply has expired"
+			return gorm.ErrInvalidData
+		}
+
+		available, err := inventory.Available(tx, supply.ID)
+		if err != nil {
+			return err
+		}
+		if allocation.Quantity > available {
+			status, msg = http.StatusConflict, fmt.Sprintf("Only %d %s available", available, supply.Unit)
+			return gorm.ErrInvalidData
+		}
+
+		return tx.Create(&allocation).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to create allocation"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(allocation)
+
+}
+
+// GetAllSupplyAllocations - GET
+// Filters: ?help_request_id=, ?rescue_operation_id=, ?relief_supply_id=, ?status=
+func GetAllSupplyAllocations(w http.ResponseWriter, r *http.Request) {
+
+	var allocations []models.SupplyAllocation
+	db := config.GetDB()
+
+	query := db.Order("created_at DESC")
+	params := r.URL.Query()
+	for _, column := range []string{"help_request_id", "rescue_operation_id", "relief_supply_id", "status"} {
+		if value := params.Get(column); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	if exportList(w, r, query, &models.SupplyAllocation{}, "allocations") {
+		return
+	}
+
+	if err := query.Find(&allocations).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch allocations")
+		return
+	}
+
+	if len(allocations) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(allocations)
+
+}
+
+// GetSupplyAllocationByID - GET
+func GetSupplyAllocationByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var allocation models.SupplyAllocation
+	db := config.GetDB()
+
+	if err := db.First(&allocation, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Allocation not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(allocation)
+
+}
+
+// lockAllocation loads an open allocation with its row locked for the rest
+// of the transaction, so picks, deliveries and cancels of it run one at a
+// time and each sees what the one before it saved
+func lockAllocation(tx *gorm.DB, allocation *models.SupplyAllocation, id int) (int, string) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(allocation, id).Error; err != nil {
+		return http.StatusNotFound, "Allocation not found"
+	}
+	if allocation.Status == "cancelled" || allocation.Status == "delivered" {
+		return http.StatusConflict, "Allocation is already closed"
+	}
+	return 0, ""
+}
+
+// PickSupplyAllocation - POST
+// Takes reserved goods off the shelf; they leave the ledger as an issue
+// http://localhost:8081/api/v1/allocations/{id}/pick  {"quantity": 5}
+func PickSupplyAllocation(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	quantity, err := decodeQuantity(r)
+	if err != nil || quantity < 0 {
+		apierror.Write(w, http.StatusBadRequest, "Invalid quantity")
+		return
+	}
+
+	var allocation models.SupplyAllocation
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the allocation so two picks cannot both take what is left
+		if status, msg = lockAllocation(tx, &allocation, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+
+		remaining := allocation.Quantity - allocation.PickedQuantity
+		if quantity == 0 {
+			quantity = remaining
+		}
+		if quantity == 0 || quantity > remaining {
+			status, msg = http.StatusBadRequest, "Cannot pick more than was reserved"
+			return gorm.ErrInvalidData
+		}
+
+		// Picked goods leave the reservation before they leave the ledger
+		allocation.PickedQuantity += quantity
+		allocation.Status = inventory.AllocationStatus(&allocation)
+		if err := tx.Save(&allocation).Error; err != nil {
+			return err
+		}
+
+		return inventory.Record(tx, &models.StockMovement{
+			ReliefSupplyID: allocation.ReliefSupplyID,
+			Type:           inventory.Issue,
+			Quantity:       -quantity,
+			Actor:          requestActor(r),
+			Reason:         "Picked for delivery",
+			Reference:      allocationReference(&allocation),
+		})
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = ledgerError(err)
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(allocation)
+
+}
+
+// DeliverSupplyAllocation - POST
+// Records goods handed over; deliveries may be partial
+// http://localhost:8081/api/v1/allocations/{id}/deliver  {"quantity": 5}
+func DeliverSupplyAllocation(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	quantity, err := decodeQuantity(r)
+	if err != nil || quantity < 0 {
+		apierror.Write(w, http.StatusBadRequest, "Invalid quantity")
+		return
+	}
+
+	var allocation models.SupplyAllocation
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockAllocation(tx, &allocation, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+
+		// Only picked goods can be handed over
+		onTheWay := allocation.PickedQuantity - allocation.DeliveredQuantity
+		if quantity == 0 {
+			quantity = onTheWay
+		}
+		if quantity == 0 || quantity > onTheWay {
+			status, msg = http.StatusBadRequest, "Cannot deliver more than has been picked"
+			return gorm.ErrInvalidData
+		}
+
+		allocation.DeliveredQuantity += quantity
+		allocation.Status = inventory.AllocationStatus(&allocation)
+		if err := tx.Save(&allocation).Error; err != nil {
+			return err
+		}
+
+		return inventory.RefreshStatus(tx, allocation.ReliefSupplyID)
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to record delivery"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(allocation)
+
+}
+
+// CancelSupplyAllocation - POST
+// Releases the unpicked reservation and returns picked but undelivered goods
+// to stock. An allocation that was partly delivered is closed at what was
+// delivered.
+func CancelSupplyAllocation(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var allocation models.SupplyAllocation
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockAllocation(tx, &allocation, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+
+		returned := allocation.PickedQuantity - allocation.DeliveredQuantity
+		if returned > 0 {
+			movement := &models.StockMovement{
+				ReliefSupplyID: allocation.ReliefSupplyID,
+				Type:           inventory.Receipt,
+				Quantity:       returned,
+				Actor:          requestActor(r),
+				Reason:         "Returned from cancelled allocation",
+				Reference:      allocationReference(&allocation),
+			}
+			if err := inventory.Record(tx, movement); err != nil {
+				return err
+			}
+		}
+
+		if allocation.DeliveredQuantity > 0 {
+			allocation.Quantity = allocation.DeliveredQuantity
+			allocation.PickedQuantity = allocation.DeliveredQuantity
+			allocation.Status = "delivered"
+		} else {
+			allocation.PickedQuantity = 0
+			allocation.Status = "cancelled"
+		}
+		return tx.Save(&allocation).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = ledgerError(err)
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(allocation)
+
+}
+
+// GetSupplyAvailability - GET
+// On hand, reserved and available quantity for one lot
+// http://localhost:8081/api/v1/relief-supplies/{id}/availability
+func GetSupplyAvailability(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var supply models.ReliefSupply
+	db := config.GetDB()
+
+	if err := db.First(&supply, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+		return
+	}
+
+	onHand, err := inventory.Balance(db, supply.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute availability")
+		return
+	}
+	reserved, err := inventory.Reserved(db, supply.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to compute availability")
+		return
+	}
+
+	response := map[string]interface{}{
+		"relief_supply_id": supply.ID,
+		"unit":             supply.Unit,
+		"on_hand":          onHand,
+		"reserved":         reserved,
+		"available":        onHand - reserved,
+		"status":           supply.Status,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/jobs"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// GetExpiringSupplies - GET
+// Available lots that expire within ?days= (default 7)
+// http://localhost:8081/api/v1/relief-supplies/expiring?days=14
+func GetExpiringSupplies(w http.ResponseWriter, r *http.Request) {
+
+	days := 7
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			apierror.Write(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = parsed
+	}
+
+	db := config.GetDB()
+	lots, err := jobs.ExpiringSoon(db, days)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch expiring supplies")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lots)
+
+}
+
+// RunExpiryCheck - POST
+// Runs the expiry job now instead of waiting for the next scheduled run
+func RunExpiryCheck(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	count, err := jobs.ExpireSupplies(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to run expiry check")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"expired_lots": count})
+
+}
+
+// SuggestAllocationLots - GET
+// First-expiry-first-out lots to cover a quantity of an item
+// http://localhost:8081/api/v1/allocations/suggest?item_name=Rice&quantity=50&unit=kg&warehouse_id=2
+func SuggestAllocationLots(w http.ResponseWriter, r *http.Request) {
+
+	params := r.URL.Query()
+	itemName := params.Get("item_name")
+	quantity, err := strconv.Atoi(params.Get("quantity"))
+	if itemName == "" || params.Get("unit") == "" || err != nil || quantity <= 0 {
+		apierror.Write(w, http.StatusBadRequest, "item_name, unit and a quantity greater than zero are required")
+		return
+	}
+
+	var warehouseID *uint
+	if value := params.Get("warehouse_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, "Invalid warehouse_id")
+			return
+		}
+		id := uint(parsed)
+		warehouseID = &id
+	}
+
+	// Lots in other units cannot make up the same quantity
+	db := config.GetDB()
+	unit, status, msg := normalizeUnit(db, params.Get("unit"))
+	if status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	lots, shortfall, err := inventory.SuggestLots(db, itemName, unit, warehouseID, quantity)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to suggest lots")
+		return
+	}
+
+	response := map[string]interface{}{
+		"item_name": itemName,
+		"unit":      unit,
+		"requested": quantity,
+		"shortfall": shortfall,
+		"lots":      lots,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// fefoAllocationRequest reserves an item rather than a specific lot
+type fefoAllocationRequest struct {
+	ItemName          string `json:"item_name" validate:"required"`
+	Quantity          int    `json:"quantity" validate:"required,min=1"`
+	Unit              string `json:"unit" validate:"required"`
+	WarehouseID       *uint  `json:"warehouse_id"`
+	HelpRequestID     *uint  `json:"help_request_id"`
+	RescueOperationID *uint  `json:"rescue_operation_id"`
+	Notes             string `json:"notes"`
+}
+
+// CreateFEFOAllocations - POST
+// Reserves an item across lots in first-expiry-first-out order, one
+// allocation per lot. Fails without reserving anything when stock is short.
+// http://localhost:8081/api/v1/allocations/fefo
+func CreateFEFOAllocations(w http.ResponseWriter, r *http.Request) {
+
+	var body fefoAllocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if (body.HelpRequestID == nil) == (body.RescueOperationID == nil) {
+		apierror.Write(w, http.StatusBadRequest, "Give either help_request_id or rescue_operation_id")
+		return
+	}
+
+	db := config.GetDB()
+
+	// Lots in other units cannot make up the same quantity
+	unit, status, msg := normalizeUnit(db, body.Unit)
+	if status == http.StatusBadRequest {
+		apierror.InvalidField(w, "unit", "oneof", msg)
+		return
+	}
+	if status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+	body.Unit = unit
+
+	if body.HelpRequestID != nil {
+		if err := db.First(&models.HelpRequest{}, *body.HelpRequestID).Error; err != nil {
+			apierror.Write(w, http.StatusNotFound, "Help request not found")
+			return
+		}
+	} else {
+		if err := db.First(&models.RescueOperation{}, *body.RescueOperationID).Error; err != nil {
+			apierror.Write(w, http.StatusNotFound, "Rescue operation not found")
+			return
+		}
+	}
+
+	allocations := []models.SupplyAllocation{}
+	shortfall := 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Locked so another reservation cannot take the same stock
+		lots, short, err := inventory.LockLots(tx, body.ItemName, body.Unit, body.WarehouseID, body.Quantity)
+		if err != nil {
+			return err
+		}
+		if short > 0 {
+			shortfall = short
+			return inventory.ErrNegativeStock
+		}
+
+		for _, lot := range lots {
+			allocation := models.SupplyAllocation{
+				ReliefSupplyID:    lot.ReliefSupplyID,
+				HelpRequestID:     body.HelpRequestID,
+				RescueOperationID: body.RescueOperationID,
+				Quantity:          lot.Take,
+				Status:            "reserved",
+				Actor:             requestActor(r),
+				Notes:             body.Notes,
+			}
+			if err := tx.Create(&allocation).Error; err != nil {
+				return err
+			}
+			allocations = append(allocations, allocation)
+		}
+		return nil
+	})
+	if err != nil {
+		if shortfall > 0 {
+			apierror.Write(w, http.StatusConflict, fmt.Sprintf("Not enough stock: %d short", shortfall))
+			return
+		}
+		status, msg := ledgerError(err)
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(allocations)
+
+}
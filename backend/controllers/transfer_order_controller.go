@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTransferOrder - POST
+// Creates a draft order; no stock moves until it is dispatched
+// http://localhost:8081/api/v1/transfer-orders
+func CreateTransferOrder(w http.ResponseWriter, r *http.Request) {
+
+	var order models.TransferOrder
+
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&order); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	if order.FromWarehouseID == order.ToWarehouseID {
+		apierror.InvalidField(w, "to_warehouse_id", "invalid", "Source and destination warehouses must differ")
+		return
+	}
+
+	db := config.GetDB()
+
+	var from, to models.Warehouse
+	if err := db.First(&from, order.FromWarehouseID).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Source warehouse not found")
+		return
+	}
+	if err := db.First(&to, order.ToWarehouseID).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Destination warehouse not found")
+		return
+	}
+	if !to.IsActive {
+		apierror.Write(w, http.StatusBadRequest, "Destination warehouse is not active")
+		return
+	}
+
+	// Every line must be a lot held in the source warehouse
+	for i, line := range order.Lines {
+		var lot models.ReliefSupply
+		if err := db.First(&lot, line.ReliefSupplyID).Error; err != nil {
+			apierror.Write(w, http.StatusNotFound, "Relief supply not found")
+			return
+		}
+		if lot.WarehouseID == nil || *lot.WarehouseID != from.ID {
+			apierror.Write(w, http.StatusBadRequest, "Relief supply is not held in the source warehouse")
+			return
+		}
+
+		order.Lines[i].ID = 0
+		order.Lines[i].ReceivedQuantity = 0
+		order.Lines[i].DestinationSupplyID = nil
+	}
+
+	order.Status = "draft"
+	order.DispatchedAt = nil
+	order.InTransitAt = nil
+	order.ReceivedAt = nil
+	if order.RequestedBy == "" {
+		order.RequestedBy = requestActor(r)
+	}
+
+	if err := db.Create(&order).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create transfer order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+
+}
+
+// GetAllTransferOrders - GET
+// Optional filters: ?status=in-transit&warehouse_id=3
+func GetAllTransferOrders(w http.ResponseWriter, r *http.Request) {
+
+	var orders []models.TransferOrder
+	db := config.GetDB()
+
+	query := db.Preload("Lines").Order("created_at DESC")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if warehouseID := r.URL.Query().Get("warehouse_id"); warehouseID != "" {
+		query = query.Where("from_warehouse_id = ? OR to_warehouse_id = ?", warehouseID, warehouseID)
+	}
+
+	if exportList(w, r, query, &models.TransferOrder{}, "transfer-orders") {
+		return
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch transfer orders")
+		return
+	}
+
+	if len(orders) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+
+}
+
+// GetTransferOrderByID - GET
+func GetTransferOrderByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var order models.TransferOrder
+	db := config.GetDB()
+
+	if err := db.Preload("Lines").First(&order, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Transfer order not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+
+}
+
+// lockTransferOrder loads a transfer order and its lines with the order row
+// locked for the rest of the transaction, so two requests cannot both move
+// it on from the status they read
+func lockTransferOrder(tx *gorm.DB, order *models.TransferOrder, id int) (int, string) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(order, id).Error; err != nil {
+		return http.StatusNotFound, "Transfer order not found"
+	}
+	if err := tx.Where("transfer_order_id = ?", order.ID).Order("id").Find(&order.Lines).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to load transfer order lines"
+	}
+	return 0, ""
+}
+
+// DispatchTransferOrder - POST
+// Takes the goods out of the source warehouse ledger
+// http://localhost:8081/api/v1/transfer-orders/{id}/dispatch
+func DispatchTransferOrder(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var order models.TransferOrder
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	actor := requestActor(r)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockTransferOrder(tx, &order, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+		if order.Status != "draft" {
+			status, msg = http.StatusConflict, "Only draft transfer orders can be dispatched"
+			return gorm.ErrInvalidData
+		}
+
+		// The ledger locks each lot and keeps stock reserved for allocations where it is
+		for _, line := range order.Lines {
+			movement := &models.StockMovement{
+				ReliefSupplyID: line.ReliefSupplyID,
+				Type:           inventory.Transfer,
+				Quantity:       -line.Quantity,
+				Actor:          actor,
+				Reason:         "Dispatched to another warehouse",
+				Reference:      fmt.Sprintf("transfer order #%d", order.ID),
+			}
+			if err := inventory.Record(tx, movement); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		order.Status = "dispatched"
+		order.DispatchedAt = &now
+		return tx.Omit("Lines").Save(&order).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = ledgerError(err)
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+
+}
+
+// MarkTransferInTransit - POST
+// http://localhost:8081/api/v1/transfer-orders/{id}/in-transit
+func MarkTransferInTransit(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var order models.TransferOrder
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockTransferOrder(tx, &order, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+		if order.Status != "dispatched" {
+			status, msg = http.StatusConflict, "Only dispatched transfer orders can be marked in transit"
+			return gorm.ErrInvalidData
+		}
+
+		now := time.Now()
+		order.Status = "in-transit"
+		order.InTransitAt = &now
+		return tx.Omit("Lines").Save(&order).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to update transfer order"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+
+}
+
+// receiveRequest lets the receiving warehouse record short deliveries.
+// Lines left out are received in full.
+type receiveRequest struct {
+	Lines []struct {
+		LineID           uint `json:"line_id"`
+		ReceivedQuantity int  `json:"received_quantity"`
+	} `json:"lines"`
+}
+
+// ReceiveTransferOrder - POST
+// Books the goods into lots at the destination warehouse; what did not
+// arrive is written off there
+// http://localhost:8081/api/v1/transfer-orders/{id}/receive
+func ReceiveTransferOrder(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	// An empty body means everything arrived
+	var body receiveRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+			return
+		}
+	}
+
+	received := map[uint]int{}
+	for _, l := range body.Lines {
+		received[l.LineID] = l.ReceivedQuantity
+	}
+
+	var order models.TransferOrder
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	actor := requestActor(r)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockTransferOrder(tx, &order, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+		if order.Status != "dispatched" && order.Status != "in-transit" {
+			status, msg = http.StatusConflict, "Only dispatched or in-transit transfer orders can be received"
+			return gorm.ErrInvalidData
+		}
+
+		for i := range order.Lines {
+			qty, ok := received[order.Lines[i].ID]
+			if !ok {
+				qty = order.Lines[i].Quantity
+			}
+			if qty < 0 || qty > order.Lines[i].Quantity {
+				status, msg = http.StatusBadRequest, "Received quantity must be between zero and the dispatched quantity"
+				return gorm.ErrInvalidData
+			}
+			order.Lines[i].ReceivedQuantity = qty
+		}
+
+		var destination models.Warehouse
+		if err := tx.First(&destination, order.ToWarehouseID).Error; err != nil {
+			status, msg = http.StatusNotFound, "Destination warehouse not found"
+			return err
+		}
+
+		reference := fmt.Sprintf("transfer order #%d", order.ID)
+		for i := range order.Lines {
+			line := &order.Lines[i]
+
+			var source models.ReliefSupply
+			if err := tx.First(&source, line.ReliefSupplyID).Error; err != nil {
+				return err
+			}
+
+			lot, err := inventory.FindOrCreateLot(tx, &source, &destination)
+			if err != nil {
+				return err
+			}
+			line.DestinationSupplyID = &lot.ID
+
+			// Book in everything that was dispatched and write off what did
+			// not arrive, so the transfer in matches the transfer out
+			movement := &models.StockMovement{
+				ReliefSupplyID: lot.ID,
+				Type:           inventory.Transfer,
+				Quantity:       line.Quantity,
+				Actor:          actor,
+				Reason:         "Received from another warehouse",
+				Reference:      reference,
+			}
+			if err := inventory.Record(tx, movement); err != nil {
+				return err
+			}
+			if shortfall := line.Quantity - line.ReceivedQuantity; shortfall > 0 {
+				loss := &models.StockMovement{
+					ReliefSupplyID: lot.ID,
+					Type:           inventory.WriteOff,
+					Quantity:       -shortfall,
+					Actor:          actor,
+					Reason:         "Not delivered in transit",
+					Reference:      reference,
+				}
+				if err := inventory.Record(tx, loss); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Save(line).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		order.Status = "received"
+		order.ReceivedAt = &now
+		return tx.Omit("Lines").Save(&order).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = ledgerError(err)
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+
+}
+
+// CancelTransferOrder - POST
+// Only drafts can be cancelled; dispatched goods have to be received
+func CancelTransferOrder(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var order models.TransferOrder
+	db := config.GetDB()
+	status, msg := http.StatusOK, ""
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if status, msg = lockTransferOrder(tx, &order, id); status != 0 {
+			return gorm.ErrInvalidData
+		}
+		if order.Status != "draft" {
+			status, msg = http.StatusConflict, "Only draft transfer orders can be cancelled"
+			return gorm.ErrInvalidData
+		}
+
+		order.Status = "cancelled"
+		return tx.Omit("Lines").Save(&order).Error
+	})
+	if err != nil {
+		if msg == "" {
+			status, msg = http.StatusInternalServerError, "Failed to cancel transfer order"
+		}
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+
+}
@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// normalizeUnit maps a unit or alias to its catalogue code. On failure it
+// returns the status and message to send back.
+func normalizeUnit(db *gorm.DB, raw string) (string, int, string) {
+	catalog, err := units.Load(db)
+	if err != nil {
+		return "", http.StatusInternalServerError, "Failed to load unit catalogue"
+	}
+
+	unit, err := catalog.Normalize(raw)
+	if err != nil {
+		return "", http.StatusBadRequest, "Unknown unit. Use one of: " + strings.Join(catalog.Codes(), ", ")
+	}
+	return unit.Code, 0, ""
+}
+
+// unitNameTaken reports whether the code or any alias is already used by
+// another unit in the catalogue
+func unitNameTaken(catalog *units.Catalog, unit models.Unit) bool {
+	names := append([]string{unit.Code}, strings.Split(unit.Aliases, ",")...)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if existing, err := catalog.Normalize(name); err == nil && existing.ID != unit.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUnits - GET
+// http://localhost:8081/api/v1/units
+func GetUnits(w http.ResponseWriter, r *http.Request) {
+
+	unitList := []models.Unit{}
+	db := config.GetDB()
+
+	if err := db.Order("dimension, to_base, code").Find(&unitList).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch units")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(unitList)
+
+}
+
+// CreateUnit - POST
+// http://localhost:8081/api/v1/units
+func CreateUnit(w http.ResponseWriter, r *http.Request) {
+
+	var unit models.Unit
+	if err := json.NewDecoder(r.Body).Decode(&unit); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	unit.Code = strings.TrimSpace(unit.Code)
+	if fields := validation.Struct(&unit); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	// Package units only convert through item conversions
+	if unit.Dimension == "package" {
+		unit.ToBase = 1
+	}
+	if unit.ToBase <= 0 {
+		apierror.InvalidField(w, "to_base", "min", "to_base must be greater than zero")
+		return
+	}
+
+	db := config.GetDB()
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	if unitNameTaken(catalog, unit) {
+		apierror.Write(w, http.StatusConflict, "Unit code or alias already exists")
+		return
+	}
+
+	if err := db.Create(&unit).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create unit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(unit)
+
+}
+
+// UpdateUnit - PUT
+// Name, aliases and the base factor can change; the code and dimension are
+// fixed because stored stock refers to them
+func UpdateUnit(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var unit models.Unit
+	db := config.GetDB()
+
+	if err := db.First(&unit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Unit not found")
+		return
+	}
+
+	var updateData models.Unit
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.Name != "" {
+		unit.Name = updateData.Name
+	}
+
+	if updateData.Aliases != "" {
+		unit.Aliases = updateData.Aliases
+	}
+
+	if updateData.ToBase != 0 {
+		if updateData.ToBase < 0 || unit.Dimension == "package" {
+			apierror.InvalidField(w, "to_base", "min", "to_base must be greater than zero and cannot be set on package units")
+			return
+		}
+		unit.ToBase = updateData.ToBase
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	if unitNameTaken(catalog, unit) {
+		apierror.Write(w, http.StatusConflict, "Unit alias already used by another unit")
+		return
+	}
+
+	if err := db.Save(&unit).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update unit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(unit)
+
+}
+
+// DeleteUnit - DELETE
+// Units still used by supplies, conversions or categories cannot be removed
+func DeleteUnit(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var unit models.Unit
+	db := config.GetDB()
+
+	if err := db.First(&unit, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Unit not found")
+		return
+	}
+
+	var supplies, conversions, categories int64
+	db.Model(&models.ReliefSupply{}).Where("unit = ?", unit.Code).Count(&supplies)
+	db.Model(&models.UnitConversion{}).Where("from_unit = ? OR to_unit = ?", unit.Code, unit.Code).Count(&conversions)
+	db.Model(&models.CategoryUnit{}).Where("unit_code = ?", unit.Code).Count(&categories)
+	if supplies+conversions+categories > 0 {
+		apierror.Write(w, http.StatusConflict, "Unit is in use by supplies, conversions or category units")
+		return
+	}
+
+	if err := db.Delete(&unit).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete unit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Unit deleted successfully"}`))
+
+}
+
+// ConvertQuantity - GET
+// http://localhost:8081/api/v1/units/convert?item_name=Drinking%20Water&quantity=10&from=bottle&to=L
+func ConvertQuantity(w http.ResponseWriter, r *http.Request) {
+
+	params := r.URL.Query()
+	quantity, err := strconv.ParseFloat(params.Get("quantity"), 64)
+	if err != nil || params.Get("from") == "" || params.Get("to") == "" {
+		apierror.Write(w, http.StatusBadRequest, "quantity, from and to are required")
+		return
+	}
+
+	catalog, err := units.Load(config.GetDB())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	converted, err := catalog.Convert(params.Get("item_name"), quantity, params.Get("from"), params.Get("to"))
+	if errors.Is(err, units.ErrUnknownUnit) {
+		apierror.Write(w, http.StatusBadRequest, "Unknown unit")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusUnprocessableEntity, "No conversion between these units for this item")
+		return
+	}
+
+	response := map[string]interface{}{
+		"item_name": params.Get("item_name"),
+		"quantity":  quantity,
+		"from":      params.Get("from"),
+		"to":        params.Get("to"),
+		"result":    converted,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetUnitConversions - GET
+// Item-specific conversions, optionally for one ?item_name=
+func GetUnitConversions(w http.ResponseWriter, r *http.Request) {
+
+	conversions := []models.UnitConversion{}
+	db := config.GetDB()
+
+	query := db.Order("item_name, from_unit")
+	if itemName := r.URL.Query().Get("item_name"); itemName != "" {
+		query = query.Where("LOWER(item_name) = LOWER(?)", itemName)
+	}
+
+	if err := query.Find(&conversions).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch unit conversions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conversions)
+
+}
+
+// CreateUnitConversion - POST
+// {"item_name":"Drinking Water","from_unit":"bottle","to_unit":"L","factor":1.5}
+func CreateUnitConversion(w http.ResponseWriter, r *http.Request) {
+
+	var conversion models.UnitConversion
+	if err := json.NewDecoder(r.Body).Decode(&conversion); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&conversion); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	if err := catalog.ValidateConversion(&conversion); err != nil {
+		if errors.Is(err, units.ErrUnknownUnit) {
+			apierror.Write(w, http.StatusBadRequest, "Unknown unit. Use one of: "+strings.Join(catalog.Codes(), ", "))
+			return
+		}
+		apierror.InvalidField(w, "factor", "min", "Factor must be greater than zero")
+		return
+	}
+
+	if conversion.FromUnit == conversion.ToUnit {
+		apierror.InvalidField(w, "to_unit", "invalid", "from_unit and to_unit must differ")
+		return
+	}
+
+	var count int64
+	db.Model(&models.UnitConversion{}).
+		Where("LOWER(item_name) = LOWER(?) AND from_unit = ? AND to_unit = ?", conversion.ItemName, conversion.FromUnit, conversion.ToUnit).
+		Count(&count)
+	if count > 0 {
+		apierror.Write(w, http.StatusConflict, "Conversion already exists for this item")
+		return
+	}
+
+	if err := db.Create(&conversion).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create unit conversion")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversion)
+
+}
+
+// DeleteUnitConversion - DELETE
+func DeleteUnitConversion(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	db := config.GetDB()
+	result := db.Delete(&models.UnitConversion{}, id)
+	if result.Error != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete unit conversion")
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierror.Write(w, http.StatusNotFound, "Unit conversion not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Unit conversion deleted successfully"}`))
+
+}
+
+// GetCategoryUnits - GET
+// The canonical unit each category is totalled in
+func GetCategoryUnits(w http.ResponseWriter, r *http.Request) {
+
+	categoryUnits := []models.CategoryUnit{}
+	db := config.GetDB()
+
+	if err := db.Order("category").Find(&categoryUnits).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch category units")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(categoryUnits)
+
+}
+
+// SetCategoryUnit - PUT
+// {"unit_code":"kg"}
+// http://localhost:8081/api/v1/category-units/Food
+func SetCategoryUnit(w http.ResponseWriter, r *http.Request) {
+
+	category := mux.Vars(r)["category"]
+	if !containsString([]string{"Food", "Medical", "Clothing", "Shelter", "Other"}, category) {
+		apierror.Write(w, http.StatusBadRequest, "Invalid category. Use: Food, Medical, Clothing, Shelter, Other")
+		return
+	}
+
+	var body models.CategoryUnit
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&body); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	db := config.GetDB()
+	unit, status, msg := normalizeUnit(db, body.UnitCode)
+	if status != 0 {
+		apierror.Write(w, status, msg)
+		return
+	}
+
+	categoryUnit := models.CategoryUnit{Category: category, UnitCode: unit}
+	if err := db.Save(&categoryUnit).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to set category unit")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(categoryUnit)
+
+}
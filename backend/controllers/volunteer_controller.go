@@ -2,14 +2,39 @@ package controllers
 
 import (
 	"encoding/json"
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/config"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/validation"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
+// validateVolunteer checks a new volunteer and sets the default status;
+// used by create and by bulk imports
+func validateVolunteer(volunteer *models.Volunteer) []apierror.FieldError {
+	// Validate required fields and the status value
+	if fields := validation.Struct(volunteer); fields != nil {
+		return fields
+	}
+
+	number, fields := phone.Field("phone", volunteer.Phone, false)
+	if fields != nil {
+		return fields
+	}
+	volunteer.Phone, volunteer.PhoneE164 = number.Display, number.E164
+
+	// set default status
+	if volunteer.Status == "" {
+		volunteer.Status = "active"
+	}
+
+	return nil
+}
+
 // CreateVolunteer POST
 //http://localhost:8081/api/v1/volunteers
 
@@ -24,25 +49,19 @@ func CreateVolunteer(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&volunteer)
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
 		return
 	}
 
-	// Validate required fields
-	if volunteer.Name == "" || volunteer.Email == "" || volunteer.Phone == "" {
-		http.Error(w, `{"error":"Name, email and phone are required"}`, http.StatusBadRequest)
+	if fields := validateVolunteer(&volunteer); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
-	// set default status
-	if volunteer.Status == "" {
-		volunteer.Status = "active"
-	}
-
 	db := config.GetDB()
 	result := db.Create(&volunteer)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to ragister volunteer"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to ragister volunteer")
 		return //Meaning of 500 INTERNAL SERVER ERROR
 	}
 
@@ -60,9 +79,14 @@ func GetAllVolunteers(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 
 	//database all data arn desc order ekt denwa
-	result := db.Order("Created_at DESC").Find(&volunteers)
+	query := db.Order("Created_at DESC")
+	if exportList(w, r, query, &models.Volunteer{}, "volunteers") {
+		return
+	}
+
+	result := query.Find(&volunteers)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to fetch volunteers"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch volunteers")
 		return
 	}
 
@@ -87,7 +111,7 @@ func GetVolunteerByID(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -96,7 +120,7 @@ func GetVolunteerByID(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&volunteer, id) //db.first = Find the volunteer with this ID and store it in the volunteer variable
 	if result.Error != nil {
-		http.Error(w, `{"error":"Volunteer not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Volunteer not found")
 		return
 	}
 
@@ -117,7 +141,7 @@ func UpdateVolunteer(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(vars["id"])
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -126,7 +150,7 @@ func UpdateVolunteer(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&existingVolunteer, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Volunteer not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Volunteer not found")
 		return
 	}
 
@@ -134,7 +158,12 @@ func UpdateVolunteer(w http.ResponseWriter, r *http.Request) {
 	err = json.NewDecoder(r.Body).Decode(&updateData)
 
 	if err != nil {
-		http.Error(w, `{"error":"Invalid JSON format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Partial(&updateData); fields != nil {
+		apierror.Invalid(w, fields)
 		return
 	}
 
@@ -149,7 +178,12 @@ func UpdateVolunteer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateData.Phone != "" {
-		existingVolunteer.Phone = updateData.Phone
+		number, fields := phone.Field("phone", updateData.Phone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existingVolunteer.Phone, existingVolunteer.PhoneE164 = number.Display, number.E164
 	}
 
 	if updateData.Skills != "" {
@@ -165,17 +199,12 @@ func UpdateVolunteer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateData.Status != "" {
-		if updateData.Status != "active" && updateData.Status != "inactive" && updateData.Status != "on-duty" {
-			http.Error(w, `{"error":"Invalid status value"}`, http.StatusBadRequest)
-			return
-		}
-
 		existingVolunteer.Status = updateData.Status
 	}
 
 	result = db.Save(&existingVolunteer)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to update volunteer"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update volunteer")
 		return
 	}
 
@@ -193,7 +222,7 @@ func DeleteVolunteer(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error":"Invalid ID format"}`, http.StatusBadRequest)
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
 		return
 	}
 
@@ -202,14 +231,14 @@ func DeleteVolunteer(w http.ResponseWriter, r *http.Request) {
 
 	result := db.First(&volunteer, id)
 	if result.Error != nil {
-		http.Error(w, `{"error":"Volunteer not found"}`, http.StatusNotFound)
+		apierror.Write(w, http.StatusNotFound, "Volunteer not found")
 		return
 	}
 
 	result = db.Delete(&volunteer)
 
 	if result.Error != nil {
-		http.Error(w, `{"error":"Failed to delete volunteer"}`, http.StatusInternalServerError)
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete volunteer")
 		return
 	}
 
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"encoding/json"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/units"
+	"flood-relief-system/backend/validation"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// CreateWarehouse - POST
+// http://localhost:8081/api/v1/warehouses
+func CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+
+	var warehouse models.Warehouse
+
+	if err := json.NewDecoder(r.Body).Decode(&warehouse); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if fields := validation.Struct(&warehouse); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+
+	warehouse.Code = strings.ToUpper(warehouse.Code)
+	warehouse.IsActive = true
+
+	warehouse.ManagerPhoneE164 = ""
+	if warehouse.ManagerPhone != "" {
+		number, fields := phone.Field("manager_phone", warehouse.ManagerPhone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		warehouse.ManagerPhone, warehouse.ManagerPhoneE164 = number.Display, number.E164
+	}
+
+	db := config.GetDB()
+
+	var count int64
+	db.Model(&models.Warehouse{}).Where("code = ?", warehouse.Code).Count(&count)
+	if count > 0 {
+		apierror.Write(w, http.StatusConflict, "Warehouse code already exists")
+		return
+	}
+
+	if err := db.Create(&warehouse).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create warehouse")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(warehouse)
+
+}
+
+// GetAllWarehouses - GET
+func GetAllWarehouses(w http.ResponseWriter, r *http.Request) {
+
+	var warehouses []models.Warehouse
+	db := config.GetDB()
+
+	query := db.Order("name ASC")
+	if exportList(w, r, query, &models.Warehouse{}, "warehouses") {
+		return
+	}
+
+	if err := query.Find(&warehouses).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch warehouses")
+		return
+	}
+
+	if len(warehouses) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(warehouses)
+
+}
+
+// GetWarehouseByID - GET
+func GetWarehouseByID(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var warehouse models.Warehouse
+	db := config.GetDB()
+
+	if err := db.First(&warehouse, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Warehouse not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(warehouse)
+
+}
+
+// UpdateWarehouse - PUT
+func UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var existing models.Warehouse
+	db := config.GetDB()
+
+	if err := db.First(&existing, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Warehouse not found")
+		return
+	}
+
+	var update models.Warehouse
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	// Update fields if provided
+	if update.Name != "" {
+		existing.Name = update.Name
+	}
+	if update.Code != "" && strings.ToUpper(update.Code) != existing.Code {
+		var count int64
+		db.Model(&models.Warehouse{}).Where("code = ?", strings.ToUpper(update.Code)).Count(&count)
+		if count > 0 {
+			apierror.Write(w, http.StatusConflict, "Warehouse code already exists")
+			return
+		}
+		existing.Code = strings.ToUpper(update.Code)
+	}
+	if update.District != "" {
+		existing.District = update.District
+	}
+	if update.Address != "" {
+		existing.Address = update.Address
+	}
+	if update.Latitude != 0 || update.Longitude != 0 {
+		existing.Latitude = update.Latitude
+		existing.Longitude = update.Longitude
+	}
+	if update.ManagerName != "" {
+		existing.ManagerName = update.ManagerName
+	}
+	if update.ManagerPhone != "" {
+		number, fields := phone.Field("manager_phone", update.ManagerPhone, false)
+		if fields != nil {
+			apierror.Invalid(w, fields)
+			return
+		}
+		existing.ManagerPhone, existing.ManagerPhoneE164 = number.Display, number.E164
+	}
+
+	existing.IsActive = update.IsActive
+
+	if err := db.Save(&existing).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update warehouse")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(existing)
+
+}
+
+// DeleteWarehouse - DELETE
+func DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var warehouse models.Warehouse
+	db := config.GetDB()
+
+	if err := db.First(&warehouse, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Warehouse not found")
+		return
+	}
+
+	// A warehouse holding stock cannot be removed
+	var lots int64
+	db.Model(&models.ReliefSupply{}).Where("warehouse_id = ? AND quantity > 0", warehouse.ID).Count(&lots)
+	if lots > 0 {
+		apierror.Write(w, http.StatusConflict, "Warehouse still holds stock")
+		return
+	}
+
+	if err := db.Delete(&warehouse).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete warehouse")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Warehouse deleted successfully"}`))
+
+}
+
+// stockLevel is one row of a stock summary. Quantities are in the canonical
+// unit of the category; stock that cannot be converted is listed separately.
+type stockLevel struct {
+	WarehouseID *uint              `json:"warehouse_id,omitempty"`
+	Warehouse   string             `json:"warehouse,omitempty"`
+	Category    string             `json:"category,omitempty"`
+	ItemName    string             `json:"item_name,omitempty"`
+	Unit        string             `json:"unit"`
+	Quantity    float64            `json:"quantity"`
+	Lots        int                `json:"lots"`
+	Unconverted []unconvertedStock `json:"unconverted,omitempty"`
+}
+
+// unconvertedStock is stock with no conversion into the canonical unit
+type unconvertedStock struct {
+	ItemName string `json:"item_name"`
+	Unit     string `json:"unit"`
+	Quantity int    `json:"quantity"`
+}
+
+// stockRow is stock on hand per warehouse, item and unit as stored
+type stockRow struct {
+	WarehouseID *uint
+	Warehouse   string
+	Category    string
+	ItemName    string
+	Unit        string
+	Quantity    int
+	Lots        int
+}
+
+// fetchStockRows reads stock on hand, optionally for a single warehouse
+func fetchStockRows(db *gorm.DB, warehouseID *uint) ([]stockRow, error) {
+	query := db.Table("relief_supplies").
+		Select("relief_supplies.warehouse_id, COALESCE(warehouses.name, 'Unassigned') AS warehouse, relief_supplies.category, relief_supplies.item_name, relief_supplies.unit, SUM(relief_supplies.quantity) AS quantity, COUNT(*) AS lots").
+		Joins("LEFT JOIN warehouses ON warehouses.id = relief_supplies.warehouse_id").
+		Where("relief_supplies.quantity > 0")
+	if warehouseID != nil {
+		query = query.Where("relief_supplies.warehouse_id = ?", *warehouseID)
+	}
+
+	rows := []stockRow{}
+	err := query.
+		Group("relief_supplies.warehouse_id, warehouses.name, relief_supplies.category, relief_supplies.item_name, relief_supplies.unit").
+		Order("warehouse, relief_supplies.category, relief_supplies.item_name").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// summarizeStock groups stock rows by warehouse, category or item and adds
+// them up in the canonical unit of each category. Grouped by item, stock
+// that cannot be converted keeps its own unit instead.
+func summarizeStock(rows []stockRow, by string, catalog *units.Catalog) []stockLevel {
+	levels := []stockLevel{}
+	index := map[string]int{}
+
+	for _, row := range rows {
+		unit := catalog.CanonicalUnit(row.Category)
+		quantity, convErr := catalog.Convert(row.ItemName, float64(row.Quantity), row.Unit, unit)
+
+		level := stockLevel{Category: row.Category, Unit: unit}
+		switch by {
+		case "warehouse":
+			level.WarehouseID = row.WarehouseID
+			level.Warehouse = row.Warehouse
+		case "item":
+			level.ItemName = row.ItemName
+			if convErr != nil {
+				level.Unit = row.Unit
+				quantity, convErr = float64(row.Quantity), nil
+			}
+		}
+
+		key := fmt.Sprintf("%v|%s|%s|%s|%s", row.WarehouseID, level.Warehouse, level.Category, level.ItemName, level.Unit)
+		if by != "warehouse" {
+			key = fmt.Sprintf("%s|%s|%s", level.Category, level.ItemName, level.Unit)
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(levels)
+			index[key] = i
+			levels = append(levels, level)
+		}
+
+		levels[i].Lots += row.Lots
+		if convErr != nil {
+			levels[i].Unconverted = append(levels[i].Unconverted, unconvertedStock{
+				ItemName: row.ItemName,
+				Unit:     row.Unit,
+				Quantity: row.Quantity,
+			})
+			continue
+		}
+		levels[i].Quantity += quantity
+	}
+
+	for i := range levels {
+		levels[i].Quantity = math.Round(levels[i].Quantity*1000) / 1000
+	}
+
+	// Rows come ordered by warehouse; other groupings read better by category
+	if by != "warehouse" {
+		sort.SliceStable(levels, func(i, j int) bool {
+			if levels[i].Category != levels[j].Category {
+				return levels[i].Category < levels[j].Category
+			}
+			return levels[i].ItemName < levels[j].ItemName
+		})
+	}
+
+	return levels
+}
+
+// GetWarehouseStock - GET
+// Stock on hand in one warehouse, per item
+// http://localhost:8081/api/v1/warehouses/{id}/stock
+func GetWarehouseStock(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return
+	}
+
+	var warehouse models.Warehouse
+	db := config.GetDB()
+
+	if err := db.First(&warehouse, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Warehouse not found")
+		return
+	}
+
+	rows, err := fetchStockRows(db, &warehouse.ID)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch warehouse stock")
+		return
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	response := map[string]interface{}{
+		"warehouse": warehouse,
+		"stock":     summarizeStock(rows, "item", catalog),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// GetStockSummary - GET
+// Stock on hand grouped by ?by=warehouse, category (default) or item, in the
+// canonical unit of each category. Lots without a warehouse are reported
+// under "Unassigned".
+// http://localhost:8081/api/v1/stock/summary?by=warehouse
+func GetStockSummary(w http.ResponseWriter, r *http.Request) {
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "category"
+	}
+
+	if by != "warehouse" && by != "category" && by != "item" {
+		apierror.Write(w, http.StatusBadRequest, "Invalid grouping. Use: warehouse, category, item")
+		return
+	}
+
+	db := config.GetDB()
+	rows, err := fetchStockRows(db, nil)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch stock summary")
+		return
+	}
+
+	catalog, err := units.Load(db)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to load unit catalogue")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summarizeStock(rows, by, catalog))
+
+}
@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/validation"
+	"flood-relief-system/backend/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// webhookWithSecret is a subscription as answered when its secret is new;
+// the secret is not shown again
+type webhookWithSecret struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// webhookDeliveryDetail is a log entry with the body that was sent
+type webhookDeliveryDetail struct {
+	models.WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+// validateWebhook checks the fields a subscription needs and tidies its
+// event types. It returns nil when the subscription is valid.
+func validateWebhook(subscription *models.WebhookSubscription) []apierror.FieldError {
+	if fields := validation.Struct(subscription); fields != nil {
+		return fields
+	}
+
+	if err := webhooks.CheckURL(subscription.URL); err != nil {
+		code := "format"
+		if errors.Is(err, webhooks.ErrBlockedAddress) {
+			code = "invalid"
+		}
+		return []apierror.FieldError{validation.Field("url", code, err.Error())}
+	}
+
+	types, err := webhooks.ParseEventTypes(subscription.EventTypes)
+	if err != nil {
+		return []apierror.FieldError{validation.Field("event_types", "invalid", "Invalid event_types. Use * or entity.action, e.g. help_request.created, rescue_operation.*")}
+	}
+	subscription.EventTypes = types
+	return nil
+}
+
+// loadWebhook reads the subscription named by {id}, answering the client
+// itself when it cannot
+func loadWebhook(w http.ResponseWriter, r *http.Request, db *gorm.DB) (*models.WebhookSubscription, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid ID format")
+		return nil, false
+	}
+
+	var subscription models.WebhookSubscription
+	if err := db.First(&subscription, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Webhook subscription not found")
+		return nil, false
+	}
+	return &subscription, true
+}
+
+// loadWebhookDelivery reads {delivery_id} of the subscription
+func loadWebhookDelivery(w http.ResponseWriter, r *http.Request, db *gorm.DB, subscription *models.WebhookSubscription) (*models.WebhookDelivery, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["delivery_id"])
+	if err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidID, "Invalid delivery ID format")
+		return nil, false
+	}
+
+	var delivery models.WebhookDelivery
+	if err := db.Where("subscription_id = ?", subscription.ID).First(&delivery, id).Error; err != nil {
+		apierror.Write(w, http.StatusNotFound, "Webhook delivery not found")
+		return nil, false
+	}
+	return &delivery, true
+}
+
+// CreateWebhook - POST
+// Subscribes a partner URL to change events. event_types is a
+// comma-separated list such as "help_request.*,rescue_operation.updated"
+// or "*". A secret is generated unless one is given; it is only shown in
+// this answer. Each POST carries X-Webhook-Event, X-Webhook-Id,
+// X-Webhook-Timestamp and X-Webhook-Signature (sha256= HMAC-SHA256 of
+// "timestamp.body" with the secret).
+// http://localhost:8081/api/v1/webhooks
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Name       string `json:"name"`
+		URL        string `json:"url"`
+		EventTypes string `json:"event_types"`
+		Secret     string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	subscription := models.WebhookSubscription{
+		Name:       input.Name,
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     input.Secret,
+		IsActive:   true,
+	}
+	if fields := validateWebhook(&subscription); fields != nil {
+		apierror.Invalid(w, fields)
+		return
+	}
+	if subscription.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			apierror.Write(w, http.StatusInternalServerError, "Failed to generate secret")
+			return
+		}
+		subscription.Secret = secret
+	} else if len(subscription.Secret) < 16 {
+		apierror.InvalidField(w, "secret", "min", "secret must be at least 16 characters")
+		return
+	}
+
+	if err := config.GetDB().Create(&subscription).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to create webhook subscription")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookWithSecret{WebhookSubscription: subscription, Secret: subscription.Secret})
+
+}
+
+// GetWebhooks - GET
+// http://localhost:8081/api/v1/webhooks
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+
+	subscriptions := []models.WebhookSubscription{}
+	if err := config.GetDB().Order("id").Find(&subscriptions).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch webhook subscriptions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscriptions)
+
+}
+
+// GetWebhookByID - GET
+// http://localhost:8081/api/v1/webhooks/{id}
+func GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+
+	subscription, ok := loadWebhook(w, r, config.GetDB())
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscription)
+
+}
+
+// UpdateWebhook - PUT
+// Changes name, url, event_types or is_active. Turning a disabled
+// subscription back on clears its failure count; deliveries still pending
+// are then sent.
+// http://localhost:8081/api/v1/webhooks/{id}
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+
+	// Decode into a map as well so is_active=false can be told apart from missing
+	var updateData models.WebhookSubscription
+	var fields map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil ||
+		json.Unmarshal(raw, &updateData) != nil || json.Unmarshal(raw, &fields) != nil {
+		apierror.WriteCode(w, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON format")
+		return
+	}
+
+	if updateData.Name != "" {
+		subscription.Name = updateData.Name
+	}
+	if updateData.URL != "" {
+		subscription.URL = updateData.URL
+	}
+	if updateData.EventTypes != "" {
+		subscription.EventTypes = updateData.EventTypes
+	}
+	if _, ok := fields["is_active"]; ok {
+		if updateData.IsActive && !subscription.IsActive {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledAt = nil
+			subscription.DisabledReason = ""
+		}
+		subscription.IsActive = updateData.IsActive
+	}
+
+	if invalid := validateWebhook(subscription); invalid != nil {
+		apierror.Invalid(w, invalid)
+		return
+	}
+
+	if err := db.Save(subscription).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to update webhook subscription")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscription)
+
+}
+
+// DeleteWebhook - DELETE
+// Removes the subscription and its delivery log
+// http://localhost:8081/api/v1/webhooks/{id}
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	})
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to delete webhook subscription")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Webhook subscription deleted successfully"}`))
+
+}
+
+// RotateWebhookSecret - POST
+// Replaces the signing secret and shows the new one once. Deliveries sent
+// from now on, retries included, are signed with it.
+// http://localhost:8081/api/v1/webhooks/{id}/rotate-secret
+func RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+	if err := db.Model(subscription).Update("secret", secret).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to rotate secret")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhookWithSecret{WebhookSubscription: *subscription, Secret: secret})
+
+}
+
+// GetWebhookDeliveries - GET
+// The delivery log, newest first. Optional ?status=pending|delivered|failed
+// and ?limit= (default 100).
+// http://localhost:8081/api/v1/webhooks/{id}/deliveries?status=failed
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+
+	query := db.Where("subscription_id = ?", subscription.ID)
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case webhooks.Pending, webhooks.Delivered, webhooks.Failed:
+		query = query.Where("status = ?", status)
+	default:
+		apierror.Write(w, http.StatusBadRequest, "Invalid status. Use: pending, delivered, failed")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			apierror.Write(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+
+}
+
+// GetWebhookDelivery - GET
+// One log entry with the payload that was sent
+// http://localhost:8081/api/v1/webhooks/{id}/deliveries/{delivery_id}
+func GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+	delivery, ok := loadWebhookDelivery(w, r, db, subscription)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhookDeliveryDetail{WebhookDelivery: *delivery, Payload: json.RawMessage(delivery.Payload)})
+
+}
+
+// RedeliverWebhook - POST
+// Sends the payload of a delivery again as a new delivery, whatever became
+// of the first one. It goes out with the next pass of the webhook job.
+// http://localhost:8081/api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+
+	db := config.GetDB()
+	subscription, ok := loadWebhook(w, r, db)
+	if !ok {
+		return
+	}
+	original, ok := loadWebhookDelivery(w, r, db, subscription)
+	if !ok {
+		return
+	}
+	if !subscription.IsActive {
+		apierror.Write(w, http.StatusConflict, "Webhook subscription is disabled; turn it back on first")
+		return
+	}
+
+	delivery, err := webhooks.Redeliver(db, original)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to queue redelivery")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+
+}
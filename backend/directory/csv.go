@@ -0,0 +1,120 @@
+package directory
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"flood-relief-system/backend/models"
+)
+
+// ErrMissingColumns is returned when a CSV file lacks the required columns
+var ErrMissingColumns = errors.New("CSV needs organization_name and phone columns")
+
+// Columns are the CSV columns, in export order. Imports match them by
+// name, in any order and case, and ignore columns they do not know.
+var Columns = []string{
+	"organization_name", "contact_person", "phone", "alternate_phone", "email", "address",
+	"service_type", "district", "is_active", "verification_status", "last_verified_at",
+}
+
+// WriteCSV writes the contacts with a header row
+func WriteCSV(w io.Writer, contacts []models.EmergencyContact) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(Columns); err != nil {
+		return err
+	}
+
+	for _, c := range contacts {
+		verified := ""
+		if c.LastVerifiedAt != nil {
+			verified = c.LastVerifiedAt.Format("2006-01-02")
+		}
+		record := []string{
+			c.OrganizationName, c.ContactPerson, c.Phone, c.AlternatePhone, c.Email, c.Address,
+			c.ServiceType, c.District, strconv.FormatBool(c.IsActive), c.VerificationStatus, verified,
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// ReadCSV reads contacts from a CSV file with a header row. Verification
+// columns are ignored: imported numbers start unverified. Rows with an
+// is_active value that is not a boolean are returned with a Problem.
+func ReadCSV(r io.Reader) ([]Card, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		index[strings.ReplaceAll(name, " ", "_")] = i
+	}
+	if _, ok := index["organization_name"]; !ok {
+		return nil, ErrMissingColumns
+	}
+	if _, ok := index["phone"]; !ok {
+		return nil, ErrMissingColumns
+	}
+
+	cards := []Card{}
+	for {
+		record, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := in.FieldPos(0)
+
+		get := func(column string) string {
+			i, ok := index[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if strings.Join(record, "") == "" {
+			continue // blank row
+		}
+
+		contact := models.EmergencyContact{
+			OrganizationName: get("organization_name"),
+			ContactPerson:    get("contact_person"),
+			Phone:            get("phone"),
+			AlternatePhone:   get("alternate_phone"),
+			Email:            get("email"),
+			Address:          get("address"),
+			ServiceType:      get("service_type"),
+			District:         get("district"),
+			IsActive:         true,
+		}
+		card := Card{Line: line, Contact: contact}
+		if value := get("is_active"); value != "" {
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				card.Problem = fmt.Sprintf("is_active must be true or false, not %q", value)
+			}
+			card.Contact.IsActive = active
+		}
+
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
@@ -0,0 +1,264 @@
+// Package directory reads and writes the emergency contact directory as
+// vCard 4.0 (RFC 6350) and CSV, for phones and partner agency spreadsheets.
+package directory
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"flood-relief-system/backend/models"
+)
+
+// ErrNoCards is returned when a vCard file holds no cards
+var ErrNoCards = errors.New("no vCards found")
+
+// vcardEscape escapes a text value
+func vcardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// vcardUnescape reverses vcardEscape
+func vcardUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitEscaped splits a structured value on sep, ignoring escaped separators
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	begin := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[begin:i])
+			begin = i + 1
+		}
+	}
+	return append(parts, s[begin:])
+}
+
+// writeLine writes one content line, folded at 75 octets without splitting
+// a UTF-8 character
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // the leading space counts
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// telURI turns a stored phone number into a tel: URI
+func telURI(phone string) string {
+	return "tel:" + strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+}
+
+// WriteVCards writes the contacts as vCard 4.0 cards. The organization is
+// the card's name so it sorts sensibly in a phone's address book.
+func WriteVCards(w io.Writer, contacts []models.EmergencyContact) error {
+	out := bufio.NewWriter(w)
+	for _, c := range contacts {
+		writeLine(out, "BEGIN:VCARD")
+		writeLine(out, "VERSION:4.0")
+		writeLine(out, "KIND:org")
+		writeLine(out, "FN:"+vcardEscape(c.OrganizationName))
+		writeLine(out, "ORG:"+vcardEscape(c.OrganizationName))
+		if c.ContactPerson != "" {
+			writeLine(out, "RELATED;TYPE=contact;VALUE=text:"+vcardEscape(c.ContactPerson))
+		}
+		writeLine(out, "TEL;VALUE=uri;TYPE=work,voice;PREF=1:"+telURI(c.Phone))
+		if c.AlternatePhone != "" {
+			writeLine(out, "TEL;VALUE=uri;TYPE=work,voice;PREF=2:"+telURI(c.AlternatePhone))
+		}
+		if c.Email != "" {
+			writeLine(out, "EMAIL;TYPE=work:"+vcardEscape(c.Email))
+		}
+		if c.Address != "" {
+			writeLine(out, "ADR;TYPE=work:;;"+vcardEscape(c.Address)+";;;;")
+		}
+		if c.ServiceType != "" {
+			writeLine(out, "CATEGORIES:"+vcardEscape(c.ServiceType))
+		}
+		if c.District != "" {
+			writeLine(out, "X-DISTRICT:"+vcardEscape(c.District))
+		}
+		if c.LastVerifiedAt != nil {
+			writeLine(out, "NOTE:Verified "+c.LastVerifiedAt.Format("2006-01-02"))
+		}
+		writeLine(out, "REV:"+c.UpdatedAt.UTC().Format("20060102T150405Z"))
+		writeLine(out, "END:VCARD")
+	}
+	return out.Flush()
+}
+
+// contentLine is one unfolded vCard line
+type contentLine struct {
+	line   int // line number where it starts, for error reports
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseLine splits "item1.TEL;TYPE=work:value" into name, parameters and value
+func parseLine(number int, raw string) (contentLine, bool) {
+	colon := strings.IndexByte(raw, ':')
+	if colon < 0 {
+		return contentLine{}, false
+	}
+
+	parts := strings.Split(raw[:colon], ";")
+	name := strings.ToUpper(parts[0])
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:] // drop the group
+	}
+
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		key, value, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return contentLine{line: number, name: name, params: params, value: raw[colon+1:]}, true
+}
+
+// Card is a contact read from an import file with the line it started on.
+// Problem describes a value the reader could not make sense of.
+type Card struct {
+	Line    int
+	Contact models.EmergencyContact
+	Problem string
+}
+
+// ReadVCards reads every card in the file. Cards from other apps are
+// accepted as long as they have a name and a phone; the first two numbers
+// become the phone and alternate phone.
+func ReadVCards(r io.Reader) ([]Card, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// Unfold continuation lines first
+	var lines []contentLine
+	var current strings.Builder
+	start, number := 0, 0
+	flush := func() {
+		if current.Len() > 0 {
+			if line, ok := parseLine(start, current.String()); ok {
+				lines = append(lines, line)
+			}
+			current.Reset()
+		}
+	}
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			current.WriteString(text[1:])
+			continue
+		}
+		flush()
+		start = number
+		current.WriteString(text)
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	cards := []Card{}
+	var card *Card
+	var fn string
+	for _, line := range lines {
+		switch {
+		case line.name == "BEGIN" && strings.EqualFold(line.value, "VCARD"):
+			card = &Card{Line: line.line, Contact: models.EmergencyContact{IsActive: true}}
+			fn = ""
+		case card == nil:
+			continue
+		case line.name == "END":
+			c := &card.Contact
+			// Cards for people rather than organizations name the person in FN
+			if c.OrganizationName == "" {
+				c.OrganizationName = fn
+			} else if fn != "" && fn != c.OrganizationName && c.ContactPerson == "" {
+				c.ContactPerson = fn
+			}
+			cards = append(cards, *card)
+			card = nil
+		default:
+			applyProperty(&card.Contact, line, &fn)
+		}
+	}
+
+	if len(cards) == 0 {
+		return nil, ErrNoCards
+	}
+	return cards, nil
+}
+
+// applyProperty copies one vCard property onto the contact
+func applyProperty(c *models.EmergencyContact, line contentLine, fn *string) {
+	switch line.name {
+	case "FN":
+		*fn = vcardUnescape(line.value)
+	case "ORG":
+		// ORG is "Organization;Unit;..."; keep the organization
+		c.OrganizationName = vcardUnescape(splitEscaped(line.value, ';')[0])
+	case "RELATED":
+		if strings.EqualFold(line.params["VALUE"], "text") {
+			c.ContactPerson = vcardUnescape(line.value)
+		}
+	case "TEL":
+		phone := strings.TrimPrefix(strings.TrimPrefix(line.value, "tel:"), "TEL:")
+		if c.Phone == "" {
+			c.Phone = phone
+		} else if c.AlternatePhone == "" {
+			c.AlternatePhone = phone
+		}
+	case "EMAIL":
+		if c.Email == "" {
+			c.Email = vcardUnescape(line.value)
+		}
+	case "ADR":
+		if c.Address == "" {
+			var parts []string
+			for _, part := range splitEscaped(line.value, ';') {
+				if part = strings.TrimSpace(vcardUnescape(part)); part != "" {
+					parts = append(parts, part)
+				}
+			}
+			c.Address = strings.Join(parts, ", ")
+		}
+	case "CATEGORIES":
+		if c.ServiceType == "" {
+			c.ServiceType = vcardUnescape(splitEscaped(line.value, ',')[0])
+		}
+	case "X-DISTRICT":
+		c.District = vcardUnescape(line.value)
+	}
+}
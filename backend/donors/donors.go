@@ -0,0 +1,171 @@
+// Package donors links relief supplies to the donors who gave them and
+// reports what each donor has given.
+package donors
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/units"
+
+	"gorm.io/gorm"
+)
+
+// FindOrCreate returns the donor with the same phone number, or with the
+// same name when no phone is given, creating an individual donor if there
+// is none. It is used for supplies that only carry a donor name and phone.
+func FindOrCreate(tx *gorm.DB, name, rawPhone string) (*models.Donor, error) {
+	name = strings.TrimSpace(name)
+	rawPhone = strings.TrimSpace(rawPhone)
+	number, _ := phone.Parse(rawPhone)
+
+	var donor models.Donor
+	var err error
+	switch {
+	case number.E164 != "":
+		err = tx.Where("phone_e164 = ?", number.E164).Order("id").First(&donor).Error
+	case name != "":
+		err = tx.Where("LOWER(name) = LOWER(?)", name).Order("id").First(&donor).Error
+	default:
+		err = gorm.ErrRecordNotFound
+	}
+	if err == nil {
+		return &donor, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if name == "" {
+		name = rawPhone
+	}
+
+	donor = models.Donor{
+		Name:              name,
+		Type:              "individual",
+		Phone:             rawPhone,
+		PhoneE164:         number.E164,
+		PreferredLanguage: "en",
+	}
+	if number.E164 != "" {
+		donor.Phone = number.Display
+	}
+	if err := tx.Create(&donor).Error; err != nil {
+		return nil, err
+	}
+	return &donor, nil
+}
+
+// Donation is a lot a donor gave: the opening receipt of a supply lot.
+// Lots created by transfers and stock returned from allocations are not
+// donations.
+type Donation struct {
+	ReliefSupplyID uint      `json:"relief_supply_id"`
+	ItemName       string    `json:"item_name"`
+	Category       string    `json:"category"`
+	Quantity       int       `json:"quantity"`
+	Unit           string    `json:"unit"`
+	WarehouseID    *uint     `json:"warehouse_id,omitempty"`
+	ReceivedAt     time.Time `json:"received_at"`
+	ReceiptNumber  string    `json:"receipt_number,omitempty"`
+}
+
+// Donations lists what a donor gave, newest first. supplyIDs narrows the
+// list to those lots when it is not empty.
+func Donations(db *gorm.DB, donorID uint, supplyIDs []uint) ([]Donation, error) {
+	query := db.Table("stock_movements").
+		Select("relief_supplies.id AS relief_supply_id, relief_supplies.item_name, relief_supplies.category, stock_movements.quantity, stock_movements.unit, relief_supplies.warehouse_id, stock_movements.created_at AS received_at, COALESCE(donation_receipts.number, '') AS receipt_number").
+		Joins("JOIN relief_supplies ON relief_supplies.id = stock_movements.relief_supply_id").
+		Joins("LEFT JOIN donation_receipt_lines ON donation_receipt_lines.relief_supply_id = relief_supplies.id").
+		Joins("LEFT JOIN donation_receipts ON donation_receipts.id = donation_receipt_lines.receipt_id").
+		Where("relief_supplies.donor_id = ? AND stock_movements.type = ?", donorID, "receipt").
+		Where("stock_movements.id = (SELECT MIN(first.id) FROM stock_movements first WHERE first.relief_supply_id = relief_supplies.id)")
+	if len(supplyIDs) > 0 {
+		query = query.Where("relief_supplies.id IN ?", supplyIDs)
+	}
+
+	donations := []Donation{}
+	err := query.Order("stock_movements.created_at DESC").Scan(&donations).Error
+	return donations, err
+}
+
+// ItemTotal is how much of one item a donor gave, in the unit it came in
+type ItemTotal struct {
+	ItemName string `json:"item_name"`
+	Unit     string `json:"unit"`
+	Quantity int    `json:"quantity"`
+	Lots     int    `json:"lots"`
+}
+
+// CategoryTotal is how much a donor gave in a category, in the canonical
+// unit of the category. Lots that cannot be converted are counted in
+// UnconvertedLots only.
+type CategoryTotal struct {
+	Category        string  `json:"category"`
+	Unit            string  `json:"unit"`
+	Quantity        float64 `json:"quantity"`
+	Lots            int     `json:"lots"`
+	UnconvertedLots int     `json:"unconverted_lots,omitempty"`
+}
+
+// Summary totals a donor's donations
+type Summary struct {
+	Donations     int             `json:"donations"`
+	FirstDonation *time.Time      `json:"first_donation,omitempty"`
+	LastDonation  *time.Time      `json:"last_donation,omitempty"`
+	ByItem        []ItemTotal     `json:"by_item"`
+	ByCategory    []CategoryTotal `json:"by_category"`
+}
+
+// Summarize totals donations per item and per category
+func Summarize(donations []Donation, catalog *units.Catalog) Summary {
+	summary := Summary{Donations: len(donations), ByItem: []ItemTotal{}, ByCategory: []CategoryTotal{}}
+	items := map[string]int{}
+	categories := map[string]int{}
+
+	for _, d := range donations {
+		received := d.ReceivedAt
+		if summary.FirstDonation == nil || received.Before(*summary.FirstDonation) {
+			summary.FirstDonation = &received
+		}
+		if summary.LastDonation == nil || received.After(*summary.LastDonation) {
+			summary.LastDonation = &received
+		}
+
+		itemKey := strings.ToLower(d.ItemName) + "|" + d.Unit
+		i, ok := items[itemKey]
+		if !ok {
+			i = len(summary.ByItem)
+			items[itemKey] = i
+			summary.ByItem = append(summary.ByItem, ItemTotal{ItemName: d.ItemName, Unit: d.Unit})
+		}
+		summary.ByItem[i].Quantity += d.Quantity
+		summary.ByItem[i].Lots++
+
+		c, ok := categories[d.Category]
+		if !ok {
+			c = len(summary.ByCategory)
+			categories[d.Category] = c
+			summary.ByCategory = append(summary.ByCategory, CategoryTotal{Category: d.Category, Unit: catalog.CanonicalUnit(d.Category)})
+		}
+		summary.ByCategory[c].Lots++
+		quantity, err := catalog.Convert(d.ItemName, float64(d.Quantity), d.Unit, summary.ByCategory[c].Unit)
+		if err != nil {
+			summary.ByCategory[c].UnconvertedLots++
+			continue
+		}
+		summary.ByCategory[c].Quantity += quantity
+	}
+
+	for i := range summary.ByCategory {
+		summary.ByCategory[i].Quantity = math.Round(summary.ByCategory[i].Quantity*1000) / 1000
+	}
+	sort.Slice(summary.ByItem, func(i, j int) bool { return summary.ByItem[i].ItemName < summary.ByItem[j].ItemName })
+	sort.Slice(summary.ByCategory, func(i, j int) bool { return summary.ByCategory[i].Category < summary.ByCategory[j].Category })
+
+	return summary
+}
@@ -0,0 +1,162 @@
+// Package entitlements checks relief handouts against the household
+// entitlement rules, e.g. one dry-ration kit per household per 7 days.
+package entitlements
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Item is an item and quantity about to be handed to a household
+type Item struct {
+	ItemName string `json:"item_name" validate:"required"`
+	Quantity int    `json:"quantity" validate:"required,min=1"`
+	Unit     string `json:"unit"`
+}
+
+// Entitlement is where a household stands against one rule
+type Entitlement struct {
+	RuleID       uint       `json:"rule_id"`
+	ItemName     string     `json:"item_name"`
+	Unit         string     `json:"unit"`
+	Mode         string     `json:"mode"`
+	PeriodDays   int        `json:"period_days"`
+	Limit        int        `json:"limit"`
+	Received     int        `json:"received"`
+	Remaining    int        `json:"remaining"`
+	NextEligible *time.Time `json:"next_eligible,omitempty"` // when the oldest handout in the period drops out
+}
+
+// Violation is an item that would take a household over a rule
+type Violation struct {
+	Entitlement
+	Requested int    `json:"requested"`
+	Message   string `json:"message"`
+}
+
+// activeRules returns the rules that apply to an item, or to every item
+// when itemName is empty
+func activeRules(db *gorm.DB, itemName, unit string) ([]models.EntitlementRule, error) {
+	query := db.Where("is_active = ?", true)
+	if itemName != "" {
+		query = query.Where("LOWER(item_name) = LOWER(?) AND unit = ?", itemName, unit)
+	}
+
+	var rules []models.EntitlementRule
+	err := query.Order("item_name, id").Find(&rules).Error
+	return rules, err
+}
+
+// evaluate works out how much of a rule the household has used
+func evaluate(db *gorm.DB, rule *models.EntitlementRule, household *models.Household) (Entitlement, error) {
+	limit := rule.Quantity
+	if rule.PerMember {
+		limit *= household.Size()
+	}
+
+	since := time.Now().AddDate(0, 0, -rule.PeriodDays)
+	var handouts []models.Distribution
+	err := db.Where("household_id = ? AND LOWER(item_name) = LOWER(?) AND unit = ? AND created_at > ?",
+		household.ID, rule.ItemName, rule.Unit, since).
+		Order("created_at ASC").
+		Find(&handouts).Error
+	if err != nil {
+		return Entitlement{}, err
+	}
+
+	e := Entitlement{
+		RuleID:     rule.ID,
+		ItemName:   rule.ItemName,
+		Unit:       rule.Unit,
+		Mode:       rule.Mode,
+		PeriodDays: rule.PeriodDays,
+		Limit:      limit,
+	}
+	for _, h := range handouts {
+		e.Received += h.Quantity
+	}
+	e.Remaining = max(limit-e.Received, 0)
+
+	if e.Remaining == 0 && len(handouts) > 0 {
+		next := handouts[0].CreatedAt.AddDate(0, 0, rule.PeriodDays)
+		e.NextEligible = &next
+	}
+
+	return e, nil
+}
+
+// Status lists where a household stands against every active rule
+func Status(db *gorm.DB, household *models.Household) ([]Entitlement, error) {
+	rules, err := activeRules(db, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	entitlements := []Entitlement{}
+	for i := range rules {
+		e, err := evaluate(db, &rules[i], household)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, e)
+	}
+	return entitlements, nil
+}
+
+// Check returns the rules the items would break. Quantities of the same
+// item in one handout are added together.
+func Check(db *gorm.DB, household *models.Household, items []Item) ([]Violation, error) {
+	requested := map[string]int{}
+	order := []Item{}
+	for _, item := range items {
+		key := strings.ToLower(item.ItemName) + "|" + item.Unit
+		if _, ok := requested[key]; !ok {
+			order = append(order, item)
+		}
+		requested[key] += item.Quantity
+	}
+
+	violations := []Violation{}
+	for _, item := range order {
+		quantity := requested[strings.ToLower(item.ItemName)+"|"+item.Unit]
+
+		rules, err := activeRules(db, item.ItemName, item.Unit)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range rules {
+			e, err := evaluate(db, &rules[i], household)
+			if err != nil {
+				return nil, err
+			}
+			if e.Received+quantity <= e.Limit {
+				continue
+			}
+
+			violations = append(violations, Violation{
+				Entitlement: e,
+				Requested:   quantity,
+				Message: fmt.Sprintf("%s: limit %d %s per %d days, already received %d, requested %d",
+					e.ItemName, e.Limit, e.Unit, e.PeriodDays, e.Received, quantity),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// Blocking reports whether any violation comes from a block rule
+func Blocking(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Mode == "block" {
+			return true
+		}
+	}
+	return false
+}
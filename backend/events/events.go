@@ -0,0 +1,177 @@
+// Package events records every create, update and delete of the core
+// entities as a models.ChangeEvent and streams them to subscribers.
+//
+// Events are written by GORM callbacks in the same transaction as the change
+// and announced with pg_notify, which Postgres only delivers on commit. Every
+// server instance LISTENs on the channel and hands the events to its own
+// subscribers, so a change made through one instance reaches clients of all
+// of them, and a rolled back change (an import dry run, say) reaches nobody.
+package events
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Channel is the Postgres notification channel events are announced on
+const Channel = "change_events"
+
+// Actions
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// tracked maps the tables whose changes are recorded to the entity name
+// events use and the column that says where the record is
+var tracked = map[string]struct {
+	entity string
+	area   string
+}{
+	"help_requests":      {"help_request", "location"},
+	"rescue_operations":  {"rescue_operation", "location"},
+	"volunteers":         {"volunteer", "location"},
+	"relief_supplies":    {"relief_supply", "location"},
+	"emergency_contacts": {"emergency_contact", "district"},
+}
+
+// Entities lists the entity names events are recorded for
+func Entities() []string {
+	names := make([]string, 0, len(tracked))
+	for _, t := range tracked {
+		names = append(names, t.entity)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EntityName reads an entity as named in events (help_request) or by its
+// table (help_requests)
+func EntityName(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for table, t := range tracked {
+		if name == t.entity || name == table {
+			return t.entity, true
+		}
+	}
+	return "", false
+}
+
+// Filter narrows a stream. Empty fields let everything through; Area
+// matches part of the location, ignoring case.
+type Filter struct {
+	Entities   []string
+	Priorities []string
+	Area       string
+}
+
+// Match reports whether the event passes the filter
+func (f Filter) Match(e *models.ChangeEvent) bool {
+	if len(f.Entities) > 0 && !contains(f.Entities, e.Entity) {
+		return false
+	}
+	if len(f.Priorities) > 0 && !contains(f.Priorities, strings.ToLower(e.Priority)) {
+		return false
+	}
+	if f.Area != "" && !strings.Contains(strings.ToLower(e.Area), strings.ToLower(f.Area)) {
+		return false
+	}
+	return true
+}
+
+// apply narrows a query on change_events the same way Match does
+func (f Filter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Entities) > 0 {
+		query = query.Where("entity IN ?", f.Entities)
+	}
+	if len(f.Priorities) > 0 {
+		query = query.Where("LOWER(priority) IN ?", f.Priorities)
+	}
+	if f.Area != "" {
+		query = query.Where("area ILIKE ?", "%"+f.Area+"%")
+	}
+	return query
+}
+
+// ReplayWindow is how many IDs before a client's last event are replayed
+// again. IDs are taken when an event is written but events are delivered
+// when their transaction commits, so an event can arrive after one with a
+// higher ID; clients skip the IDs they have already seen.
+const ReplayWindow = 100
+
+// replayFrom is the ID replays start after for a client that last saw lastID
+func replayFrom(lastID uint) uint {
+	return lastID - min(lastID, ReplayWindow)
+}
+
+// Since returns up to limit events after lastID, and those in the
+// ReplayWindow before it, that pass the filter, oldest first. complete is
+// false when events after lastID are no longer kept or there were more
+// than limit of them, and the client should reload its data instead of
+// replaying.
+func Since(db *gorm.DB, lastID uint, f Filter, limit int) (events []models.ChangeEvent, complete bool, err error) {
+	var pruned uint
+	if err := db.Model(&models.ChangeEventPrune{}).Select("COALESCE(MAX(pruned_through), 0)").Scan(&pruned).Error; err != nil {
+		return nil, false, err
+	}
+	// Events after lastID have already been pruned
+	if pruned > lastID {
+		return nil, false, nil
+	}
+
+	err = f.apply(db.Where("id > ?", replayFrom(lastID))).Order("id").Limit(limit + 1).Find(&events).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > limit {
+		return nil, false, nil
+	}
+	return events, true, nil
+}
+
+// Message is an event as clients receive it
+type Message struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"` // entity.action, e.g. help_request.created
+	Entity    string          `json:"entity"`
+	Action    string          `json:"action"`
+	EntityID  uint            `json:"entity_id"`
+	Priority  string          `json:"priority,omitempty"`
+	Area      string          `json:"area,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// MessageOf turns a stored event into what clients receive
+func MessageOf(e *models.ChangeEvent) Message {
+	m := Message{
+		ID:        e.ID,
+		Type:      e.Entity + "." + e.Action,
+		Entity:    e.Entity,
+		Action:    e.Action,
+		EntityID:  e.EntityID,
+		Priority:  e.Priority,
+		Area:      e.Area,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.Data != "" {
+		m.Data = json.RawMessage(e.Data)
+	}
+	return m
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
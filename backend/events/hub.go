@@ -0,0 +1,70 @@
+package events
+
+import (
+	"sync"
+
+	"flood-relief-system/backend/models"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by
+// before it is dropped. A dropped client reconnects and replays from its
+// Last-Event-ID, so nothing is lost.
+const subscriberBuffer = 256
+
+// Subscriber receives the events that pass its filter on C. C is closed
+// when the subscriber falls too far behind.
+type Subscriber struct {
+	C      chan models.ChangeEvent
+	filter Filter
+}
+
+// hub is this instance's set of subscribers
+var hub = struct {
+	sync.Mutex
+	subscribers map[*Subscriber]struct{}
+}{subscribers: map[*Subscriber]struct{}{}}
+
+// Subscribe starts receiving events that pass the filter. Call Close when
+// done.
+func Subscribe(f Filter) *Subscriber {
+	s := &Subscriber{C: make(chan models.ChangeEvent, subscriberBuffer), filter: f}
+	hub.Lock()
+	hub.subscribers[s] = struct{}{}
+	hub.Unlock()
+	return s
+}
+
+// Close stops the subscription
+func (s *Subscriber) Close() {
+	hub.Lock()
+	defer hub.Unlock()
+	if _, ok := hub.subscribers[s]; ok {
+		delete(hub.subscribers, s)
+		close(s.C)
+	}
+}
+
+// Subscribers is how many clients this instance is streaming to
+func Subscribers() int {
+	hub.Lock()
+	defer hub.Unlock()
+	return len(hub.subscribers)
+}
+
+// broadcast hands an event to every matching subscriber without waiting on
+// any of them
+func broadcast(e models.ChangeEvent) {
+	hub.Lock()
+	defer hub.Unlock()
+	for s := range hub.subscribers {
+		if !s.filter.Match(&e) {
+			continue
+		}
+		select {
+		case s.C <- e:
+		default:
+			delete(hub.subscribers, s)
+			close(s.C)
+		}
+	}
+}
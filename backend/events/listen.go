@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"flood-relief-system/backend/models"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// recentSize is how many delivered event IDs are remembered, so an event
+// seen both while catching up and as a notification goes out once
+const recentSize = 1024
+
+// delivered tracks what this instance has handed to its subscribers
+var delivered = struct {
+	sync.Mutex
+	last   uint
+	recent map[uint]struct{}
+	order  []uint
+}{recent: map[uint]struct{}{}}
+
+// deliver broadcasts an event unless it already went out
+func deliver(e models.ChangeEvent) {
+	delivered.Lock()
+	if _, seen := delivered.recent[e.ID]; seen {
+		delivered.Unlock()
+		return
+	}
+	delivered.recent[e.ID] = struct{}{}
+	delivered.order = append(delivered.order, e.ID)
+	if len(delivered.order) > recentSize {
+		delete(delivered.recent, delivered.order[0])
+		delivered.order = delivered.order[1:]
+	}
+	if e.ID > delivered.last {
+		delivered.last = e.ID
+	}
+	delivered.Unlock()
+
+	broadcast(e)
+}
+
+// Start listens for events announced by any instance and delivers them to
+// this instance's subscribers. It keeps one database connection for
+// itself and reconnects with backoff if it is lost, catching up on what was
+// missed in between.
+func Start(db *gorm.DB) {
+	// Only stream what happens from now on
+	db.Model(&models.ChangeEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&delivered.last)
+
+	go func() {
+		backoff := time.Second
+		for {
+			started := time.Now()
+			err := listen(db)
+			log.Println("❌ Change event listener stopped:", err)
+
+			if time.Since(started) > time.Minute {
+				backoff = time.Second
+			}
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+// listen holds a connection that LISTENs on Channel until it fails
+func listen(db *gorm.DB) error {
+	ctx := context.Background()
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var stopped error
+	conn.Raw(func(driverConn interface{}) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			stopped = errors.New("database driver is not pgx")
+			return nil
+		}
+		if _, err := pgConn.Conn().Exec(ctx, "LISTEN "+Channel); err != nil {
+			stopped = err
+			return driver.ErrBadConn
+		}
+		log.Printf("📡 Listening for change events on %q", Channel)
+
+		// Anything committed while we were not listening
+		if err := catchUp(db); err != nil {
+			log.Println("⚠️  Catching up on change events failed:", err)
+		}
+
+		for {
+			notification, err := pgConn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				stopped = err
+				// The connection is still listening; keep it out of the pool
+				return driver.ErrBadConn
+			}
+			id, err := strconv.ParseUint(notification.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			var event models.ChangeEvent
+			if err := db.First(&event, id).Error; err != nil {
+				log.Printf("⚠️  Change event %d could not be read: %v", id, err)
+				continue
+			}
+			deliver(event)
+		}
+	})
+	return stopped
+}
+
+// catchUp delivers the events after the last one this instance delivered,
+// starting ReplayWindow IDs early for events that committed late; deliver
+// skips the ones already sent
+func catchUp(db *gorm.DB) error {
+	delivered.Lock()
+	last := replayFrom(delivered.last)
+	delivered.Unlock()
+
+	for {
+		var batch []models.ChangeEvent
+		if err := db.Where("id > ?", last).Order("id").Limit(500).Find(&batch).Error; err != nil {
+			return err
+		}
+		for _, e := range batch {
+			deliver(e)
+			last = e.ID
+		}
+		if len(batch) < 500 {
+			return nil
+		}
+	}
+}
+
+// Prune deletes events older than keep and returns how many went. It
+// deletes up to the highest old ID and records that ID, which Since
+// compares resuming clients with.
+func Prune(db *gorm.DB, keep time.Duration) (int64, error) {
+	var through uint
+	err := db.Model(&models.ChangeEvent{}).Where("created_at < ?", time.Now().Add(-keep)).
+		Select("COALESCE(MAX(id), 0)").Scan(&through).Error
+	if err != nil || through == 0 {
+		return 0, err
+	}
+
+	var deleted int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id <= ?", through).Delete(&models.ChangeEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return tx.Create(&models.ChangeEventPrune{PrunedThrough: through}).Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,217 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// affectedKey is where the before-callbacks leave the rows an update or
+// delete is about to touch
+const affectedKey = "events:affected"
+
+// hooks run in the transaction of every recorded event
+var hooks []func(tx *gorm.DB, e *models.ChangeEvent) error
+
+// OnRecord adds work that must happen exactly when an event is recorded,
+// such as queueing webhook deliveries. It is called before Register.
+func OnRecord(hook func(tx *gorm.DB, e *models.ChangeEvent) error) {
+	hooks = append(hooks, hook)
+}
+
+// Register adds the callbacks that record changes. It runs once, after the
+// migrations, so backfills at start-up are not streamed.
+func Register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:after_create").Before("gorm:commit_or_rollback_transaction").
+		Register("events:record_create", recordCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:before_update").Before("gorm:update").
+		Register("events:find_updated", findAffected); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:after_update").Before("gorm:commit_or_rollback_transaction").
+		Register("events:record_update", recordUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:before_delete").Before("gorm:delete").
+		Register("events:find_deleted", findAffected); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:after_delete").Before("gorm:commit_or_rollback_transaction").
+		Register("events:record_delete", recordDelete)
+}
+
+// trackedTable is the table of the statement if its changes are recorded
+func trackedTable(db *gorm.DB) (string, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return "", false
+	}
+	table := db.Statement.Schema.Table
+	_, ok := tracked[table]
+	return table, ok
+}
+
+// session runs queries on the connection (and so the transaction) of the
+// statement being recorded
+func session(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true, SkipDefaultTransaction: true})
+}
+
+// recordCreate records the rows a create inserted
+func recordCreate(db *gorm.DB) {
+	if _, ok := trackedTable(db); !ok || db.RowsAffected == 0 {
+		return
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Struct:
+		record(db, Created, rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			record(db, Created, reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
+
+// findAffected loads the rows an update or delete is about to change: the
+// record itself when the statement has one with an ID, or whatever its
+// conditions match
+func findAffected(db *gorm.DB) {
+	table, ok := trackedTable(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	query := session(db).Table(table)
+	rv := reflect.Indirect(stmt.ReflectValue)
+	if id, zero := primaryKey(db, rv); !zero {
+		query = query.Where("id = ?", id)
+	} else if where, ok := stmt.Clauses["WHERE"]; ok {
+		query = query.Clauses(where.Expression)
+	} else {
+		return
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Order("id").Find(rows.Interface()).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(affectedKey, rows.Elem())
+}
+
+// recordUpdate records the rows an update changed as they are now
+func recordUpdate(db *gorm.DB) {
+	table, ok := trackedTable(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	before, ok := db.InstanceGet(affectedKey)
+	if !ok {
+		return
+	}
+
+	rows := before.(reflect.Value)
+	ids := make([]interface{}, rows.Len())
+	for i := range ids {
+		ids[i], _ = primaryKey(db, rows.Index(i))
+	}
+	after := reflect.New(rows.Type())
+	if err := session(db).Table(table).Where("id IN ?", ids).Order("id").Find(after.Interface()).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	for i := 0; i < after.Elem().Len(); i++ {
+		record(db, Updated, after.Elem().Index(i))
+	}
+}
+
+// recordDelete records the rows a delete removed as they were before it
+func recordDelete(db *gorm.DB) {
+	if _, ok := trackedTable(db); !ok || db.RowsAffected == 0 {
+		return
+	}
+	before, ok := db.InstanceGet(affectedKey)
+	if !ok {
+		return
+	}
+
+	rows := before.(reflect.Value)
+	for i := 0; i < rows.Len(); i++ {
+		record(db, Deleted, rows.Index(i))
+	}
+}
+
+// primaryKey reads the ID of a record of the statement's model
+func primaryKey(db *gorm.DB, rv reflect.Value) (interface{}, bool) {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || rv.Kind() != reflect.Struct {
+		return nil, true
+	}
+	return field.ValueOf(db.Statement.Context, rv)
+}
+
+// stringField reads a text column of a record, or "" if the model has none
+func stringField(db *gorm.DB, s *schema.Schema, rv reflect.Value, column string) string {
+	field := s.LookUpField(column)
+	if field == nil {
+		return ""
+	}
+	value, _ := field.ValueOf(db.Statement.Context, rv)
+	text, _ := value.(string)
+	return text
+}
+
+// record writes the event for one row and announces it. Both happen in the
+// statement's transaction, so they are undone if it rolls back.
+func record(db *gorm.DB, action string, rv reflect.Value) {
+	s := db.Statement.Schema
+	t := tracked[s.Table]
+
+	id, zero := primaryKey(db, rv)
+	if zero {
+		return
+	}
+	entityID, ok := id.(uint)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(rv.Interface())
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	event := models.ChangeEvent{
+		Entity:   t.entity,
+		Action:   action,
+		EntityID: entityID,
+		Priority: stringField(db, s, rv, "priority"),
+		Area:     stringField(db, s, rv, t.area),
+		Data:     string(data),
+	}
+	tx := session(db)
+	if err := tx.Create(&event).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	for _, hook := range hooks {
+		if err := hook(tx, &event); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+	if err := tx.Exec("SELECT pg_notify(?, ?)", Channel, strconv.FormatUint(uint64(event.ID), 10)).Error; err != nil {
+		db.AddError(err)
+	}
+}
@@ -0,0 +1,287 @@
+// Package forecast estimates how long relief stock will last and which
+// categories run short first.
+package forecast
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Stock statuses, most urgent first
+const (
+	Out          = "out"
+	BelowReorder = "below-reorder"
+	LowCover     = "low-cover"
+	OK           = "ok"
+)
+
+var statusRank = map[string]int{Out: 0, BelowReorder: 1, LowCover: 2, OK: 3}
+
+// envInt reads a positive integer setting, falling back to def
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// Settings tune the forecast:
+// FORECAST_WINDOW_DAYS is how far back issue rates look (default 7),
+// FORECAST_COVER_DAYS is the cover below which stock counts as low (default 3),
+// HELP_REQUEST_HOUSEHOLD_SIZE is the people behind one open help request (default 4).
+type Settings struct {
+	WindowDays    int
+	CoverDays     int
+	HouseholdSize int
+}
+
+// LoadSettings reads the settings from the environment
+func LoadSettings() Settings {
+	return Settings{
+		WindowDays:    envInt("FORECAST_WINDOW_DAYS", 7),
+		CoverDays:     envInt("FORECAST_COVER_DAYS", 3),
+		HouseholdSize: envInt("HELP_REQUEST_HOUSEHOLD_SIZE", 4),
+	}
+}
+
+// Forecast is the outlook for one reorder threshold
+type Forecast struct {
+	ThresholdID    uint     `json:"threshold_id"`
+	ItemName       string   `json:"item_name"`
+	Category       string   `json:"category"`
+	Unit           string   `json:"unit"`
+	WarehouseID    *uint    `json:"warehouse_id,omitempty"`
+	Warehouse      string   `json:"warehouse"`
+	Available      int      `json:"available"`
+	ReorderPoint   int      `json:"reorder_point"`
+	TargetQuantity int      `json:"target_quantity"`
+	IssuedPerDay   float64  `json:"issued_per_day"`
+	PeopleServed   int      `json:"people_served"`
+	NeedPerDay     float64  `json:"need_per_day"`
+	DemandPerDay   float64  `json:"demand_per_day"`
+	DaysOfCover    *float64 `json:"days_of_cover"` // empty when there is no demand
+	Status         string   `json:"status"`
+	SuggestedOrder int      `json:"suggested_order"`
+}
+
+// issuedPerDay is the average daily quantity issued over the window
+func issuedPerDay(db *gorm.DB, t *models.ReorderThreshold, windowDays int) (float64, error) {
+	query := db.Table("stock_movements").
+		Joins("JOIN relief_supplies ON relief_supplies.id = stock_movements.relief_supply_id").
+		Where("relief_supplies.item_name = ? AND relief_supplies.unit = ?", t.ItemName, t.Unit).
+		Where("stock_movements.type = ? AND stock_movements.created_at > ?", inventory.Issue, time.Now().AddDate(0, 0, -windowDays))
+	if t.WarehouseID != nil {
+		query = query.Where("relief_supplies.warehouse_id = ?", *t.WarehouseID)
+	}
+
+	var issued int
+	if err := query.Select("COALESCE(SUM(-stock_movements.quantity), 0)").Scan(&issued).Error; err != nil {
+		return 0, err
+	}
+	return float64(issued) / float64(windowDays), nil
+}
+
+// peopleServed counts shelter occupants and the people behind open help
+// requests. For a warehouse with a district only that district counts; help
+// requests have no district field, so their location text is matched.
+func peopleServed(db *gorm.DB, district string, householdSize int) (int, error) {
+	shelters := db.Model(&models.Shelter{}).Where("status = ?", "open")
+	requests := db.Model(&models.HelpRequest{}).Where("status IN ?", []string{"pending", "in-progress"})
+	if district != "" {
+		shelters = shelters.Where("district = ?", district)
+		requests = requests.Where("location ILIKE ?", "%"+district+"%")
+	}
+
+	var occupants int
+	if err := shelters.Select("COALESCE(SUM(current_occupancy), 0)").Scan(&occupants).Error; err != nil {
+		return 0, err
+	}
+
+	var openRequests int64
+	if err := requests.Count(&openRequests).Error; err != nil {
+		return 0, err
+	}
+
+	return occupants + int(openRequests)*householdSize, nil
+}
+
+// ForThreshold works out the outlook for one threshold. Demand is the
+// higher of the recent issue rate and the daily need of the people served.
+func ForThreshold(db *gorm.DB, t *models.ReorderThreshold, s Settings) (Forecast, error) {
+	f := Forecast{
+		ThresholdID:    t.ID,
+		ItemName:       t.ItemName,
+		Category:       t.Category,
+		Unit:           t.Unit,
+		WarehouseID:    t.WarehouseID,
+		Warehouse:      "All warehouses",
+		ReorderPoint:   t.ReorderPoint,
+		TargetQuantity: t.TargetQuantity,
+	}
+
+	district := ""
+	if t.WarehouseID != nil {
+		var warehouse models.Warehouse
+		if err := db.First(&warehouse, *t.WarehouseID).Error; err != nil {
+			return f, err
+		}
+		f.Warehouse = warehouse.Name
+		district = warehouse.District
+	}
+
+	var err error
+	if f.Available, err = inventory.AvailableForItem(db, t.ItemName, t.Unit, t.WarehouseID); err != nil {
+		return f, err
+	}
+	if f.IssuedPerDay, err = issuedPerDay(db, t, s.WindowDays); err != nil {
+		return f, err
+	}
+	if t.DailyPerPerson > 0 {
+		if f.PeopleServed, err = peopleServed(db, district, s.HouseholdSize); err != nil {
+			return f, err
+		}
+		f.NeedPerDay = float64(f.PeopleServed) * t.DailyPerPerson
+	}
+	f.DemandPerDay = math.Max(f.IssuedPerDay, f.NeedPerDay)
+
+	if f.DemandPerDay > 0 {
+		cover := math.Round(float64(f.Available)/f.DemandPerDay*10) / 10
+		f.DaysOfCover = &cover
+	}
+
+	switch {
+	case f.Available <= 0:
+		f.Status = Out
+	case f.Available <= t.ReorderPoint:
+		f.Status = BelowReorder
+	case f.DaysOfCover != nil && *f.DaysOfCover < float64(s.CoverDays):
+		f.Status = LowCover
+	default:
+		f.Status = OK
+	}
+
+	// Order up to the target, or enough for the cover period if demand is higher
+	if f.Status != OK {
+		want := math.Max(float64(t.TargetQuantity), math.Ceil(f.DemandPerDay*float64(s.CoverDays)))
+		f.SuggestedOrder = max(int(want)-f.Available, 0)
+	}
+
+	f.IssuedPerDay = math.Round(f.IssuedPerDay*100) / 100
+	f.NeedPerDay = math.Round(f.NeedPerDay*100) / 100
+	f.DemandPerDay = math.Round(f.DemandPerDay*100) / 100
+
+	return f, nil
+}
+
+// All forecasts every threshold, optionally for one warehouse
+func All(db *gorm.DB, warehouseID *uint, s Settings) ([]Forecast, error) {
+	query := db.Order("category, item_name, id")
+	if warehouseID != nil {
+		query = query.Where("warehouse_id = ?", *warehouseID)
+	}
+
+	var thresholds []models.ReorderThreshold
+	if err := query.Find(&thresholds).Error; err != nil {
+		return nil, err
+	}
+
+	forecasts := []Forecast{}
+	for i := range thresholds {
+		f, err := ForThreshold(db, &thresholds[i], s)
+		if err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, f)
+	}
+	return forecasts, nil
+}
+
+// less orders forecasts by urgency: worse status first, then less cover
+func less(a, b Forecast) bool {
+	if statusRank[a.Status] != statusRank[b.Status] {
+		return statusRank[a.Status] < statusRank[b.Status]
+	}
+	return coverOrMax(a.DaysOfCover) < coverOrMax(b.DaysOfCover)
+}
+
+func coverOrMax(cover *float64) float64 {
+	if cover == nil {
+		return math.MaxFloat64
+	}
+	return *cover
+}
+
+// CategoryShortage is one category of the shortage report
+type CategoryShortage struct {
+	Category          string     `json:"category"`
+	Status            string     `json:"status"` // most urgent status in the category
+	MinDaysOfCover    *float64   `json:"min_days_of_cover"`
+	ItemsOut          int        `json:"items_out"`
+	ItemsBelowReorder int        `json:"items_below_reorder"`
+	ItemsLowCover     int        `json:"items_low_cover"`
+	Items             []Forecast `json:"items"` // items that need ordering, most urgent first
+}
+
+// Shortages groups forecasts that need ordering by category, most urgent
+// category first, so procurement knows what to buy first
+func Shortages(forecasts []Forecast) []CategoryShortage {
+	index := map[string]int{}
+	report := []CategoryShortage{}
+
+	for _, f := range forecasts {
+		if f.Status == OK {
+			continue
+		}
+
+		i, ok := index[f.Category]
+		if !ok {
+			i = len(report)
+			index[f.Category] = i
+			report = append(report, CategoryShortage{Category: f.Category, Status: OK})
+		}
+		c := &report[i]
+
+		c.Items = append(c.Items, f)
+		switch f.Status {
+		case Out:
+			c.ItemsOut++
+		case BelowReorder:
+			c.ItemsBelowReorder++
+		case LowCover:
+			c.ItemsLowCover++
+		}
+		if statusRank[f.Status] < statusRank[c.Status] {
+			c.Status = f.Status
+		}
+		if f.DaysOfCover != nil && (c.MinDaysOfCover == nil || *f.DaysOfCover < *c.MinDaysOfCover) {
+			c.MinDaysOfCover = f.DaysOfCover
+		}
+	}
+
+	for i := range report {
+		items := report[i].Items
+		sort.SliceStable(items, func(a, b int) bool { return less(items[a], items[b]) })
+	}
+
+	sort.SliceStable(report, func(a, b int) bool {
+		x, y := report[a], report[b]
+		if statusRank[x.Status] != statusRank[y.Status] {
+			return statusRank[x.Status] < statusRank[y.Status]
+		}
+		if x.ItemsOut != y.ItemsOut {
+			return x.ItemsOut > y.ItemsOut
+		}
+		return coverOrMax(x.MinDaysOfCover) < coverOrMax(y.MinDaysOfCover)
+	})
+
+	return report
+}
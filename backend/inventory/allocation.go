@@ -0,0 +1,82 @@
+package inventory
+
+import (
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// openAllocationStatuses are allocations that still hold a reservation
+var openAllocationStatuses = []string{"reserved", "picked", "partially-delivered"}
+
+// Reserved is the stock promised to open allocations but not yet picked
+// off the shelf. Picked stock has already left the ledger as an issue.
+func Reserved(db *gorm.DB, supplyID uint) (int, error) {
+	var reserved int
+	err := db.Model(&models.SupplyAllocation{}).
+		Where("relief_supply_id = ? AND status IN ?", supplyID, openAllocationStatuses).
+		Select("COALESCE(SUM(quantity - picked_quantity), 0)").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// Available is the on-hand balance less open reservations
+func Available(db *gorm.DB, supplyID uint) (int, error) {
+	onHand, err := Balance(db, supplyID)
+	if err != nil {
+		return 0, err
+	}
+
+	reserved, err := Reserved(db, supplyID)
+	if err != nil {
+		return 0, err
+	}
+
+	return onHand - reserved, nil
+}
+
+// RefreshStatus derives the supply status from stock and allocations: a lot
+// that is empty and has had deliveries is "Distributed", otherwise it is
+// "Available". Expired lots keep their status.
+func RefreshStatus(tx *gorm.DB, supplyID uint) error {
+	var supply models.ReliefSupply
+	if err := tx.First(&supply, supplyID).Error; err != nil {
+		return err
+	}
+	if supply.Status == "Expired" {
+		return nil
+	}
+
+	status := "Available"
+	if supply.Quantity == 0 {
+		var delivered int64
+		err := tx.Model(&models.SupplyAllocation{}).
+			Where("relief_supply_id = ? AND delivered_quantity > 0", supplyID).
+			Count(&delivered).Error
+		if err != nil {
+			return err
+		}
+		if delivered > 0 {
+			status = "Distributed"
+		}
+	}
+
+	if status == supply.Status {
+		return nil
+	}
+	return tx.Model(&supply).Update("status", status).Error
+}
+
+// AllocationStatus derives the status of an open allocation from its
+// picked and delivered quantities
+func AllocationStatus(a *models.SupplyAllocation) string {
+	switch {
+	case a.DeliveredQuantity >= a.Quantity:
+		return "delivered"
+	case a.DeliveredQuantity > 0:
+		return "partially-delivered"
+	case a.PickedQuantity >= a.Quantity:
+		return "picked"
+	}
+	return "reserved"
+}
@@ -0,0 +1,137 @@
+package inventory
+
+import (
+	"math"
+	"time"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LotSuggestion is a lot to take stock from and how much to take
+type LotSuggestion struct {
+	ReliefSupplyID uint       `json:"relief_supply_id"`
+	WarehouseID    *uint      `json:"warehouse_id,omitempty"`
+	Location       string     `json:"location"`
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty"`
+	Available      int        `json:"available"`
+	Take           int        `json:"take"`
+	Unit           string     `json:"unit"`
+}
+
+// SuggestLots picks lots of an item in one catalogue unit
+// first-expiry-first-out: the lot that expires soonest comes first, lots
+// without an expiry date come last and ties go to the oldest lot. It
+// returns the lots to use and how much of the requested quantity could not
+// be covered.
+func SuggestLots(db *gorm.DB, itemName, unit string, warehouseID *uint, quantity int) ([]LotSuggestion, int, error) {
+	query := db.Where("item_name = ? AND unit = ? AND status = ? AND quantity > 0", itemName, unit, "Available").
+		Where("expiry_date IS NULL OR expiry_date > ?", time.Now())
+	if warehouseID != nil {
+		query = query.Where("warehouse_id = ?", *warehouseID)
+	}
+
+	var lots []models.ReliefSupply
+	if err := query.Order("expiry_date ASC NULLS LAST, created_at ASC, id ASC").Find(&lots).Error; err != nil {
+		return nil, 0, err
+	}
+
+	suggestions := []LotSuggestion{}
+	remaining := quantity
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+
+		available, err := Available(db, lot.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if available <= 0 {
+			continue
+		}
+
+		take := min(available, remaining)
+		suggestions = append(suggestions, LotSuggestion{
+			ReliefSupplyID: lot.ID,
+			WarehouseID:    lot.WarehouseID,
+			Location:       lot.Location,
+			ExpiryDate:     lot.ExpiryDate,
+			Available:      available,
+			Take:           take,
+			Unit:           lot.Unit,
+		})
+		remaining -= take
+	}
+
+	return suggestions, remaining, nil
+}
+
+// LockLots suggests lots like SuggestLots, then locks each one and checks
+// again that it still has what it is to give, in case another request took
+// the stock in between. It returns ErrNegativeStock when one no longer
+// does; a shortfall is returned as by SuggestLots, with no lots.
+func LockLots(tx *gorm.DB, itemName, unit string, warehouseID *uint, quantity int) ([]LotSuggestion, int, error) {
+	lots, short, err := SuggestLots(tx, itemName, unit, warehouseID, quantity)
+	if err != nil || short > 0 {
+		return nil, short, err
+	}
+
+	for _, lot := range lots {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.ReliefSupply{}, lot.ReliefSupplyID).Error; err != nil {
+			return nil, 0, err
+		}
+		available, err := Available(tx, lot.ReliefSupplyID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if available < lot.Take {
+			return nil, 0, ErrNegativeStock
+		}
+	}
+
+	return lots, 0, nil
+}
+
+// IssueLots issues quantity of an item first-expiry-first-out, one issue
+// movement per lot locked by LockLots. It returns the lots it took from, or
+// the shortfall when the stock cannot cover quantity.
+func IssueLots(tx *gorm.DB, itemName, unit string, warehouseID *uint, quantity int, actor, reason, reference string) ([]LotSuggestion, int, error) {
+	lots, short, err := LockLots(tx, itemName, unit, warehouseID, quantity)
+	if err != nil || short > 0 {
+		return nil, short, err
+	}
+
+	for _, lot := range lots {
+		err := Record(tx, &models.StockMovement{
+			ReliefSupplyID: lot.ReliefSupplyID,
+			Type:           Issue,
+			Quantity:       -lot.Take,
+			Actor:          actor,
+			Reason:         reason,
+			Reference:      reference,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return lots, 0, nil
+}
+
+// AvailableForItem is the unreserved stock of an item in one unit across
+// all usable lots, optionally in one warehouse
+func AvailableForItem(db *gorm.DB, itemName, unit string, warehouseID *uint) (int, error) {
+	lots, _, err := SuggestLots(db, itemName, unit, warehouseID, math.MaxInt)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, lot := range lots {
+		total += lot.Take
+	}
+	return total, nil
+}
@@ -0,0 +1,178 @@
+// Package inventory keeps relief supply stock in a movement ledger.
+// ReliefSupply.Quantity is a cached copy of the ledger balance and is only
+// ever written here.
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Movement types
+const (
+	Receipt    = "receipt"
+	Issue      = "issue"
+	Adjustment = "adjustment"
+	Transfer   = "transfer"
+	WriteOff   = "write-off"
+	Expiry     = "expiry"
+)
+
+// MovementTypes lists every valid movement type
+var MovementTypes = []string{Receipt, Issue, Adjustment, Transfer, WriteOff, Expiry}
+
+var (
+	ErrNegativeStock   = errors.New("movement would make stock negative")
+	ErrReservedStock   = errors.New("movement would take stock reserved for allocations")
+	ErrInvalidType     = errors.New("invalid movement type")
+	ErrInvalidQuantity = errors.New("quantity must not be zero")
+	ErrUnitMismatch    = errors.New("unit does not match the supply unit")
+)
+
+// IsValidType reports whether t is a known movement type
+func IsValidType(t string) bool {
+	for _, v := range MovementTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Signed turns the positive quantity a user enters into a ledger delta.
+// Adjustments and transfers keep the sign they were given.
+func Signed(movementType string, quantity int) int {
+	switch movementType {
+	case Issue, WriteOff, Expiry:
+		if quantity > 0 {
+			return -quantity
+		}
+	case Receipt:
+		if quantity < 0 {
+			return -quantity
+		}
+	}
+	return quantity
+}
+
+// Balance sums the ledger for one supply
+func Balance(db *gorm.DB, supplyID uint) (int, error) {
+	var balance int
+	err := db.Model(&models.StockMovement{}).
+		Where("relief_supply_id = ?", supplyID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// Record appends a movement with a signed quantity to the ledger and
+// refreshes the cached quantity on the supply. It must run inside a
+// transaction; the supply row is locked so concurrent movements serialize.
+// Stock leaving the lot may not take what open allocations have reserved,
+// so a pick must count itself as picked before its issue is recorded.
+func Record(tx *gorm.DB, m *models.StockMovement) error {
+	if !IsValidType(m.Type) {
+		return ErrInvalidType
+	}
+	if m.Quantity == 0 {
+		return ErrInvalidQuantity
+	}
+
+	var supply models.ReliefSupply
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&supply, m.ReliefSupplyID).Error; err != nil {
+		return err
+	}
+
+	if m.Unit == "" {
+		m.Unit = supply.Unit
+	}
+	if !strings.EqualFold(m.Unit, supply.Unit) {
+		return ErrUnitMismatch
+	}
+	m.Unit = supply.Unit
+
+	balance, err := Balance(tx, supply.ID)
+	if err != nil {
+		return err
+	}
+
+	newBalance := balance + m.Quantity
+	if newBalance < 0 {
+		return fmt.Errorf("%w: %d %s on hand", ErrNegativeStock, balance, supply.Unit)
+	}
+	if m.Quantity < 0 {
+		reserved, err := Reserved(tx, supply.ID)
+		if err != nil {
+			return err
+		}
+		if newBalance < reserved {
+			return fmt.Errorf("%w: %d %s reserved", ErrReservedStock, reserved, supply.Unit)
+		}
+	}
+	m.BalanceAfter = newBalance
+
+	if err := tx.Create(m).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&supply).Update("quantity", newBalance).Error; err != nil {
+		return err
+	}
+
+	return RefreshStatus(tx, supply.ID)
+}
+
+// Move records a transfer out of one supply lot and into another
+func Move(tx *gorm.DB, fromID, toID uint, quantity int, actor, reportedBy, reason string) (out, in *models.StockMovement, err error) {
+	if quantity <= 0 {
+		return nil, nil, ErrInvalidQuantity
+	}
+
+	// Lock both lots in ID order so two opposite transfers cannot deadlock
+	first, second := fromID, toID
+	if second < first {
+		first, second = second, first
+	}
+	var lots []models.ReliefSupply
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", []uint{first, second}).Order("id").Find(&lots).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(lots) != 2 {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	out = &models.StockMovement{
+		ReliefSupplyID: fromID,
+		Type:           Transfer,
+		Quantity:       -quantity,
+		Actor:          actor,
+		ReportedBy:     reportedBy,
+		Reason:         reason,
+		Reference:      fmt.Sprintf("to supply #%d", toID),
+	}
+	if err := Record(tx, out); err != nil {
+		return nil, nil, err
+	}
+
+	in = &models.StockMovement{
+		ReliefSupplyID: toID,
+		Type:           Transfer,
+		Quantity:       quantity,
+		Unit:           out.Unit,
+		Actor:          actor,
+		ReportedBy:     reportedBy,
+		Reason:         reason,
+		Reference:      fmt.Sprintf("from supply #%d", fromID),
+	}
+	if err := Record(tx, in); err != nil {
+		return nil, nil, err
+	}
+
+	return out, in, nil
+}
@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"fmt"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FindOrCreateLot returns the lot in the warehouse that holds the same goods
+// as source (same item, category, unit, donor and expiry date), creating an
+// empty one when the warehouse has none yet.
+func FindOrCreateLot(tx *gorm.DB, source *models.ReliefSupply, warehouse *models.Warehouse) (*models.ReliefSupply, error) {
+	query := tx.Where("warehouse_id = ? AND item_name = ? AND category = ? AND unit = ? AND donor_name = ? AND id <> ?",
+		warehouse.ID, source.ItemName, source.Category, source.Unit, source.DonorName, source.ID)
+	if source.ExpiryDate != nil {
+		query = query.Where("expiry_date = ?", *source.ExpiryDate)
+	} else {
+		query = query.Where("expiry_date IS NULL")
+	}
+
+	var lot models.ReliefSupply
+	err := query.Order("id").First(&lot).Error
+	if err == nil {
+		return &lot, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	lot = models.ReliefSupply{
+		ItemName:    source.ItemName,
+		Category:    source.Category,
+		Quantity:    0,
+		Unit:        source.Unit,
+		DonorID:     source.DonorID,
+		DonorName:   source.DonorName,
+		DonorPhone:  source.DonorPhone,
+		Location:    warehouse.Name,
+		WarehouseID: &warehouse.ID,
+		Status:      "Available",
+		ExpiryDate:  source.ExpiryDate,
+		Notes:       fmt.Sprintf("Received by transfer from supply #%d", source.ID),
+	}
+	if err := tx.Create(&lot).Error; err != nil {
+		return nil, err
+	}
+
+	return &lot, nil
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/events"
+)
+
+// StartChangeEventPruneJob deletes change events older than
+// EVENT_RETENTION_HOURS (default 72) once an hour. Clients that were away
+// longer than that reload instead of replaying.
+func StartChangeEventPruneJob() {
+	keep := time.Duration(envInt("EVENT_RETENTION_HOURS", 72)) * time.Hour
+
+	go func() {
+		for {
+			if count, err := events.Prune(config.GetDB(), keep); err != nil {
+				log.Println("❌ Change event prune job failed:", err)
+			} else if count > 0 {
+				log.Printf("🧹 Change event prune job deleted %d old events", count)
+			}
+
+			time.Sleep(time.Hour)
+		}
+	}()
+}
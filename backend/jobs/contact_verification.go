@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// VerificationDays is how long a verified emergency contact stays fresh,
+// from CONTACT_VERIFICATION_DAYS (default 30)
+func VerificationDays() int {
+	return envInt("CONTACT_VERIFICATION_DAYS", 30)
+}
+
+// StartContactVerificationJob flags stale emergency contacts every
+// CONTACT_VERIFICATION_JOB_INTERVAL_MINUTES (default 360)
+func StartContactVerificationJob() {
+	interval := time.Duration(envInt("CONTACT_VERIFICATION_JOB_INTERVAL_MINUTES", 360)) * time.Minute
+
+	go func() {
+		for {
+			if count, err := FlagStaleContacts(config.GetDB(), VerificationDays()); err != nil {
+				log.Println("❌ Contact verification job failed:", err)
+			} else if count > 0 {
+				log.Printf("☎️  Contact verification job flagged %d contacts as due for verification", count)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// FlagStaleContacts marks verified contacts not verified in the last days
+// as due. It returns the number of contacts flagged.
+func FlagStaleContacts(db *gorm.DB, days int) (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	result := db.Model(&models.EmergencyContact{}).
+		Where("verification_status = ? AND (last_verified_at IS NULL OR last_verified_at < ?)", "verified", cutoff).
+		Update("verification_status", "due")
+	return int(result.RowsAffected), result.Error
+}
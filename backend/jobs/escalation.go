@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/oncall"
+)
+
+// StartEscalationJob moves unacknowledged escalations to the next contact,
+// checking every ESCALATION_JOB_INTERVAL_MINUTES (default 1)
+func StartEscalationJob() {
+	interval := time.Duration(envInt("ESCALATION_JOB_INTERVAL_MINUTES", 1)) * time.Minute
+
+	go func() {
+		for {
+			if count, err := oncall.Advance(config.GetDB(), time.Now()); err != nil {
+				log.Println("❌ Escalation job failed:", err)
+			} else if count > 0 {
+				log.Printf("📟 Escalation job moved %d call-outs to the next contact", count)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
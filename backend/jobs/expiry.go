@@ -0,0 +1,182 @@
+// Package jobs runs the periodic background tasks of the server.
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/inventory"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/notify"
+	"flood-relief-system/backend/phone"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// envInt reads a positive integer setting, falling back to def
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// StartExpiryJob expires lots every EXPIRY_JOB_INTERVAL_MINUTES (default 60)
+// and sends the expiring-soon alert once a day
+func StartExpiryJob() {
+	interval := time.Duration(envInt("EXPIRY_JOB_INTERVAL_MINUTES", 60)) * time.Minute
+
+	go func() {
+		var lastAlert time.Time
+		for {
+			db := config.GetDB()
+
+			if count, err := ExpireSupplies(db); err != nil {
+				log.Println("❌ Expiry job failed:", err)
+			} else if count > 0 {
+				log.Printf("🗑️  Expiry job marked %d relief supply lots as Expired", count)
+			}
+
+			if time.Since(lastAlert) >= 24*time.Hour {
+				if err := SendExpiryAlert(db); err != nil {
+					log.Println("❌ Expiry alert failed:", err)
+				} else {
+					lastAlert = time.Now()
+				}
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// errAlreadyExpired rolls back a lot that another run expired first
+var errAlreadyExpired = errors.New("lot already expired")
+
+// ExpireSupplies marks every lot past its expiry date as Expired, writes the
+// remaining stock off the ledger as an expiry movement and releases stock
+// that was reserved but not yet picked. A lot that fails is logged and left
+// for the next run. It returns the number of lots expired.
+func ExpireSupplies(db *gorm.DB) (int, error) {
+	var lots []models.ReliefSupply
+	err := db.Where("expiry_date IS NOT NULL AND expiry_date <= ? AND status <> ?", time.Now(), "Expired").
+		Find(&lots).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Lock the lot so no movement lands between the balance and the
+			// write-off, and skip it if another run expired it first
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lot, lot.ID).Error; err != nil {
+				return err
+			}
+			if lot.Status == "Expired" {
+				return errAlreadyExpired
+			}
+
+			// Set the status first so the ledger does not derive a new one
+			if err := tx.Model(&lot).Update("status", "Expired").Error; err != nil {
+				return err
+			}
+
+			if err := releaseReservations(tx, lot.ID); err != nil {
+				return err
+			}
+
+			balance, err := inventory.Balance(tx, lot.ID)
+			if err != nil {
+				return err
+			}
+			if balance <= 0 {
+				return nil
+			}
+
+			return inventory.Record(tx, &models.StockMovement{
+				ReliefSupplyID: lot.ID,
+				Type:           inventory.Expiry,
+				Quantity:       -balance,
+				Actor:          "system",
+				Reason:         fmt.Sprintf("Expired on %s", lot.ExpiryDate.Format("2006-01-02")),
+			})
+		})
+		if errors.Is(err, errAlreadyExpired) {
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Could not expire relief supply #%d: %v", lot.ID, err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// releaseReservations shrinks open allocations on an expired lot to what was
+// already picked; allocations with nothing picked are cancelled
+func releaseReservations(tx *gorm.DB, supplyID uint) error {
+	var allocations []models.SupplyAllocation
+	err := tx.Where("relief_supply_id = ? AND status IN ? AND picked_quantity < quantity", supplyID, []string{"reserved", "partially-delivered"}).
+		Find(&allocations).Error
+	if err != nil {
+		return err
+	}
+
+	for _, allocation := range allocations {
+		allocation.Quantity = allocation.PickedQuantity
+		if allocation.Quantity == 0 {
+			allocation.Status = "cancelled"
+		} else {
+			allocation.Status = inventory.AllocationStatus(&allocation)
+		}
+		allocation.Notes = "Reservation released: lot expired"
+
+		if err := tx.Save(&allocation).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpiringSoon lists available lots that expire within the next days
+func ExpiringSoon(db *gorm.DB, days int) ([]models.ReliefSupply, error) {
+	now := time.Now()
+
+	lots := []models.ReliefSupply{}
+	err := db.Where("status = ? AND quantity > 0 AND expiry_date > ? AND expiry_date <= ?", "Available", now, now.AddDate(0, 0, days)).
+		Order("expiry_date ASC").
+		Find(&lots).Error
+	return lots, err
+}
+
+// SendExpiryAlert sends a summary of lots expiring within EXPIRY_ALERT_DAYS
+// (default 7) to EXPIRY_ALERT_PHONE, or logs it when no phone is set
+func SendExpiryAlert(db *gorm.DB) error {
+	days := envInt("EXPIRY_ALERT_DAYS", 7)
+
+	lots, err := ExpiringSoon(db, days)
+	if err != nil || len(lots) == 0 {
+		return err
+	}
+
+	message := fmt.Sprintf("Flood Relief: %d supply lots expire within %d days. First: %s (%d %s) on %s.",
+		len(lots), days, lots[0].ItemName, lots[0].Quantity, lots[0].Unit, lots[0].ExpiryDate.Format("2006-01-02"))
+
+	to := phone.Key(os.Getenv("EXPIRY_ALERT_PHONE"))
+	if to == "" {
+		log.Println("⚠️ ", message)
+		return nil
+	}
+	return notify.SendSMS(to, message)
+}
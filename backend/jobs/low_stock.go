@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/forecast"
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/notify"
+	"flood-relief-system/backend/phone"
+
+	"gorm.io/gorm"
+)
+
+// StartLowStockJob checks reorder thresholds every
+// LOW_STOCK_JOB_INTERVAL_MINUTES (default 60)
+func StartLowStockJob() {
+	interval := time.Duration(envInt("LOW_STOCK_JOB_INTERVAL_MINUTES", 60)) * time.Minute
+
+	go func() {
+		for {
+			if count, err := CheckLowStock(config.GetDB()); err != nil {
+				log.Println("❌ Low stock job failed:", err)
+			} else if count > 0 {
+				log.Printf("📉 Low stock job sent %d reorder alerts", count)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// CheckLowStock alerts once when a threshold drops to its reorder point or
+// runs out, and re-arms the alert when stock recovers. Each alert is claimed
+// by setting alerted_at before it is sent, so two instances running the
+// job do not both send it. Alerts go to the warehouse manager, or to
+// LOW_STOCK_ALERT_PHONE for network-wide thresholds and warehouses without
+// a manager phone. It returns the number of alerts sent.
+func CheckLowStock(db *gorm.DB) (int, error) {
+	settings := forecast.LoadSettings()
+
+	var thresholds []models.ReorderThreshold
+	if err := db.Find(&thresholds).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, t := range thresholds {
+		f, err := forecast.ForThreshold(db, &t, settings)
+		if err != nil {
+			return sent, err
+		}
+
+		low := f.Status == forecast.Out || f.Status == forecast.BelowReorder
+		if !low {
+			if t.AlertedAt != nil {
+				db.Model(&t).Update("alerted_at", nil)
+			}
+			continue
+		}
+		if t.AlertedAt != nil {
+			continue
+		}
+
+		// Postgres keeps microseconds, so the claim can be matched again on release
+		now := time.Now().Truncate(time.Microsecond)
+		claim := db.Model(&models.ReorderThreshold{}).Where("id = ? AND alerted_at IS NULL", t.ID).Update("alerted_at", &now)
+		if claim.Error != nil {
+			return sent, claim.Error
+		}
+		if claim.RowsAffected != 1 {
+			continue
+		}
+
+		message := fmt.Sprintf("Flood Relief: %s at %s is down to %d %s (reorder at %d). Suggested order: %d %s.",
+			f.ItemName, f.Warehouse, f.Available, f.Unit, f.ReorderPoint, f.SuggestedOrder, f.Unit)
+		if f.DaysOfCover != nil {
+			message += fmt.Sprintf(" About %.1f days of cover left.", *f.DaysOfCover)
+		}
+
+		to := phone.Key(os.Getenv("LOW_STOCK_ALERT_PHONE"))
+		if t.WarehouseID != nil {
+			var warehouse models.Warehouse
+			if err := db.First(&warehouse, *t.WarehouseID).Error; err == nil && warehouse.ManagerPhoneE164 != "" {
+				to = warehouse.ManagerPhoneE164
+			}
+		}
+
+		if to == "" {
+			log.Println("⚠️ ", message)
+		} else if err := notify.SendSMS(to, message); err != nil {
+			// Release the claim so the next run tries again
+			log.Println("❌ Low stock alert failed:", err)
+			db.Model(&models.ReorderThreshold{}).Where("id = ? AND alerted_at = ?", t.ID, now).Update("alerted_at", nil)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/events"
+	"flood-relief-system/backend/webhooks"
+)
+
+// StartWebhookJob sends due webhook deliveries as soon as a change event
+// arrives, and every WEBHOOK_JOB_INTERVAL_SECONDS (default 5) for retries
+func StartWebhookJob() {
+	interval := time.Duration(envInt("WEBHOOK_JOB_INTERVAL_SECONDS", 5)) * time.Second
+	settings := webhooks.LoadSettings()
+
+	go func() {
+		// New events mean new deliveries; the filter lets everything through
+		sub := events.Subscribe(events.Filter{})
+		for {
+			if count, err := webhooks.DeliverDue(config.GetDB(), settings); err != nil {
+				log.Println("❌ Webhook job failed:", err)
+			} else if count > 0 {
+				log.Printf("🔗 Webhook job attempted %d deliveries", count)
+			}
+
+			select {
+			case <-time.After(interval):
+			case _, ok := <-sub.C:
+				if !ok {
+					sub = events.Subscribe(events.Filter{})
+				}
+			}
+		}
+	}()
+}
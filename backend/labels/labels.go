@@ -0,0 +1,198 @@
+// Package labels renders the QR and Code128 labels stuck on relief supply
+// lots, as PNG images and as printable PDF sheets.
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+
+	"flood-relief-system/backend/models"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Label types
+const (
+	QR      = "qr"
+	Code128 = "code128"
+)
+
+// IsType reports whether t is a supported label type
+func IsType(t string) bool {
+	return t == QR || t == Code128
+}
+
+// encode draws the bare symbol for a label code, scaled up by factor
+func encode(code, labelType string, factor int) (barcode.Barcode, error) {
+	var bc barcode.Barcode
+	var err error
+	if labelType == Code128 {
+		bc, err = code128.Encode(code)
+	} else {
+		bc, err = qr.Encode(code, qr.M, qr.Auto)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := bc.Bounds()
+	height := bounds.Dy() * factor
+	if labelType == Code128 {
+		height = 40 * factor // 1D codes are one module tall
+	}
+	return barcode.Scale(bc, bounds.Dx()*factor, height)
+}
+
+// PNG writes the symbol with the code printed underneath, for label printers
+// that take images
+func PNG(w io.Writer, code, labelType string) error {
+	factor := 8
+	if labelType == Code128 {
+		factor = 3
+	}
+	bc, err := encode(code, labelType, factor)
+	if err != nil {
+		return err
+	}
+
+	const quiet, textHeight = 16, 20
+	bounds := bc.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx()+2*quiet, bounds.Dy()+2*quiet+textHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, bounds.Add(image.Pt(quiet, quiet)), bc, bounds.Min, draw.Src)
+
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(color.Black), Face: face}
+	x := (img.Bounds().Dx() - drawer.MeasureString(code).Round()) / 2
+	drawer.Dot = fixed.P(x, quiet+bounds.Dy()+textHeight-4)
+	drawer.DrawString(code)
+
+	return png.Encode(w, img)
+}
+
+// Sheet layout: 3 x 8 labels of 70 x 37 mm on A4, the common address
+// label sheet
+const (
+	sheetColumns = 3
+	sheetRows    = 8
+	labelWidth   = 70.0
+	labelHeight  = 37.0
+	sheetTop     = (297 - sheetRows*labelHeight) / 2
+)
+
+// newPDF starts a document, using LABEL_FONT (a TrueType font) for item
+// names when set and Helvetica otherwise
+func newPDF(orientation string, size fpdf.SizeType) (*fpdf.Fpdf, func(string) string, error) {
+	pdf := fpdf.NewCustom(&fpdf.InitType{OrientationStr: orientation, UnitStr: "mm", Size: size})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	if path := os.Getenv("LABEL_FONT"); path != "" {
+		font, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading label font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes("label", "", font)
+		pdf.SetFont("label", "", 9)
+		return pdf, func(s string) string { return s }, nil
+	}
+
+	pdf.SetFont("Helvetica", "", 9)
+	return pdf, pdf.UnicodeTranslatorFromDescriptor(""), nil
+}
+
+// drawLabel places one lot's label with its top-left corner at x, y
+func drawLabel(pdf *fpdf.Fpdf, text func(string) string, x, y float64, supply *models.ReliefSupply, labelType string) error {
+	bc, err := encode(supply.LabelCode, labelType, 4)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, bc); err != nil {
+		return err
+	}
+	options := fpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(supply.LabelCode+labelType, options, &buf)
+
+	const pad = 3.0
+	lines := []string{supply.ItemName, fmt.Sprintf("%d %s", supply.Quantity, supply.Unit)}
+	if supply.ExpiryDate != nil {
+		lines = append(lines, "Exp "+supply.ExpiryDate.Format("2006-01-02"))
+	}
+
+	if labelType == Code128 {
+		// Barcode across the top, details underneath
+		pdf.ImageOptions(supply.LabelCode+labelType, x+pad, y+pad, labelWidth-2*pad, 14, false, options, 0, "")
+		pdf.SetXY(x+pad, y+pad+15)
+		pdf.CellFormat(labelWidth-2*pad, 4, supply.LabelCode, "", 2, "C", false, 0, "")
+		pdf.CellFormat(labelWidth-2*pad, 4, text(lines[0]+"  "+lines[1]), "", 2, "C", false, 0, "")
+		if len(lines) > 2 {
+			pdf.CellFormat(labelWidth-2*pad, 4, lines[2], "", 2, "C", false, 0, "")
+		}
+		return nil
+	}
+
+	// QR code on the left, details on the right
+	side := labelHeight - 2*pad
+	pdf.ImageOptions(supply.LabelCode+labelType, x+pad, y+pad, side, side, false, options, 0, "")
+	textX := x + pad + side + 2
+	textWidth := labelWidth - side - 2*pad - 2
+	pdf.SetXY(textX, y+pad+2)
+	pdf.MultiCell(textWidth, 4, text(lines[0]), "", "L", false)
+	for _, line := range append([]string{supply.LabelCode}, lines[1:]...) {
+		pdf.SetX(textX)
+		pdf.CellFormat(textWidth, 4, text(line), "", 2, "L", false, 0, "")
+	}
+	return nil
+}
+
+// Sheet writes the lots' labels onto as many A4 sheets as they need
+func Sheet(w io.Writer, supplies []models.ReliefSupply, labelType string) error {
+	pdf, text, err := newPDF("P", fpdf.SizeType{Wd: 210, Ht: 297})
+	if err != nil {
+		return err
+	}
+
+	perPage := sheetColumns * sheetRows
+	left := (210 - sheetColumns*labelWidth) / 2
+	for i := range supplies {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		slot := i % perPage
+		x := left + float64(slot%sheetColumns)*labelWidth
+		y := sheetTop + float64(slot/sheetColumns)*labelHeight
+		if err := drawLabel(pdf, text, x, y, &supplies[i], labelType); err != nil {
+			return err
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// Single writes one label on a page the size of the label, for roll printers
+func Single(w io.Writer, supply *models.ReliefSupply, labelType string) error {
+	pdf, text, err := newPDF("P", fpdf.SizeType{Wd: labelWidth, Ht: labelHeight})
+	if err != nil {
+		return err
+	}
+
+	pdf.AddPage()
+	if err := drawLabel(pdf, text, 0, 0, supply, labelType); err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
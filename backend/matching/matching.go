@@ -0,0 +1,318 @@
+// Package matching compares missing-person reports with people already
+// known to the system (evacuees and help requests).
+package matching
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"flood-relief-system/backend/phone"
+)
+
+// Threshold is the lowest score that is put in the review queue
+const Threshold = 0.75
+
+// Person is one side of a comparison. Zero values mean "unknown".
+type Person struct {
+	Name    string
+	Phone   string
+	Age     int    // exact age, when known
+	AgeBand string // 0-4, 5-17, 18-59, 60+
+}
+
+// spellingVariants folds the common ways the same Sinhala or Tamil sound is
+// written in English, e.g. "Thilak"/"Tilak", "Sivakumar"/"Shivakumar".
+var spellingVariants = []struct{ from, to string }{
+	{"th", "t"}, {"dh", "d"}, {"kh", "k"}, {"gh", "g"}, {"bh", "b"},
+	{"ph", "p"}, {"jh", "j"}, {"sh", "s"}, {"zh", "l"}, {"ch", "c"},
+	{"aa", "a"}, {"ee", "i"}, {"ii", "i"}, {"oo", "u"}, {"uu", "u"},
+	{"ae", "e"}, {"w", "v"}, {"z", "s"}, {"q", "k"}, {"x", "ks"},
+	{"y", "i"},
+}
+
+// Key reduces a name to a phonetic key so spelling variants compare equal
+func Key(name string) string {
+	latin := strings.ToLower(Transliterate(name))
+
+	var b strings.Builder
+	for _, r := range latin {
+		if unicode.IsLetter(r) && r < unicode.MaxASCII {
+			b.WriteRune(r)
+		}
+	}
+	key := b.String()
+
+	for _, v := range spellingVariants {
+		key = strings.ReplaceAll(key, v.from, v.to)
+	}
+
+	// Doubled letters carry no meaning once the variants are folded
+	var out []rune
+	for _, r := range key {
+		if len(out) > 0 && out[len(out)-1] == r {
+			continue
+		}
+		out = append(out, r)
+	}
+
+	return string(out)
+}
+
+// isNameSeparator splits a name into words
+func isNameSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '.' || r == ',' || r == '-'
+}
+
+// tokens splits a name into phonetic keys, one per word
+func tokens(name string) []string {
+	fields := strings.FieldsFunc(Transliterate(name), isNameSeparator)
+
+	keys := []string{}
+	for _, f := range fields {
+		if k := Key(f); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// NameScore compares two names word by word, ignoring word order and
+// treating a single letter as an initial.
+func NameScore(a, b string) float64 {
+	ta, tb := tokens(a), tokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	// Compare from the side with fewer words; with as many words on each
+	// side, both ways, so the score does not depend on the argument order
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+	score := wordScore(ta, tb)
+	if len(ta) == len(tb) {
+		score = min(score, wordScore(tb, ta))
+	}
+	return score
+}
+
+// wordScore is the average over the words of ta of their best match in tb
+func wordScore(ta, tb []string) float64 {
+	total := 0.0
+	for _, x := range ta {
+		best := 0.0
+		for _, y := range tb {
+			var s float64
+			if len(x) == 1 || len(y) == 1 {
+				if x[0] == y[0] {
+					s = 0.9
+				}
+			} else {
+				s = JaroWinkler(x, y)
+			}
+			if s > best {
+				best = s
+			}
+		}
+		total += best
+	}
+
+	return total / float64(len(ta))
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings (0 to 1)
+func JaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+
+	matches := 0
+	for i := range ra {
+		lo := max(0, i-window)
+		hi := min(len(rb)-1, i+window)
+		for j := lo; j <= hi; j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// bandRange returns the ages covered by an age band
+func bandRange(band string) (int, int, bool) {
+	switch band {
+	case "0-4":
+		return 0, 4, true
+	case "5-17":
+		return 5, 17, true
+	case "18-59":
+		return 18, 59, true
+	case "60+":
+		return 60, 150, true
+	}
+	return 0, 0, false
+}
+
+// NameKey is the phonetic key of every word of a name, separated by
+// spaces. Names in Sinhala, Tamil and English spellings of them share the
+// same key, so it is stored next to the name for SQL lookups.
+func NameKey(name string) string {
+	return strings.Join(tokens(name), " ")
+}
+
+// Initials lists the letters the words of a name key start with. Candidates
+// whose key has no word starting with one of them are not worth scoring,
+// which lets callers narrow the candidates in SQL whatever script either
+// name was written in.
+func Initials(name string) []string {
+	seen := map[byte]bool{}
+	initials := []string{}
+	for _, key := range tokens(name) {
+		if !seen[key[0]] {
+			seen[key[0]] = true
+			initials = append(initials, key[:1])
+		}
+	}
+	return initials
+}
+
+// BandAges returns the exact ages that compare with an age band: the band
+// itself and three years either side, for guesses near the boundary
+func BandAges(band string) (int, int, bool) {
+	lo, hi, ok := bandRange(band)
+	if !ok {
+		return 0, 0, false
+	}
+	return lo - 3, hi + 3, true
+}
+
+// Bands lists the age bands an exact age compares with
+func Bands(age int) []string {
+	bands := []string{}
+	for _, band := range []string{"0-4", "5-17", "18-59", "60+"} {
+		if lo, hi, _ := BandAges(band); age >= lo && age <= hi {
+			bands = append(bands, band)
+		}
+	}
+	return bands
+}
+
+// ageScore compares ages; ok is false when either side has no age
+func ageScore(a, b Person) (score float64, ok bool) {
+	// Exact ages on both sides
+	if a.Age > 0 && b.Age > 0 {
+		diff := a.Age - b.Age
+		if diff < 0 {
+			diff = -diff
+		}
+		switch {
+		case diff <= 2:
+			return 1, true
+		case diff <= 5:
+			return 0.5, true
+		}
+		return 0, true
+	}
+
+	// Exact age against a band, allowing for a guess near the boundary
+	age, band := a.Age, b.AgeBand
+	if age == 0 {
+		age, band = b.Age, a.AgeBand
+	}
+	lo, hi, known := bandRange(band)
+	if age == 0 || !known {
+		return 0, false
+	}
+	switch {
+	case age >= lo && age <= hi:
+		return 1, true
+	case age >= lo-3 && age <= hi+3:
+		return 0.5, true
+	}
+	return 0, true
+}
+
+// Score compares a report with a candidate. Name, phone and age are weighted
+// 0.6, 0.3 and 0.1; fields missing on either side are left out of the average.
+// The reasons explain the score to the person reviewing the match.
+func Score(report, candidate Person) (float64, []string) {
+	reasons := []string{}
+
+	nameScore := NameScore(report.Name, candidate.Name)
+	weighted, weights := nameScore*0.6, 0.6
+	reasons = append(reasons, fmt.Sprintf("name %.0f%%", nameScore*100))
+
+	phoneMatch := false
+	pa, pb := phone.Key(report.Phone), phone.Key(candidate.Phone)
+	if pa != "" && pb != "" {
+		weights += 0.3
+		if pa == pb {
+			phoneMatch = true
+			weighted += 0.3
+			reasons = append(reasons, "same phone")
+		} else {
+			reasons = append(reasons, "different phone")
+		}
+	}
+
+	if s, ok := ageScore(report, candidate); ok {
+		weights += 0.1
+		weighted += s * 0.1
+		reasons = append(reasons, fmt.Sprintf("age %.0f%%", s*100))
+	}
+
+	score := weighted / weights
+
+	// A matching phone is strong evidence even with a poorly spelled name,
+	// but a name that does not look alike on its own is not enough.
+	if !phoneMatch && nameScore < 0.7 {
+		score = min(score, nameScore)
+	}
+
+	return score, reasons
+}
@@ -0,0 +1,160 @@
+package matching
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"කමල්", "kamal"},
+		{"පෙරේරා", "pereeraa"},
+		{"ලක්ෂ්මී", "lakshmii"},
+		{"ශ්‍රී", "shrii"}, // conjunct written with a zero-width joiner
+		{"சிவகுமார்", "sivakumaar"},
+		{"ஸ்ரீ", "srii"},
+		{"Kamal Perera", "Kamal Perera"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Transliterate(tt.text); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Thilak", "Tilak"},
+		{"Sivakumar", "Shivakumar"},
+		{"Wijesinghe", "Vijesinghe"},
+		{"Lakshmi", "Laxmi"},
+		{"Perera", "Pereraa"},
+		{"පෙරේරා", "Pereeraa"},
+		{"சிவகுமார்", "Sivakumar"},
+		{"ශ්‍රී", "ஸ்ரீ"},
+	}
+
+	for _, tt := range tests {
+		if ka, kb := Key(tt.a), Key(tt.b); ka != kb {
+			t.Errorf("Key(%q) = %q, Key(%q) = %q, want equal", tt.a, ka, tt.b, kb)
+		}
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{"same name", "Kamal Perera", "Kamal Perera", 1, 1},
+		{"word order", "Kamal Perera", "Perera Kamal", 1, 1},
+		{"spelling variant", "Thilak Fernando", "Tilak Fernando", 1, 1},
+		{"initial", "K. Perera", "Kamal Perera", 0.9, 0.99},
+		{"fewer words", "Kamal Perera", "Kamal", 1, 1},
+		{"Sinhala and English", "කමල් පෙරේරා", "Kamal Perera", 0.9, 1},
+		{"Tamil and English", "சிவகுமார்", "Shivakumar", 1, 1},
+		{"different people", "Kamal Perera", "Nimal Silva", 0, 0.7},
+		{"different initial", "K. Perera", "Nimal Perera", 0.4, 0.6},
+		{"empty", "", "Kamal", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameScore(tt.a, tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("NameScore(%q, %q) = %.3f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+			}
+			if rev := NameScore(tt.b, tt.a); rev != got {
+				t.Errorf("NameScore(%q, %q) = %.3f, but %.3f the other way round", tt.a, tt.b, got, rev)
+			}
+		})
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.840},
+		{"dixon", "dicksonx", 0.813},
+		{"kamal", "kamal", 1},
+		{"kamal", "", 0},
+		{"abc", "xyz", 0},
+	}
+
+	for _, tt := range tests {
+		if got := JaroWinkler(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNameKeyAcrossScripts(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		initials []string
+	}{
+		{"Kamal Perera", "kamal perera", []string{"k", "p"}},
+		{"කමල් පෙරේරා", "kamal perira", []string{"k", "p"}},
+		{"Wijesinghe", "vijesinge", []string{"v"}},
+		{"சிவகுமார்", "sivakumar", []string{"s"}},
+		{"Shivakumar", "sivakumar", []string{"s"}},
+		{"K. Perera", "k perera", []string{"k", "p"}},
+		{"", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := NameKey(tt.name); got != tt.key {
+			t.Errorf("NameKey(%q) = %q, want %q", tt.name, got, tt.key)
+		}
+		if got := Initials(tt.name); !reflect.DeepEqual(got, tt.initials) {
+			t.Errorf("Initials(%q) = %q, want %q", tt.name, got, tt.initials)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	t.Setenv("PHONE_COUNTRY_CODE", "")
+
+	tests := []struct {
+		name           string
+		report, person Person
+		above          bool
+	}{
+		{"same name and phone",
+			Person{Name: "Kamal Perera", Phone: "077 123 4567", Age: 34},
+			Person{Name: "Kamal Perera", Phone: "+94771234567", AgeBand: "18-59"}, true},
+		{"misspelt name, same phone",
+			Person{Name: "Kamal Pereira", Phone: "0771234567"},
+			Person{Name: "Kamil Perera", Phone: "0094 77 123 4567"}, true},
+		{"Sinhala name, no phone",
+			Person{Name: "කමල් පෙරේරා", Age: 70},
+			Person{Name: "Kamal Perera", AgeBand: "60+"}, true},
+		{"same phone, different person",
+			Person{Name: "Kamal Perera", Phone: "0771234567"},
+			Person{Name: "Nimal Silva", Phone: "0771234567"}, false},
+		{"same name, different phone and age",
+			Person{Name: "Kamal Perera", Phone: "0771234567", Age: 8},
+			Person{Name: "Kamal Perera", Phone: "0719876543", AgeBand: "60+"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := Score(tt.report, tt.person)
+			if (score >= Threshold) != tt.above {
+				t.Errorf("Score = %.3f (%v), want above threshold %v", score, reasons, tt.above)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package matching
+
+import "strings"
+
+// Sinhala and Tamil letters mapped to the Latin spelling most people use
+// when they write a name in English. Consonants carry an inherent "a" that is
+// replaced by a following vowel sign or dropped by the virama.
+
+var consonants = map[rune]string{
+	// Sinhala
+	'ක': "k", 'ඛ': "kh", 'ග': "g", 'ඝ': "gh", 'ඞ': "ng", 'ඟ': "ng",
+	'ච': "ch", 'ඡ': "chh", 'ජ': "j", 'ඣ': "jh", 'ඤ': "ny", 'ඥ': "gn", 'ඦ': "nj",
+	'ට': "t", 'ඨ': "th", 'ඩ': "d", 'ඪ': "dh", 'ණ': "n", 'ඬ': "nd",
+	'ත': "th", 'ථ': "th", 'ද': "d", 'ධ': "dh", 'න': "n", 'ඳ': "nd",
+	'ප': "p", 'ඵ': "ph", 'බ': "b", 'භ': "bh", 'ම': "m", 'ඹ': "mb",
+	'ය': "y", 'ර': "r", 'ල': "l", 'ව': "w", 'ශ': "sh", 'ෂ': "sh",
+	'ස': "s", 'හ': "h", 'ළ': "l", 'ෆ': "f",
+
+	// Tamil
+	'க': "k", 'ங': "ng", 'ச': "s", 'ஜ': "j", 'ஞ': "ny", 'ட': "d",
+	'ண': "n", 'த': "th", 'ந': "n", 'ன': "n", 'ப': "p", 'ம': "m",
+	'ய': "y", 'ர': "r", 'ற': "r", 'ல': "l", 'ள': "l", 'ழ': "zh",
+	'வ': "v", 'ஶ': "sh", 'ஷ': "sh", 'ஸ': "s", 'ஹ': "h",
+}
+
+var independentVowels = map[rune]string{
+	// Sinhala
+	'අ': "a", 'ආ': "aa", 'ඇ': "ae", 'ඈ': "aae", 'ඉ': "i", 'ඊ': "ii",
+	'උ': "u", 'ඌ': "uu", 'ඍ': "ri", 'එ': "e", 'ඒ': "ee", 'ඓ': "ai",
+	'ඔ': "o", 'ඕ': "oo", 'ඖ': "au",
+
+	// Tamil
+	'அ': "a", 'ஆ': "aa", 'இ': "i", 'ஈ': "ii", 'உ': "u", 'ஊ': "uu",
+	'எ': "e", 'ஏ': "ee", 'ஐ': "ai", 'ஒ': "o", 'ஓ': "oo", 'ஔ': "au",
+}
+
+var vowelSigns = map[rune]string{
+	// Sinhala
+	'ා': "aa", 'ැ': "ae", 'ෑ': "aae", 'ි': "i", 'ී': "ii", 'ු': "u",
+	'ූ': "uu", 'ෘ': "ru", 'ෙ': "e", 'ේ': "ee", 'ෛ': "ai", 'ො': "o",
+	'ෝ': "oo", 'ෞ': "au", 'ෟ': "lu",
+
+	// Tamil
+	'ா': "aa", 'ி': "i", 'ீ': "ii", 'ு': "u", 'ூ': "uu", 'ெ': "e",
+	'ே': "ee", 'ை': "ai", 'ொ': "o", 'ோ': "oo", 'ௌ': "au",
+}
+
+var otherSigns = map[rune]string{
+	'ං': "ng", 'ඃ': "h", // Sinhala anusvara and visarga
+	'ஃ': "h", // Tamil aytham
+}
+
+const (
+	sinhalaVirama = '\u0dca'
+	tamilVirama   = '\u0bcd'
+	zeroWidthJoin = '\u200d'
+)
+
+// Transliterate converts Sinhala and Tamil script to Latin letters.
+// Any other text is returned unchanged.
+func Transliterate(text string) string {
+	runes := []rune(text)
+
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if c, ok := consonants[r]; ok {
+			b.WriteString(c)
+
+			// Skip joiners so the vowel sign after a conjunct is still seen
+			next := i + 1
+			for next < len(runes) && runes[next] == zeroWidthJoin {
+				next++
+			}
+
+			if next < len(runes) {
+				if runes[next] == sinhalaVirama || runes[next] == tamilVirama {
+					i = next
+					continue
+				}
+				if v, ok := vowelSigns[runes[next]]; ok {
+					b.WriteString(v)
+					i = next
+					continue
+				}
+			}
+
+			b.WriteString("a")
+			continue
+		}
+
+		if v, ok := independentVowels[r]; ok {
+			b.WriteString(v)
+			continue
+		}
+		if s, ok := otherSigns[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		if r == zeroWidthJoin {
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
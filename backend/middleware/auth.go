@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"context"
+	"flood-relief-system/backend/apierror"
 	"net/http"
 	"os"
 	"strings"
@@ -22,14 +23,14 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		// Check if header exists
 		if authHeader == "" {
-			http.Error(w, `{"error":"Authorization header required"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
 		// Check if it starts with "Bearer "
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, `{"error":"Invalid authorization format. Use: Bearer <token>"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "Invalid authorization format. Use: Bearer <token>")
 			return
 		}
 
@@ -48,27 +49,27 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		// Check parsing errors
 		if err != nil {
-			http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		// Check if token is valid
 		if !token.Valid {
-			http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
 		// Extract claims (payload data)
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			http.Error(w, `{"error":"Invalid token claims"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "Invalid token claims")
 			return
 		}
 
 		// Get user ID from claims
 		userID, ok := claims["user_id"]
 		if !ok {
-			http.Error(w, `{"error":"User ID not found in token"}`, http.StatusUnauthorized)
+			apierror.Write(w, http.StatusUnauthorized, "User ID not found in token")
 			return
 		}
 
@@ -2,19 +2,68 @@ package middlewares
 
 import (
 	"net/http"
+	"os"
+	"strings"
 )
 
+// corsOrigins reads CORS_ORIGIN: "*" (the default) or a comma-separated
+// list of origins such as https://relief.example.org
+func corsOrigins() []string {
+	value := os.Getenv("CORS_ORIGIN")
+	if strings.TrimSpace(value) == "" {
+		return []string{"*"}
+	}
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// OriginListed reports whether CORS_ORIGIN names origin. The "*" wildcard
+// does not name anyone; CORSMiddleware treats it as any origin.
+func OriginListed(origin string) bool {
+	for _, allowed := range corsOrigins() {
+		if allowed != "*" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyOrigin reports whether CORS_ORIGIN lets every origin in with "*"
+func anyOrigin() bool {
+	for _, allowed := range corsOrigins() {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // CORSMiddleware handles Cross-Origin Resource Sharing
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from any origin (for development)
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		// Allow the origins in CORS_ORIGIN, or any origin when it is "*" (for development)
+		origin := r.Header.Get("Origin")
+		switch {
+		case OriginListed(origin):
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		case anyOrigin():
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
 
 		// Allow specific HTTP methods
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 
 		// Allow specific headers in requests
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+
+		// Let the frontend read the request ID to quote in bug reports
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		// Allow credentials (cookies, auth headers)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -1,7 +1,10 @@
 package middlewares
 
 import (
+	"bufio"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
@@ -18,6 +21,28 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes streamed output (Server-Sent Events) straight to the client
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over for WebSocket upgrades
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LoggerMiddleware logs all HTTP requests
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,12 +63,13 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 
 		// Log request details
 		log.Printf(
-			"[%s] %s %s - Status: %d - Duration: %v",
+			"[%s] %s %s - Status: %d - Duration: %v - Request: %s",
 			r.Method,           // HTTP method (GET, POST, etc.)
 			r.RequestURI,       // Requested URL path
 			r.RemoteAddr,       // Client IP address
 			wrapped.statusCode, // HTTP status code
 			duration,           // Time taken to process request
+			RequestID(r),       // ID echoed in X-Request-ID and error bodies
 		)
 	})
 }
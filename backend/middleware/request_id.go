@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flood-relief-system/backend/apierror"
+	"net/http"
+)
+
+// RequestIDKey holds the request ID in the request context
+const RequestIDKey contextKey = "requestID"
+
+// RequestIDMiddleware gives every request an ID, so an error a user reports
+// can be found in the logs. An X-Request-ID from a proxy in front is kept
+// when it looks sane; otherwise one is made up. The ID is echoed in the
+// X-Request-ID response header and in error bodies.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(apierror.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(apierror.RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestID is the ID of a request, or "" outside the middleware
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDKey).(string)
+	return id
+}
+
+// validRequestID accepts up to 64 letters, digits, dashes and underscores,
+// which keeps forged IDs out of log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -2,8 +2,14 @@ package migrations
 
 import (
 	"flood-relief-system/backend/config"
+	"flood-relief-system/backend/donors"
+	"flood-relief-system/backend/matching"
 	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/phone"
+	"flood-relief-system/backend/units"
 	"log"
+
+	"gorm.io/gorm"
 )
 
 // RunMigrations auto-migrates all database models
@@ -17,6 +23,253 @@ func RunMigrations() {
 	db.AutoMigrate(&models.ReliefSupply{})
 	db.AutoMigrate(&models.RescueOperation{})
 	db.AutoMigrate(&models.EmergencyContact{})
+	db.AutoMigrate(&models.Evacuee{})
+	db.AutoMigrate(&models.Shelter{})
+	db.AutoMigrate(&models.MissingPersonReport{})
+	db.AutoMigrate(&models.MissingPersonMatch{})
+	db.AutoMigrate(&models.StockMovement{})
+	db.AutoMigrate(&models.Warehouse{})
+	db.AutoMigrate(&models.TransferOrder{})
+	db.AutoMigrate(&models.TransferOrderLine{})
+	db.AutoMigrate(&models.SupplyAllocation{})
+	db.AutoMigrate(&models.Unit{})
+	db.AutoMigrate(&models.UnitConversion{})
+	db.AutoMigrate(&models.CategoryUnit{})
+	db.AutoMigrate(&models.Donor{})
+	db.AutoMigrate(&models.DonationReceipt{})
+	db.AutoMigrate(&models.DonationReceiptLine{})
+	db.AutoMigrate(&models.DocumentSequence{})
+	db.AutoMigrate(&models.KitTemplate{})
+	db.AutoMigrate(&models.KitComponent{})
+	db.AutoMigrate(&models.KitAssembly{})
+	db.AutoMigrate(&models.Household{})
+	db.AutoMigrate(&models.HouseholdMember{})
+	db.AutoMigrate(&models.DistributionEvent{})
+	db.AutoMigrate(&models.Distribution{})
+	db.AutoMigrate(&models.EntitlementRule{})
+	db.AutoMigrate(&models.ReorderThreshold{})
+	db.AutoMigrate(&models.OnCallShift{})
+	db.AutoMigrate(&models.EscalationChain{})
+	db.AutoMigrate(&models.EscalationStep{})
+	db.AutoMigrate(&models.Escalation{})
+	db.AutoMigrate(&models.SitRep{})
+	db.AutoMigrate(&models.SitRepTemplate{})
+	db.AutoMigrate(&models.ChangeEvent{})
+	db.AutoMigrate(&models.ChangeEventPrune{})
+	db.AutoMigrate(&models.WebhookSubscription{})
+	db.AutoMigrate(&models.WebhookDelivery{})
+
+	seedUnits(db)
+	normalizeSupplyUnits(db)
+	backfillOpeningBalances(db)
+	normalizePhones(db)
+	linkDonors(db)
+	backfillLabelCodes(db)
+	markPrunedEvents(db)
+	backfillNameKeys(db)
 
 	log.Println("✅ Migrations completed successfully")
 }
+
+// backfillOpeningBalances gives supplies created before the stock ledger an
+// opening receipt, so the ledger balance matches their quantity
+func backfillOpeningBalances(db *gorm.DB) {
+	var supplies []models.ReliefSupply
+	db.Where("quantity > 0 AND NOT EXISTS (SELECT 1 FROM stock_movements WHERE stock_movements.relief_supply_id = relief_supplies.id)").
+		Find(&supplies)
+
+	for _, supply := range supplies {
+		db.Create(&models.StockMovement{
+			ReliefSupplyID: supply.ID,
+			Type:           "receipt",
+			Quantity:       supply.Quantity,
+			Unit:           supply.Unit,
+			BalanceAfter:   supply.Quantity,
+			Actor:          "system",
+			Reason:         "Opening balance",
+		})
+	}
+
+	if len(supplies) > 0 {
+		log.Printf("📦 Recorded opening balances for %d relief supplies", len(supplies))
+	}
+}
+
+// seedUnits adds the default unit catalogue and category units that are
+// missing; units edited by administrators are left alone
+func seedUnits(db *gorm.DB) {
+	for _, unit := range units.Defaults {
+		db.Where(models.Unit{Code: unit.Code}).FirstOrCreate(&unit)
+	}
+	for _, categoryUnit := range units.DefaultCategoryUnits {
+		db.Where(models.CategoryUnit{Category: categoryUnit.Category}).FirstOrCreate(&categoryUnit)
+	}
+}
+
+// normalizeSupplyUnits rewrites free-text units such as "Kgs" on existing
+// supplies and their movements to catalogue codes. Units the catalogue does
+// not know are logged and kept as they are.
+func normalizeSupplyUnits(db *gorm.DB) {
+	catalog, err := units.Load(db)
+	if err != nil {
+		log.Println("❌ Failed to load unit catalogue:", err)
+		return
+	}
+
+	var stored []string
+	db.Model(&models.ReliefSupply{}).Distinct().Pluck("unit", &stored)
+
+	for _, raw := range stored {
+		unit, err := catalog.Normalize(raw)
+		if err != nil {
+			log.Printf("⚠️  Relief supplies use unit %q which is not in the unit catalogue", raw)
+			continue
+		}
+		if unit.Code == raw {
+			continue
+		}
+
+		db.Model(&models.ReliefSupply{}).Where("unit = ?", raw).Update("unit", unit.Code)
+		db.Model(&models.StockMovement{}).Where("unit = ?", raw).Update("unit", unit.Code)
+		log.Printf("📏 Normalized unit %q to %q", raw, unit.Code)
+	}
+}
+
+// linkDonors creates donor records for supplies that only carry a donor
+// name and phone, so repeat donors share one record
+func linkDonors(db *gorm.DB) {
+	var supplies []models.ReliefSupply
+	db.Where("donor_id IS NULL AND (donor_name <> '' OR donor_phone <> '')").Order("id").Find(&supplies)
+
+	for _, supply := range supplies {
+		donor, err := donors.FindOrCreate(db, supply.DonorName, supply.DonorPhone)
+		if err != nil {
+			log.Println("❌ Failed to link donor:", err)
+			return
+		}
+		db.Model(&supply).Update("donor_id", donor.ID)
+	}
+
+	if len(supplies) > 0 {
+		log.Printf("🤝 Linked %d relief supplies to donor records", len(supplies))
+	}
+}
+
+// backfillLabelCodes gives lots created before labels a label code
+func backfillLabelCodes(db *gorm.DB) {
+	var supplies []models.ReliefSupply
+	db.Where("label_code IS NULL OR label_code = ''").Find(&supplies)
+
+	for _, supply := range supplies {
+		code, err := models.NewLabelCode()
+		if err != nil {
+			log.Println("❌ Failed to generate label code:", err)
+			return
+		}
+		db.Model(&supply).Update("label_code", code)
+	}
+
+	if len(supplies) > 0 {
+		log.Printf("🏷️  Assigned label codes to %d relief supplies", len(supplies))
+	}
+}
+
+// markPrunedEvents records how far change events were pruned before prune
+// runs were recorded: up to just before the oldest event still kept
+func markPrunedEvents(db *gorm.DB) {
+	var runs int64
+	db.Model(&models.ChangeEventPrune{}).Count(&runs)
+	if runs > 0 {
+		return
+	}
+
+	var oldest uint
+	db.Model(&models.ChangeEvent{}).Select("COALESCE(MIN(id), 0)").Scan(&oldest)
+	if oldest > 1 {
+		db.Create(&models.ChangeEventPrune{PrunedThrough: oldest - 1})
+	}
+}
+
+// backfillNameKeys fills in the name keys of people stored before missing
+// person matching looked them up by key
+func backfillNameKeys(db *gorm.DB) {
+	for _, table := range []string{"evacuees", "help_requests", "missing_person_reports"} {
+		var rows []struct {
+			ID   uint
+			Name string
+		}
+		db.Table(table).Select("id, name").Where("name_key IS NULL").Find(&rows)
+
+		for _, row := range rows {
+			db.Table(table).Where("id = ?", row.ID).Update("name_key", matching.NameKey(row.Name))
+		}
+
+		if len(rows) > 0 {
+			log.Printf("🔤 Filled in name keys for %d rows in %s", len(rows), table)
+		}
+	}
+}
+
+// phoneColumns are the phone numbers normalized by normalizePhones. An
+// empty e164 column means the table keeps only the display form.
+var phoneColumns = []struct {
+	table, column, e164 string
+	shortCodes          bool
+}{
+	{"help_requests", "phone", "phone_e164", false},
+	{"volunteers", "phone", "phone_e164", false},
+	{"emergency_contacts", "phone", "phone_e164", true},
+	{"relief_supplies", "donor_phone", "donor_phone_e164", false},
+	{"donors", "phone", "phone_e164", false},
+	{"evacuees", "phone", "phone_e164", false},
+	{"households", "phone", "phone_e164", false},
+	{"missing_person_reports", "phone", "phone_e164", false},
+	{"missing_person_reports", "reporter_phone", "reporter_phone_e164", false},
+	{"shelters", "manager_phone", "manager_phone_e164", false},
+	{"warehouses", "manager_phone", "manager_phone_e164", false},
+}
+
+// normalizePhones rewrites phone numbers stored before validation in the
+// display form and fills in their E.164 form. Numbers that cannot be
+// parsed are logged and left as they are for someone to correct.
+func normalizePhones(db *gorm.DB) {
+	for _, c := range phoneColumns {
+		var rows []struct {
+			ID    uint
+			Phone string
+		}
+		query := db.Table(c.table).Select("id, " + c.column + " AS phone").Where(c.column + " <> ''")
+		if c.e164 != "" {
+			query = query.Where("(" + c.e164 + " IS NULL OR " + c.e164 + " = '')")
+		}
+		query.Find(&rows)
+
+		fixed, invalid := 0, 0
+		for _, row := range rows {
+			parse := phone.Parse
+			if c.shortCodes {
+				parse = phone.ParseService
+			}
+			number, err := parse(row.Phone)
+			if err != nil {
+				log.Printf("⚠️  %s #%d has an invalid phone number %q", c.table, row.ID, row.Phone)
+				invalid++
+				continue
+			}
+			if number.Display == row.Phone && (c.e164 == "" || number.E164 == "") {
+				continue
+			}
+
+			updates := map[string]interface{}{c.column: number.Display}
+			if c.e164 != "" {
+				updates[c.e164] = number.E164
+			}
+			db.Table(c.table).Where("id = ?", row.ID).Updates(updates)
+			fixed++
+		}
+
+		if fixed > 0 || invalid > 0 {
+			log.Printf("📞 Normalized %d phone numbers in %s (%d invalid)", fixed, c.table, invalid)
+		}
+	}
+}
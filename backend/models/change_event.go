@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ChangeEvent records that a help request, rescue operation, volunteer,
+// relief supply or emergency contact was created, updated or deleted. The
+// ID orders events and is what stream clients resume from; Data is the
+// record as JSON after the change (before it, for deletes).
+type ChangeEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Entity    string    `gorm:"size:30;not null;index" json:"entity"` // help_request, rescue_operation, volunteer, relief_supply, emergency_contact
+	Action    string    `gorm:"size:10;not null" json:"action"`       // created, updated, deleted
+	EntityID  uint      `gorm:"not null" json:"entity_id"`
+	Priority  string    `gorm:"size:20" json:"priority,omitempty"`
+	Area      string    `gorm:"size:255" json:"area,omitempty"` // location, or district for contacts
+	Data      string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// ChangeEventPrune records a run of the prune job. PrunedThrough is the
+// highest event ID it deleted: IDs have gaps, so the oldest event left does
+// not say whether the one after a client's last ID was pruned.
+type ChangeEventPrune struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	PrunedThrough uint      `gorm:"not null" json:"pruned_through"`
+	CreatedAt     time.Time `json:"created_at"`
+}
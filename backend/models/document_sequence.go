@@ -0,0 +1,8 @@
+package models
+
+// DocumentSequence hands out gap-free numbers for printed documents such as
+// donation receipts. Name identifies the series, e.g. donation-receipt-2026.
+type DocumentSequence struct {
+	Name string `gorm:"primaryKey;size:50" json:"name"`
+	Last int    `gorm:"not null;default:0" json:"last"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Donor is a person or organization that gives relief supplies
+type Donor struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Name              string    `gorm:"size:100;not null" json:"name" validate:"required"`
+	Type              string    `gorm:"size:20;not null;default:'individual'" json:"type" validate:"oneof=individual organization"`
+	ContactPerson     string    `gorm:"size:100" json:"contact_person"`
+	Phone             string    `gorm:"size:20;index" json:"phone"`      // display form, e.g. 077 123 4567
+	PhoneE164         string    `gorm:"size:16;index" json:"phone_e164"` // +94771234567, for lookups
+	Email             string    `gorm:"size:100" json:"email"`
+	Address           string    `gorm:"size:255" json:"address"`
+	PreferredLanguage string    `gorm:"size:2;default:'en'" json:"preferred_language" validate:"oneof=en si ta"`
+	Notes             string    `gorm:"type:text" json:"notes"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// DonationReceipt acknowledges one or more donated supply lots. Numbers are
+// sequential per year, e.g. DR-2026-000042.
+type DonationReceipt struct {
+	ID        uint                  `gorm:"primaryKey" json:"id"`
+	Number    string                `gorm:"size:30;uniqueIndex;not null" json:"number"`
+	DonorID   uint                  `gorm:"not null;index" json:"donor_id"`
+	Language  string                `gorm:"size:2;not null" json:"language" validate:"oneof=en si ta"`
+	IssuedBy  string                `gorm:"size:100" json:"issued_by"`
+	Lines     []DonationReceiptLine `gorm:"foreignKey:ReceiptID" json:"lines,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+// DonationReceiptLine is a donated lot as it was when the receipt was issued.
+// A lot is acknowledged on one receipt only.
+type DonationReceiptLine struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ReceiptID      uint      `gorm:"not null;index" json:"receipt_id"`
+	ReliefSupplyID uint      `gorm:"not null;uniqueIndex" json:"relief_supply_id"`
+	ItemName       string    `gorm:"size:100;not null" json:"item_name"`
+	Quantity       int       `gorm:"not null" json:"quantity"`
+	Unit           string    `gorm:"size:20;not null" json:"unit"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
@@ -3,15 +3,23 @@ package models
 import "time"
 
 type EmergencyContact struct {
-	ID               uint      `gorm:"primaryKey" json:"id"`
-	OrganizationName string    `gorm:"size:100;not null" json:"organization_name"` //Police, Hospital, Fire Dept
-	ContactPerson    string    `gorm:"size:100" json:"contact_person"`
-	Phone            string    `gorm:"size:15;not null" json:"phone"`
-	AlternatePhone   string    `gorm:"size:100" json:"alternate_phone"`
-	Email            string    `gorm:"size:100" json:"email"`
-	Address          string    `gorm:"size:255" json:"address"`
-	ServiceType      string    `gorm:"size:50" json:"service_type"` // Medical, Rescue, Food, Shelter
-	IsActive         bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	OrganizationName   string     `gorm:"size:100;not null" json:"organization_name" validate:"required"` //Police, Hospital, Fire Dept
+	ContactPerson      string     `gorm:"size:100" json:"contact_person"`
+	Phone              string     `gorm:"size:20;not null" json:"phone" validate:"required"` // display form; hotlines may be short codes such as 1990
+	PhoneE164          string     `gorm:"size:16;index" json:"phone_e164"`                   // empty for short codes
+	AlternatePhone     string     `gorm:"size:100" json:"alternate_phone"`
+	Email              string     `gorm:"size:100" json:"email"`
+	Address            string     `gorm:"size:255" json:"address"`
+	ServiceType        string     `gorm:"size:50" json:"service_type" validate:"oneof=Medical Rescue Food Shelter Police Fire Other"`
+	District           string     `gorm:"size:50;index" json:"district"`
+	IsActive           bool       `gorm:"default:true" json:"is_active"`
+	VerificationStatus string     `gorm:"size:20;default:'unverified';index" json:"verification_status" validate:"oneof=unverified verified due unreachable"`
+	LastVerifiedAt     *time.Time `json:"last_verified_at,omitempty"` // someone reached the service on this number
+	VerifiedBy         string     `gorm:"size:100" json:"verified_by,omitempty"`
+	VerificationMethod string     `gorm:"size:20" json:"verification_method,omitempty" validate:"oneof=call sms email in-person website"`
+	UnreachableAt      *time.Time `json:"unreachable_at,omitempty"`
+	VerificationNotes  string     `gorm:"type:text" json:"verification_notes,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
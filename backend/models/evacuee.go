@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"flood-relief-system/backend/matching"
+
+	"gorm.io/gorm"
+)
+
+// Evacuee is a person brought out by a rescue operation.
+// Every evacuee belongs to the RescueOperation that rescued them.
+type Evacuee struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	RescueOperationID uint       `gorm:"not null;index" json:"rescue_operation_id"`
+	Name              string     `gorm:"size:100;not null;index" json:"name" validate:"required"`
+	NameKey           string     `gorm:"size:255;index" json:"-"` // matching.NameKey, for lookups
+	AgeBand           string     `gorm:"size:10" json:"age_band" validate:"oneof=0-4 5-17 18-59 60+"`
+	Sex               string     `gorm:"size:10" json:"sex" validate:"oneof=male female other"`
+	IDNumber          string     `gorm:"size:20" json:"id_number"`
+	Phone             string     `gorm:"size:20" json:"phone"`            // display form, e.g. 077 123 4567
+	PhoneE164         string     `gorm:"size:16;index" json:"phone_e164"` // +94771234567, for lookups
+	MedicalCondition  string     `gorm:"type:text" json:"medical_condition"`
+	DestinationType   string     `gorm:"size:20" json:"destination_type" validate:"oneof=shelter hospital"`
+	DestinationName   string     `gorm:"size:255" json:"destination_name"`
+	ShelterID         *uint      `gorm:"index" json:"shelter_id,omitempty"`
+	CheckedInAt       *time.Time `json:"checked_in_at,omitempty"`
+	CheckedOutAt      *time.Time `json:"checked_out_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// BeforeSave keeps the name key in step with the name
+func (e *Evacuee) BeforeSave(tx *gorm.DB) error {
+	e.NameKey = matching.NameKey(e.Name)
+	return nil
+}
@@ -3,16 +3,28 @@ package models
 import ( /*Time model uses timestamps.
 	  Without it, Go cannot understand time.Time.*/
 	"time"
+
+	"flood-relief-system/backend/matching"
+
+	"gorm.io/gorm"
 )
 
 type HelpRequest struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"size:100;not null" json:"name"`
-	Phone       string    `gorm:"size:15;not null" json:"phone"`
-	Location    string    `gorm:"size:255;not null" json:"location"`
+	Name        string    `gorm:"size:100;not null" json:"name" validate:"required"`
+	NameKey     string    `gorm:"size:255;index" json:"-"`                           // matching.NameKey, for lookups
+	Phone       string    `gorm:"size:20;not null" json:"phone" validate:"required"` // display form, e.g. 077 123 4567
+	PhoneE164   string    `gorm:"size:16;index" json:"phone_e164"`                   // +94771234567, for lookups
+	Location    string    `gorm:"size:255;not null" json:"location" validate:"required"`
 	Description string    `gorm:"type:text" json:"description"`
-	Priority    string    `json:"priority"`
-	Status      string    `gorm:"size:20;default:'pending'" json:"status"` // pending, in-progress, completed
-	CreatedAt   time.Time `json:"created_at"`
+	Priority    string    `gorm:"index" json:"priority" validate:"oneof=low medium high critical"`
+	Status      string    `gorm:"size:20;default:'pending';index" json:"status" validate:"oneof=pending in-progress completed"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// BeforeSave keeps the name key in step with the name
+func (h *HelpRequest) BeforeSave(tx *gorm.DB) error {
+	h.NameKey = matching.NameKey(h.Name)
+	return nil
+}
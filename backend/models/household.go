@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Household is an affected family registered to receive relief.
+// The head of household is not repeated in Members.
+type Household struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	HeadName     string            `gorm:"size:100;not null;index" json:"head_name" validate:"required"`
+	HeadIDNumber string            `gorm:"size:20;index" json:"head_id_number"`
+	Phone        string            `gorm:"size:20" json:"phone"`            // display form, e.g. 077 123 4567
+	PhoneE164    string            `gorm:"size:16;index" json:"phone_e164"` // +94771234567, for lookups
+	Address      string            `gorm:"size:255;not null" json:"address" validate:"required"`
+	District     string            `gorm:"size:50;index" json:"district"`
+	Notes        string            `gorm:"type:text" json:"notes"`
+	Members      []HouseholdMember `gorm:"foreignKey:HouseholdID" json:"members" validate:"dive"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Size is the number of people in the household, head included
+func (h *Household) Size() int {
+	return len(h.Members) + 1
+}
+
+// HouseholdMember is a person living in a household other than its head
+type HouseholdMember struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	HouseholdID  uint   `gorm:"not null;index" json:"household_id"`
+	Name         string `gorm:"size:100;not null" json:"name" validate:"required"`
+	AgeBand      string `gorm:"size:10" json:"age_band" validate:"oneof=0-4 5-17 18-59 60+"`
+	Sex          string `gorm:"size:10" json:"sex" validate:"oneof=male female other"`
+	IDNumber     string `gorm:"size:20;index" json:"id_number"`
+	Relationship string `gorm:"size:30" json:"relationship"`
+}
+
+// DistributionEvent is a handout of relief items at a place and time.
+// Events tied to a warehouse issue the items from its stock.
+type DistributionEvent struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:100;not null" json:"name" validate:"required"`
+	Location    string     `gorm:"size:255;not null" json:"location" validate:"required"`
+	WarehouseID *uint      `gorm:"index" json:"warehouse_id,omitempty"`
+	EventDate   time.Time  `json:"event_date"`
+	Status      string     `gorm:"size:20;default:'open'" json:"status" validate:"oneof=open closed"`
+	CreatedBy   string     `gorm:"size:100" json:"created_by"`
+	Notes       string     `gorm:"type:text" json:"notes"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Distribution is one item handed to a household at an event
+type Distribution struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	DistributionEventID uint      `gorm:"not null;index" json:"distribution_event_id"`
+	HouseholdID         uint      `gorm:"not null;index" json:"household_id"`
+	ItemName            string    `gorm:"size:100;not null" json:"item_name"`
+	Quantity            int       `gorm:"not null" json:"quantity"`
+	Unit                string    `gorm:"size:20;not null" json:"unit"`
+	Warning             string    `gorm:"size:255" json:"warning,omitempty"` // entitlement exceeded under a warn rule
+	Actor               string    `gorm:"size:100" json:"actor"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// EntitlementRule limits how much of an item a household may collect in a
+// rolling period, e.g. one dry-ration kit per household per 7 days. With
+// PerMember the limit is multiplied by the household size. Mode "block"
+// refuses over-collection, "warn" records it with a warning.
+type EntitlementRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ItemName   string    `gorm:"size:100;not null;index" json:"item_name" validate:"required"`
+	Unit       string    `gorm:"size:20;not null" json:"unit"`
+	Quantity   int       `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	PeriodDays int       `gorm:"not null" json:"period_days" validate:"required,min=1"`
+	PerMember  bool      `gorm:"default:false" json:"per_member"`
+	Mode       string    `gorm:"size:10;not null;default:'block'" json:"mode" validate:"oneof=block warn"`
+	IsActive   bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// KitTemplate is a standard relief kit (dry rations, hygiene kit, baby kit)
+// defined as a bill of materials over relief supply items
+type KitTemplate struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"size:100;uniqueIndex;not null" json:"name" validate:"required"` // item name of assembled kits
+	Category    string         `gorm:"size:50;not null" json:"category" validate:"required,oneof=Food Medical Clothing Shelter Other"`
+	Description string         `gorm:"type:text" json:"description"`
+	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	Components  []KitComponent `gorm:"foreignKey:KitTemplateID" json:"components" validate:"required,dive"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// KitComponent is how much of one item goes into a single kit
+type KitComponent struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	KitTemplateID uint   `gorm:"not null;index" json:"kit_template_id"`
+	ItemName      string `gorm:"size:100;not null" json:"item_name" validate:"required"`
+	Quantity      int    `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	Unit          string `gorm:"size:20;not null" json:"unit" validate:"required"`
+}
+
+// KitAssembly records kits built in a warehouse and the kit lot they went into
+type KitAssembly struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	KitTemplateID  uint      `gorm:"not null;index" json:"kit_template_id"`
+	WarehouseID    uint      `gorm:"not null;index" json:"warehouse_id"`
+	Quantity       int       `gorm:"not null" json:"quantity"`
+	ReliefSupplyID uint      `gorm:"not null" json:"relief_supply_id"`
+	Actor          string    `gorm:"size:100" json:"actor"`
+	Notes          string    `gorm:"type:text" json:"notes"`
+	CreatedAt      time.Time `json:"created_at"`
+}
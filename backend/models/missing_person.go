@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"flood-relief-system/backend/matching"
+
+	"gorm.io/gorm"
+)
+
+// MissingPersonReport is a relative's report that someone is missing
+type MissingPersonReport struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Name              string     `gorm:"size:100;not null" json:"name" validate:"required"`
+	NameKey           string     `gorm:"size:255;index" json:"-"` // matching.NameKey, for lookups
+	Age               int        `json:"age" validate:"min=0,max=150"`
+	Sex               string     `gorm:"size:10" json:"sex" validate:"oneof=male female other"`
+	Phone             string     `gorm:"size:20" json:"phone"`
+	PhoneE164         string     `gorm:"size:16;index" json:"phone_e164"`
+	LastSeenLocation  string     `gorm:"size:255" json:"last_seen_location"`
+	LastSeenAt        *time.Time `json:"last_seen_at,omitempty"`
+	Description       string     `gorm:"type:text" json:"description"`
+	ReporterName      string     `gorm:"size:100;not null" json:"reporter_name" validate:"required"`
+	ReporterPhone     string     `gorm:"size:20;not null" json:"reporter_phone" validate:"required"`
+	ReporterPhoneE164 string     `gorm:"size:16" json:"reporter_phone_e164"`
+	Status            string     `gorm:"size:20;default:'missing'" json:"status" validate:"oneof=missing found closed"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// BeforeSave keeps the name key in step with the name
+func (r *MissingPersonReport) BeforeSave(tx *gorm.DB) error {
+	r.NameKey = matching.NameKey(r.Name)
+	return nil
+}
+
+// MissingPersonMatch is a candidate match waiting in the review queue
+type MissingPersonMatch struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ReportID      uint       `gorm:"not null;index" json:"report_id"`
+	CandidateType string     `gorm:"size:20;not null" json:"candidate_type" validate:"oneof=evacuee help_request"`
+	CandidateID   uint       `gorm:"not null" json:"candidate_id"`
+	CandidateName string     `gorm:"size:100" json:"candidate_name"`
+	Score         float64    `json:"score"`
+	Reasons       string     `gorm:"size:255" json:"reasons"`
+	Status        string     `gorm:"size:20;default:'pending'" json:"status" validate:"oneof=pending confirmed rejected"`
+	ReviewedBy    string     `gorm:"size:100" json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	NotifiedAt    *time.Time `json:"notified_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// OnCallShift puts an emergency contact on call for a service between two
+// times. Shifts without a district cover every district.
+type OnCallShift struct {
+	ID                 uint             `gorm:"primaryKey" json:"id"`
+	EmergencyContactID uint             `gorm:"not null;index" json:"emergency_contact_id" validate:"required"`
+	Contact            EmergencyContact `gorm:"foreignKey:EmergencyContactID" json:"contact"`
+	ServiceType        string           `gorm:"size:50;not null;index" json:"service_type" validate:"oneof=Medical Rescue Food Shelter Police Fire Other"`
+	OrganizationName   string           `gorm:"size:100" json:"organization_name"`
+	District           string           `gorm:"size:50;index" json:"district"`
+	StartsAt           time.Time        `gorm:"not null;index" json:"starts_at" validate:"required"`
+	EndsAt             time.Time        `gorm:"not null;index" json:"ends_at"`
+	Notes              string           `gorm:"type:text" json:"notes"`
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+}
+
+// EscalationChain is the ordered list of contacts to try for a service.
+// Each step gets AckTimeoutMinutes to acknowledge before the next is called.
+type EscalationChain struct {
+	ID                uint             `gorm:"primaryKey" json:"id"`
+	Name              string           `gorm:"size:100;not null" json:"name" validate:"required"`
+	ServiceType       string           `gorm:"size:50;not null;index" json:"service_type" validate:"required,oneof=Medical Rescue Food Shelter Police Fire Other"`
+	OrganizationName  string           `gorm:"size:100" json:"organization_name"`
+	District          string           `gorm:"size:50;index" json:"district"`
+	AckTimeoutMinutes int              `gorm:"not null;default:10" json:"ack_timeout_minutes" validate:"min=0"`
+	IsActive          bool             `gorm:"default:true" json:"is_active"`
+	Steps             []EscalationStep `gorm:"foreignKey:EscalationChainID" json:"steps" validate:"required,dive"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+}
+
+// EscalationStep is one contact in a chain, called in Position order
+type EscalationStep struct {
+	ID                 uint             `gorm:"primaryKey" json:"id"`
+	EscalationChainID  uint             `gorm:"not null;index" json:"escalation_chain_id"`
+	Position           int              `gorm:"not null" json:"position"`
+	EmergencyContactID uint             `gorm:"not null" json:"emergency_contact_id" validate:"required"`
+	Contact            EmergencyContact `gorm:"foreignKey:EmergencyContactID" json:"contact"`
+}
+
+// Escalation is one call-out working down a chain until someone
+// acknowledges it or the chain runs out
+type Escalation struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	EscalationChainID  uint       `gorm:"not null;index" json:"escalation_chain_id"`
+	Message            string     `gorm:"type:text;not null" json:"message"`
+	Status             string     `gorm:"size:20;default:'open';index" json:"status" validate:"oneof=open acknowledged exhausted cancelled"`
+	Position           int        `json:"position"`                       // step currently being called
+	EmergencyContactID *uint      `json:"emergency_contact_id,omitempty"` // contact currently being called
+	NotifiedAt         *time.Time `json:"notified_at,omitempty"`
+	RaisedBy           string     `gorm:"size:100" json:"raised_by"`
+	AcknowledgedBy     string     `gorm:"size:100" json:"acknowledged_by,omitempty"`
+	AcknowledgedAt     *time.Time `json:"acknowledged_at,omitempty"`
+	ClosedAt           *time.Time `json:"closed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
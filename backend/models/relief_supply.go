@@ -1,21 +1,53 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/base32"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type ReliefSupply struct {
-	ID         uint       `gorm:"primaryKey" json:"id"`
-	ItemName   string     `gorm:"size:100;not null" json:"item_name"`
-	Category   string     `gorm:"size:50;not null" json:"category"`
-	Quantity   int        `gorm:"not null" json:"quantity"`
-	Unit       string     `gorm:"size:20;not null" json:"unit"`
-	DonorName  string     `gorm:"size:100" json:"donor_name"`
-	DonorPhone string     `gorm:"size:15" json:"donor_phone"`
-	Location   string     `gorm:"size:255;not null" json:"location"`
-	Status     string     `gorm:"size:20;default:'Available'" json:"status"` // ✅ CHANGED FROM 'available' to 'Available'
-	ExpiryDate *time.Time `json:"expiry_date,omitempty"`
-	Notes      string     `gorm:"type:text" json:"notes"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ItemName       string     `gorm:"size:100;not null" json:"item_name" validate:"required"`
+	Category       string     `gorm:"size:50;not null" json:"category" validate:"required,oneof=Food Medical Clothing Shelter Other"`
+	Quantity       int        `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	Unit           string     `gorm:"size:20;not null" json:"unit" validate:"required"`
+	DonorID        *uint      `gorm:"index" json:"donor_id,omitempty"`
+	DonorName      string     `gorm:"size:100" json:"donor_name"`
+	DonorPhone     string     `gorm:"size:20" json:"donor_phone"`
+	DonorPhoneE164 string     `gorm:"size:16;index" json:"donor_phone_e164,omitempty"`
+	Location       string     `gorm:"size:255;not null" json:"location" validate:"required"`
+	WarehouseID    *uint      `gorm:"index" json:"warehouse_id,omitempty"`
+	LabelCode      string     `gorm:"size:20;uniqueIndex" json:"label_code"`                                                    // printed on the lot's QR or barcode label
+	Status         string     `gorm:"size:20;default:'Available'" json:"status" validate:"oneof=Available Expired Distributed"` // ✅ CHANGED FROM 'available' to 'Available'
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty"`
+	Notes          string     `gorm:"type:text" json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// NewLabelCode returns a random lot label code such as "RS-K3QZ7XDA".
+// Codes are random rather than the ID so a label cannot be guessed from
+// its neighbours.
+func NewLabelCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "RS-" + base32.StdEncoding.EncodeToString(b), nil
+}
+
+// BeforeCreate gives every new lot a label code
+func (s *ReliefSupply) BeforeCreate(tx *gorm.DB) error {
+	if s.LabelCode != "" {
+		return nil
+	}
+	code, err := NewLabelCode()
+	if err != nil {
+		return err
+	}
+	s.LabelCode = code
+	return nil
 }
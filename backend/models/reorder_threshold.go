@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ReorderThreshold is the stock level of an item at which a warehouse, or
+// the whole network when WarehouseID is empty, should reorder. DailyPerPerson
+// is how much one person uses a day (e.g. 3 L of drinking water); it turns
+// the people being served into expected demand.
+type ReorderThreshold struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ItemName       string     `gorm:"size:100;not null;index" json:"item_name" validate:"required"`
+	Category       string     `gorm:"size:50;not null" json:"category" validate:"oneof=Food Medical Clothing Shelter Other"`
+	Unit           string     `gorm:"size:20;not null" json:"unit"`
+	WarehouseID    *uint      `gorm:"index" json:"warehouse_id,omitempty"`
+	ReorderPoint   int        `gorm:"not null" json:"reorder_point" validate:"min=0"`
+	TargetQuantity int        `gorm:"not null" json:"target_quantity"` // reorder up to this level
+	DailyPerPerson float64    `gorm:"default:0" json:"daily_per_person" validate:"min=0"`
+	AlertedAt      *time.Time `json:"alerted_at,omitempty"` // set while below the reorder point
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
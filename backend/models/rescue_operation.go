@@ -5,17 +5,17 @@ import "time"
 type RescueOperation struct {
 	ID uint `gorm:"primaryKey" json:"id"`
 
-	OperationName string `gorm:"size:255;not null" json:"operation_name"`
+	OperationName string `gorm:"size:255;not null" json:"operation_name" validate:"required"`
 
 	HelpRequestID uint       `json:"help_request_id"`
 	VolunteerID   uint       `json:"volunteer_id"`
-	TeamSize      int        `gorm:"not null" json:"team_size"`
+	TeamSize      int        `gorm:"not null" json:"team_size" validate:"required,min=1"`
 	VehicleType   string     `gorm:"size:50" json:"vehicle_type"`
-	Location      string     `gorm:"size:255" json:"location"`
-	StartTime     time.Time  `gorm:"not null" json:"start_time"`
-	EndTime       *time.Time `json:"end_time,omitempty"`
-	Status        string     `gorm:"size:20;default:'initiated'" json:"status"`
-	Priority      string     `gorm:"size:20;default:'medium'" json:"priority"`
+	Location      string     `gorm:"size:255" json:"location" validate:"required"`
+	StartTime     time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime       *time.Time `gorm:"index" json:"end_time,omitempty"`
+	Status        string     `gorm:"size:20;default:'initiated';index" json:"status" validate:"oneof=initiated in-progress completed failed"`
+	Priority      string     `gorm:"size:20;default:'medium'" json:"priority" validate:"oneof=low medium high critical"`
 	PeopleRescued int        `gorm:"default:0" json:"people_rescued"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Shelter is an evacuation centre that evacuees are checked in to
+type Shelter struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:100;not null" json:"name" validate:"required"`
+	Address          string    `gorm:"size:255;not null" json:"address" validate:"required"`
+	District         string    `gorm:"size:50" json:"district"`
+	Latitude         float64   `json:"latitude"`
+	Longitude        float64   `json:"longitude"`
+	Capacity         int       `gorm:"not null" json:"capacity" validate:"required,min=1"`
+	CurrentOccupancy int       `gorm:"default:0" json:"current_occupancy"`
+	HasWater         bool      `json:"has_water"`
+	HasToilets       bool      `json:"has_toilets"`
+	HasPower         bool      `json:"has_power"`
+	HasMedical       bool      `json:"has_medical"`
+	ManagerName      string    `gorm:"size:100" json:"manager_name"`
+	ManagerPhone     string    `gorm:"size:20" json:"manager_phone"`
+	ManagerPhoneE164 string    `gorm:"size:16" json:"manager_phone_e164"`
+	Status           string    `gorm:"size:20;default:'open'" json:"status" validate:"oneof=open closed"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
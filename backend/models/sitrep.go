@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// SitRep is an archived situation report. Data is the report as JSON and
+// Markdown and HTML are what the templates rendered at the time, so an
+// archived SitRep reads the same after the templates are edited.
+type SitRep struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PeriodStart time.Time `gorm:"not null;index" json:"period_start"`
+	PeriodEnd   time.Time `gorm:"not null" json:"period_end"`
+	Data        string    `gorm:"type:text;not null" json:"-"`
+	Markdown    string    `gorm:"type:text" json:"-"`
+	HTML        string    `gorm:"type:text" json:"-"`
+	GeneratedBy string    `gorm:"size:100" json:"generated_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SitRepTemplate is an edited SitRep template. A format without one uses
+// the built-in template.
+type SitRepTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Format    string    `gorm:"size:10;not null;uniqueIndex" json:"format" validate:"oneof=md html"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	UpdatedBy string    `gorm:"size:100" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
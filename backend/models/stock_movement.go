@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// StockMovement is one entry in the relief supply ledger.
+// Quantity is signed: receipts add stock, issues remove it.
+type StockMovement struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ReliefSupplyID uint      `gorm:"not null;index" json:"relief_supply_id"`
+	Type           string    `gorm:"size:20;not null" json:"type" validate:"oneof=receipt issue adjustment transfer write-off expiry"`
+	Quantity       int       `gorm:"not null" json:"quantity"`
+	Unit           string    `gorm:"size:20;not null" json:"unit"`
+	BalanceAfter   int       `gorm:"not null" json:"balance_after"`
+	Actor          string    `gorm:"size:100;not null" json:"actor"`        // authenticated user, or "unauthenticated"
+	ReportedBy     string    `gorm:"size:100" json:"reported_by,omitempty"` // name given by the client, unverified
+	Reason         string    `gorm:"size:255" json:"reason"`
+	Reference      string    `gorm:"size:100" json:"reference"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
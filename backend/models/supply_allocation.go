@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SupplyAllocation reserves relief supply stock for a help request or a
+// rescue operation and tracks it through picking and delivery
+type SupplyAllocation struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	ReliefSupplyID    uint      `gorm:"not null;index" json:"relief_supply_id" validate:"required"`
+	HelpRequestID     *uint     `gorm:"index" json:"help_request_id,omitempty"`
+	RescueOperationID *uint     `gorm:"index" json:"rescue_operation_id,omitempty"`
+	Quantity          int       `gorm:"not null" json:"quantity" validate:"required,min=1"` // reserved
+	PickedQuantity    int       `gorm:"default:0" json:"picked_quantity"`
+	DeliveredQuantity int       `gorm:"default:0" json:"delivered_quantity"`
+	Status            string    `gorm:"size:20;default:'reserved'" json:"status" validate:"oneof=reserved picked partially-delivered delivered cancelled"`
+	Actor             string    `gorm:"size:100" json:"actor"`
+	Notes             string    `gorm:"type:text" json:"notes"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
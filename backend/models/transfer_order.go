@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// TransferOrder moves relief supplies from one warehouse to another
+type TransferOrder struct {
+	ID              uint                `gorm:"primaryKey" json:"id"`
+	FromWarehouseID uint                `gorm:"not null;index" json:"from_warehouse_id" validate:"required"`
+	ToWarehouseID   uint                `gorm:"not null;index" json:"to_warehouse_id" validate:"required"`
+	Status          string              `gorm:"size:20;default:'draft'" json:"status" validate:"oneof=draft dispatched in-transit received cancelled"`
+	RequestedBy     string              `gorm:"size:100" json:"requested_by"`
+	VehicleNumber   string              `gorm:"size:20" json:"vehicle_number"`
+	Notes           string              `gorm:"type:text" json:"notes"`
+	DispatchedAt    *time.Time          `json:"dispatched_at,omitempty"`
+	InTransitAt     *time.Time          `json:"in_transit_at,omitempty"`
+	ReceivedAt      *time.Time          `json:"received_at,omitempty"`
+	Lines           []TransferOrderLine `gorm:"foreignKey:TransferOrderID" json:"lines" validate:"required,dive"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TransferOrderLine is one source lot and quantity on a transfer order
+type TransferOrderLine struct {
+	ID                  uint  `gorm:"primaryKey" json:"id"`
+	TransferOrderID     uint  `gorm:"not null;index" json:"transfer_order_id"`
+	ReliefSupplyID      uint  `gorm:"not null" json:"relief_supply_id" validate:"required"` // source lot
+	Quantity            int   `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	ReceivedQuantity    int   `gorm:"default:0" json:"received_quantity"`
+	DestinationSupplyID *uint `json:"destination_supply_id,omitempty"` // lot created or topped up on receipt
+}
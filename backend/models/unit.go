@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Unit is an entry in the managed unit-of-measure catalogue.
+// Units of the same dimension convert through ToBase (kg, L and piece are
+// the base units); package units such as bottle or packet only convert
+// through item-specific conversions.
+type Unit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"size:20;uniqueIndex;not null" json:"code" validate:"required"` // canonical spelling, e.g. kg
+	Name      string    `gorm:"size:50;not null" json:"name" validate:"required"`
+	Dimension string    `gorm:"size:20;not null" json:"dimension" validate:"required,oneof=mass volume count package"`
+	ToBase    float64   `gorm:"not null;default:1" json:"to_base"`
+	Aliases   string    `gorm:"size:255" json:"aliases"` // comma separated, e.g. Kg,kgs,kilogram
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UnitConversion is an item-specific conversion, e.g. 1 bottle of
+// "Drinking Water" = 1.5 L
+type UnitConversion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ItemName  string    `gorm:"size:100;not null;index" json:"item_name" validate:"required"`
+	FromUnit  string    `gorm:"size:20;not null" json:"from_unit"`
+	ToUnit    string    `gorm:"size:20;not null" json:"to_unit"`
+	Factor    float64   `gorm:"not null" json:"factor"` // 1 FromUnit = Factor ToUnit
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CategoryUnit is the canonical unit totals are reported in for a category
+type CategoryUnit struct {
+	Category string `gorm:"primaryKey;size:50" json:"category" validate:"oneof=Food Medical Clothing Shelter Other"`
+	UnitCode string `gorm:"size:20;not null" json:"unit_code" validate:"required"`
+}
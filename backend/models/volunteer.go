@@ -6,13 +6,14 @@ import "time"
 //"id" -> we lastly use simple letters because JSON uses lowercase by convention
 type Volunteer struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
-	Name         string    `gorm:"size:100;not null" json:"name"`
-	Email        string    `gorm:"size:100;unique; not null" json:"email"`
-	Phone        string    `gorm:"size:15;not null" json:"phone"`
+	Name         string    `gorm:"size:100;not null" json:"name" validate:"required"`
+	Email        string    `gorm:"size:100;unique; not null" json:"email" validate:"required"`
+	Phone        string    `gorm:"size:20;not null" json:"phone" validate:"required"` // display form, e.g. 077 123 4567
+	PhoneE164    string    `gorm:"size:16;index" json:"phone_e164"`                   // +94771234567, for lookups
 	Skills       string    `gorm:"type:text" json:"skills"`
 	Availability string    `gorm:"size:100" json:"availability"`
 	Location     string    `gorm:"size:255" json:"location"`
-	Status       string    `gorm:"size:20;default:'active'" json:"status"`
+	Status       string    `gorm:"size:20;default:'active'" json:"status" validate:"oneof=active inactive on-duty"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
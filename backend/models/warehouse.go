@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Warehouse is a depot or store where relief supplies are kept
+type Warehouse struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:100;not null" json:"name" validate:"required"`
+	Code             string    `gorm:"size:20;uniqueIndex;not null" json:"code" validate:"required"` // e.g. RAT-01
+	District         string    `gorm:"size:50" json:"district"`
+	Address          string    `gorm:"size:255" json:"address"`
+	Latitude         float64   `json:"latitude"`
+	Longitude        float64   `json:"longitude"`
+	ManagerName      string    `gorm:"size:100" json:"manager_name"`
+	ManagerPhone     string    `gorm:"size:20" json:"manager_phone"`
+	ManagerPhoneE164 string    `gorm:"size:16" json:"manager_phone_e164"`
+	IsActive         bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// WebhookSubscription sends change events of the listed types to a partner
+// system. Payloads are signed with Secret; a subscription that keeps
+// failing is switched off and says why.
+type WebhookSubscription struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	Name                string     `gorm:"size:100;not null" json:"name" validate:"required"`
+	URL                 string     `gorm:"size:500;not null" json:"url" validate:"required"`
+	Secret              string     `gorm:"size:100;not null" json:"-"`                               // only shown when created or rotated
+	EventTypes          string     `gorm:"size:500;not null" json:"event_types" validate:"required"` // comma separated, e.g. help_request.created,rescue_operation.*
+	IsActive            bool       `gorm:"default:true;index" json:"is_active"`
+	ConsecutiveFailures int        `gorm:"default:0" json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `gorm:"size:255" json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery is one change event on its way to one subscription, and
+// the log of how sending it went. Payload is kept so a redelivery sends
+// exactly the same body.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"not null;index" json:"subscription_id"`
+	ChangeEventID  uint       `gorm:"index" json:"change_event_id"`
+	EventType      string     `gorm:"size:50;not null" json:"event_type"`
+	Payload        string     `gorm:"type:text;not null" json:"-"`
+	Status         string     `gorm:"size:20;default:'pending';index" json:"status" validate:"oneof=pending delivered failed"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `gorm:"size:500" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	RedeliveryOf   *uint      `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
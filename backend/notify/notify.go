@@ -0,0 +1,50 @@
+// Package notify sends SMS messages to the public through the SMS gateway.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// SendSMS posts a message to SMS_GATEWAY_URL as {"to": ..., "message": ...}.
+// When no gateway is configured the message is only written to the log.
+func SendSMS(phone, message string) error {
+	gateway := os.Getenv("SMS_GATEWAY_URL")
+	if gateway == "" {
+		log.Printf("📱 SMS to %s (no gateway configured): %s", phone, message)
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{"to": phone, "message": message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", gateway, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv("SMS_GATEWAY_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway returned %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package oncall works out who to call for a service right now and walks
+// escalation chains until someone acknowledges the call-out.
+package oncall
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"flood-relief-system/backend/models"
+	"flood-relief-system/backend/notify"
+	"flood-relief-system/backend/phone"
+
+	"gorm.io/gorm"
+)
+
+// Where an answer came from, most specific first
+const (
+	FromShift     = "shift"
+	FromChain     = "escalation-chain"
+	FromDirectory = "directory"
+)
+
+// Answer is who to call for a service at a time. Backups are the other
+// people on shift and the rest of the escalation chain, in calling order.
+type Answer struct {
+	ServiceType string                    `json:"service_type"`
+	District    string                    `json:"district,omitempty"`
+	At          time.Time                 `json:"at"`
+	Source      string                    `json:"source,omitempty"` // empty when nobody is available
+	Contact     *models.EmergencyContact  `json:"contact"`
+	Shift       *models.OnCallShift       `json:"shift,omitempty"`
+	Backups     []models.EmergencyContact `json:"backups"`
+}
+
+// scope limits a query to the service and organization, and to the district
+// or to records that cover every district. District-specific records sort
+// first.
+func scope(query *gorm.DB, table, service, district, organization string) *gorm.DB {
+	query = query.Where(table+".service_type = ?", service)
+	if organization != "" {
+		query = query.Where(table+".organization_name = ?", organization)
+	}
+	if district != "" {
+		query = query.Where("("+table+".district = ? OR "+table+".district = '')", district).
+			Order("CASE WHEN " + table + ".district = '' THEN 1 ELSE 0 END")
+	}
+	return query
+}
+
+// Current answers who is on call for the service at the given time. A
+// matching shift wins, then the first step of an escalation chain, then any
+// active directory contact for the service. Contacts marked unreachable
+// are skipped.
+func Current(db *gorm.DB, service, district, organization string, at time.Time) (*Answer, error) {
+	answer := &Answer{ServiceType: service, District: district, At: at, Backups: []models.EmergencyContact{}}
+	seen := map[uint]bool{}
+	add := func(contact models.EmergencyContact, source string, shift *models.OnCallShift) {
+		if !contact.IsActive || contact.VerificationStatus == "unreachable" || seen[contact.ID] {
+			return
+		}
+		seen[contact.ID] = true
+		if answer.Contact == nil {
+			answer.Contact = &contact
+			answer.Source = source
+			answer.Shift = shift
+			return
+		}
+		answer.Backups = append(answer.Backups, contact)
+	}
+
+	var shifts []models.OnCallShift
+	err := scope(db.Preload("Contact"), "on_call_shifts", service, district, organization).
+		Where("starts_at <= ? AND ends_at > ?", at, at).
+		Order("starts_at DESC, id").
+		Find(&shifts).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range shifts {
+		add(shifts[i].Contact, FromShift, &shifts[i])
+	}
+
+	var chains []models.EscalationChain
+	err = scope(db.Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).Preload("Steps.Contact"),
+		"escalation_chains", service, district, organization).
+		Where("is_active = ?", true).
+		Order("id").
+		Find(&chains).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		for _, step := range chain.Steps {
+			add(step.Contact, FromChain, nil)
+		}
+	}
+
+	if answer.Contact == nil {
+		var contacts []models.EmergencyContact
+		err := scope(db, "emergency_contacts", service, district, organization).
+			Where("is_active = ?", true).
+			Order("organization_name ASC").
+			Find(&contacts).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, contact := range contacts {
+			add(contact, FromDirectory, nil)
+		}
+	}
+
+	return answer, nil
+}
+
+// Start opens an escalation on the chain and calls its first step
+func Start(db *gorm.DB, chain *models.EscalationChain, message, raisedBy string) (*models.Escalation, error) {
+	escalation := &models.Escalation{
+		EscalationChainID: chain.ID,
+		Message:           message,
+		Status:            "open",
+		RaisedBy:          raisedBy,
+	}
+	if err := db.Create(escalation).Error; err != nil {
+		return nil, err
+	}
+
+	if err := callNext(db, escalation, chain); err != nil {
+		return nil, err
+	}
+	return escalation, nil
+}
+
+// callNext texts the next active, reachable contact in the chain after the
+// current position. Each step is claimed before its text goes out, so two
+// runs advancing the same escalation cannot both text a contact; the run
+// that loses the claim stops. When the chain runs out the escalation is
+// marked exhausted and ESCALATION_ALERT_PHONE is told nobody answered.
+func callNext(db *gorm.DB, escalation *models.Escalation, chain *models.EscalationChain) error {
+	var steps []models.EscalationStep
+	err := db.Preload("Contact").
+		Where("escalation_chain_id = ? AND position > ?", chain.ID, escalation.Position).
+		Order("position").
+		Find(&steps).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, step := range steps {
+		if !step.Contact.IsActive || step.Contact.VerificationStatus == "unreachable" {
+			continue
+		}
+
+		// Claim the step: it only moves on from the position this run saw,
+		// and only while nobody has acknowledged
+		result := db.Model(&models.Escalation{}).
+			Where("id = ? AND status = ? AND position = ?", escalation.ID, "open", escalation.Position).
+			Updates(map[string]interface{}{"position": step.Position, "emergency_contact_id": step.EmergencyContactID, "notified_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return nil
+		}
+		escalation.Position = step.Position
+		escalation.EmergencyContactID = &step.EmergencyContactID
+		escalation.NotifiedAt = &now
+
+		message := fmt.Sprintf("Flood Relief %s call-out #%d: %s. Please acknowledge.", chain.ServiceType, escalation.ID, escalation.Message)
+		to := step.Contact.PhoneE164
+		if to == "" {
+			to = step.Contact.Phone // short codes have no E.164 form
+		}
+		if err := notify.SendSMS(to, message); err != nil {
+			// An unreachable number is what escalation is for; try the next one
+			log.Printf("❌ Escalation #%d could not reach %s: %v", escalation.ID, step.Contact.Phone, err)
+			continue
+		}
+		return nil
+	}
+
+	// Only close it if nobody acknowledged while the texts were going out
+	escalation.Status = "exhausted"
+	escalation.EmergencyContactID = nil
+	escalation.ClosedAt = &now
+	result := db.Model(escalation).Where("status = ? AND position = ?", "open", escalation.Position).
+		Select("status", "emergency_contact_id", "closed_at").Updates(escalation)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	message := fmt.Sprintf("Flood Relief: nobody acknowledged %s call-out #%d (%s): %s", chain.ServiceType, escalation.ID, chain.Name, escalation.Message)
+	if to := phone.Key(os.Getenv("ESCALATION_ALERT_PHONE")); to != "" {
+		return notify.SendSMS(to, message)
+	}
+	log.Println("⚠️ ", message)
+	return nil
+}
+
+// Advance moves every open escalation whose current step has not
+// acknowledged within the chain's timeout on to the next step. An
+// escalation that fails is logged and skipped so the others still move. It
+// returns the number of escalations moved on.
+func Advance(db *gorm.DB, now time.Time) (int, error) {
+	var escalations []models.Escalation
+	if err := db.Where("status = ?", "open").Order("id").Find(&escalations).Error; err != nil {
+		return 0, err
+	}
+
+	advanced := 0
+	for i := range escalations {
+		escalation := &escalations[i]
+
+		var chain models.EscalationChain
+		if err := db.First(&chain, escalation.EscalationChainID).Error; err != nil {
+			log.Printf("❌ Escalation #%d has no usable chain: %v", escalation.ID, err)
+			continue
+		}
+
+		timeout := time.Duration(chain.AckTimeoutMinutes) * time.Minute
+		if escalation.NotifiedAt != nil && now.Sub(*escalation.NotifiedAt) < timeout {
+			continue
+		}
+
+		if err := callNext(db, escalation, &chain); err != nil {
+			log.Printf("❌ Escalation #%d could not move on: %v", escalation.ID, err)
+			continue
+		}
+		advanced++
+	}
+
+	return advanced, nil
+}
@@ -0,0 +1,338 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Op annotates a handler with what it is for, what it reads and what it
+// answers. Paths and methods are not part of it; they come from the router.
+type Op struct {
+	Handler     http.HandlerFunc
+	Tag         string
+	Summary     string
+	Description string
+	Path        []Param     // path parameters; {id} and {*_id} are IDs unless listed
+	Query       []Param     // query parameters
+	Body        interface{} // JSON request body: a value of its type, or a *Schema
+	Required    []string    // body fields that must be given, besides those a POST body's type tags required
+	Upload      []string    // file types accepted as the raw body, besides a multipart "file" field
+	Status      int         // success status, 200 when zero
+	Response    interface{} // JSON answer: a value of its type, or a *Schema
+	Produces    []string    // other content types the success answer can have
+	Export      bool        // a list that also answers ?format=csv and ?format=xlsx
+	Errors      []int       // error statuses besides the 400, 404 and 500 that are inferred
+}
+
+// Param is a path or query parameter
+type Param struct {
+	Name        string
+	Description string
+	Schema      *Schema
+	Required    bool
+}
+
+// Spec is a built document, with its operations indexed by method and path
+// template for the request validator
+type Spec struct {
+	Document   Document
+	operations map[string]*operation
+}
+
+type operation struct {
+	*Operation
+	body *Schema // the JSON body schema, nil when the body is not JSON
+}
+
+// errorResponses are the shared error answers by status
+var errorResponses = map[int]string{
+	http.StatusBadRequest:            "BadRequest",
+	http.StatusNotFound:              "NotFound",
+	http.StatusConflict:              "Conflict",
+	http.StatusRequestEntityTooLarge: "RequestEntityTooLarge",
+	http.StatusUnprocessableEntity:   "UnprocessableEntity",
+	http.StatusInternalServerError:   "InternalServerError",
+}
+
+var exportTypes = []string{
+	"text/csv",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+var pathParam = regexp.MustCompile(`\{([^{}:]+)(?::[^{}]*)?\}`)
+
+// Build walks the router and describes every route with the annotation of
+// its handler. Paths are listed relative to base, the server URL. Routes
+// without an annotation and annotations without a route are returned as
+// the error; the document is still built from everything that matched.
+func Build(router *mux.Router, base string, info Info, ops []Op) (*Spec, error) {
+	s := newSchemas()
+	spec := &Spec{
+		Document: Document{
+			OpenAPI: "3.0.3",
+			Info:    info,
+			Servers: []Server{{URL: base}},
+			Paths:   map[string]PathItem{},
+		},
+		operations: map[string]*operation{},
+	}
+	var problems []string
+
+	byHandler := map[uintptr]*Op{}
+	for i := range ops {
+		ptr := reflect.ValueOf(ops[i].Handler).Pointer()
+		if _, dup := byHandler[ptr]; dup {
+			problems = append(problems, fmt.Sprintf("%s is annotated twice", funcName(ptr)))
+		}
+		byHandler[ptr] = &ops[i]
+	}
+
+	used := map[uintptr]bool{}
+	seenTags := map[string]bool{}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		handler, ok := route.GetHandler().(http.HandlerFunc)
+		if !ok {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s has no methods", template))
+			return nil
+		}
+
+		ptr := reflect.ValueOf(handler).Pointer()
+		op, ok := byHandler[ptr]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s: %s has no annotation",
+				strings.Join(methods, ","), template, funcName(ptr)))
+			return nil
+		}
+		used[ptr] = true
+
+		if op.Tag != "" && !seenTags[op.Tag] {
+			seenTags[op.Tag] = true
+			spec.Document.Tags = append(spec.Document.Tags, Tag{Name: op.Tag})
+		}
+
+		path := strings.TrimPrefix(template, base)
+		for _, method := range methods {
+			built := spec.operation(s, op, method, template, ptr)
+			if spec.Document.Paths[path] == nil {
+				spec.Document.Paths[path] = PathItem{}
+			}
+			spec.Document.Paths[path][strings.ToLower(method)] = built.Operation
+			spec.operations[method+" "+template] = built
+		}
+		return nil
+	})
+
+	var unused []string
+	for ptr := range byHandler {
+		if !used[ptr] {
+			unused = append(unused, fmt.Sprintf("%s is annotated but has no route", funcName(ptr)))
+		}
+	}
+	sort.Strings(unused)
+	problems = append(problems, unused...)
+
+	spec.Document.Components = Components{
+		Schemas:   s.components,
+		Responses: map[string]Response{},
+	}
+	s.components["FieldError"] = Object(map[string]*Schema{
+		"field":   {Type: "string", Description: "JSON path of the input, e.g. members[1].name"},
+		"code":    Enum("required", "oneof", "min", "max", "type", "format", "phone", "invalid"),
+		"message": String(),
+	}, "field", "code", "message")
+	s.components["Error"] = Object(map[string]*Schema{
+		"code":       {Type: "string", Description: "Machine-readable reason, e.g. validation_failed or not_found"},
+		"message":    String(),
+		"error":      {Type: "string", Description: "The message again, for clients that predate code and message"},
+		"fields":     ArrayOf(&Schema{Ref: "#/components/schemas/FieldError"}),
+		"request_id": {Type: "string", Description: "Also sent as the X-Request-ID header"},
+	}, "code", "message", "error")
+	for status, name := range errorResponses {
+		spec.Document.Components.Responses[name] = Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+	}
+
+	var err error
+	if len(problems) > 0 {
+		err = errors.New(strings.Join(problems, "\n"))
+	}
+	return spec, err
+}
+
+// operation describes one method of a route
+func (spec *Spec) operation(s *schemas, op *Op, method, template string, ptr uintptr) *operation {
+	name := funcName(ptr)
+	built := &operation{Operation: &Operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: name[strings.LastIndex(name, ".")+1:],
+		Responses:   map[string]Response{},
+	}}
+	if op.Tag != "" {
+		built.Tags = []string{op.Tag}
+	}
+
+	hasID := false
+	for _, match := range pathParam.FindAllStringSubmatch(template, -1) {
+		param := Parameter{Name: match[1], In: "path", Required: true, Schema: String()}
+		if match[1] == "id" || strings.HasSuffix(match[1], "_id") {
+			param.Schema = ID()
+			hasID = true
+		}
+		for _, p := range op.Path {
+			if p.Name == match[1] {
+				param.Description = p.Description
+				param.Schema = p.Schema
+			}
+		}
+		built.Parameters = append(built.Parameters, param)
+	}
+
+	query := append([]Param{}, op.Query...)
+	if op.Export {
+		query = append(query,
+			Param{Name: "format", Description: "json (the default), or csv or xlsx to download the list", Schema: Enum("json", "csv", "xlsx")},
+			Param{Name: "lang", Description: "Language of the download's column headers; defaults to Accept-Language, then en", Schema: Enum("en", "si", "ta")},
+		)
+	}
+	for _, p := range query {
+		built.Parameters = append(built.Parameters, Parameter{
+			Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: p.Schema,
+		})
+	}
+
+	if op.Body != nil || op.Upload != nil {
+		body := &RequestBody{Content: map[string]MediaType{}}
+		if op.Body != nil {
+			schema := s.of(op.Body)
+			required := op.Required
+			if method == http.MethodPost {
+				required = bodyRequired(op.Body, required)
+			}
+			if len(required) > 0 {
+				schema = &Schema{Type: "object", AllOf: []*Schema{schema}, Required: required}
+				body.Required = true
+			}
+			body.Content["application/json"] = MediaType{Schema: schema}
+			built.body = schema
+		}
+		if op.Upload != nil {
+			body.Required = true
+			body.Content["multipart/form-data"] = MediaType{Schema: Object(map[string]*Schema{
+				"file": {Type: "string", Format: "binary"},
+			}, "file")}
+			for _, contentType := range op.Upload {
+				body.Content[contentType] = MediaType{Schema: fileSchema(contentType)}
+			}
+		}
+		built.RequestBody = body
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status), Content: map[string]MediaType{}}
+	if op.Response != nil {
+		success.Content["application/json"] = MediaType{Schema: s.of(op.Response)}
+	}
+	produces := append([]string{}, op.Produces...)
+	if op.Export {
+		produces = append(produces, exportTypes...)
+	}
+	for _, contentType := range produces {
+		success.Content[contentType] = MediaType{Schema: fileSchema(contentType)}
+	}
+	if len(success.Content) == 0 {
+		success.Content = nil
+	}
+	built.Responses[strconv.Itoa(status)] = success
+
+	errs := append([]int{http.StatusInternalServerError}, op.Errors...)
+	if len(built.Parameters) > 0 || built.RequestBody != nil {
+		errs = append(errs, http.StatusBadRequest)
+	}
+	if hasID {
+		errs = append(errs, http.StatusNotFound)
+	}
+	for _, code := range errs {
+		if name, ok := errorResponses[code]; ok {
+			built.Responses[strconv.Itoa(code)] = Response{Ref: "#/components/responses/" + name}
+			continue
+		}
+		built.Responses[strconv.Itoa(code)] = Response{
+			Description: http.StatusText(code),
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+	}
+	return built
+}
+
+// bodyRequired adds the fields a body's type tags required to the listed
+// ones, without repeats
+func bodyRequired(body interface{}, listed []string) []string {
+	switch body.(type) {
+	case *Schema, Fields:
+		return listed
+	}
+	required := append([]string{}, listed...)
+	for _, name := range requiredFields(reflect.TypeOf(body)) {
+		if !contains(required, name) {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+// JSON is the document as indented JSON, the same bytes every time for the
+// same routes and annotations
+func (spec *Spec) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(spec.Document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fileSchema describes a body that is a file rather than JSON
+func fileSchema(contentType string) *Schema {
+	if strings.HasPrefix(contentType, "text/") {
+		return String()
+	}
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+// funcName is the package-qualified name of a handler, e.g.
+// controllers.GetAllHelpRequests
+func funcName(ptr uintptr) string {
+	fn := runtime.FuncForPC(ptr)
+	if fn == nil {
+		return "unknown handler"
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,293 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/validation"
+
+	"github.com/gorilla/mux"
+)
+
+// Validation modes, from OPENAPI_VALIDATION
+const (
+	Enforce = "enforce" // reject requests that do not match with 400
+	Report  = "report"  // log them and let them through
+	Off     = "off"
+)
+
+// ValidationMode reads OPENAPI_VALIDATION, enforce by default
+func ValidationMode() string {
+	switch mode := strings.ToLower(os.Getenv("OPENAPI_VALIDATION")); mode {
+	case Report, Off:
+		return mode
+	}
+	return Enforce
+}
+
+// Validator is middleware that checks each request against the operation
+// of the route it matched: path and query parameters, and JSON bodies.
+// Like the handlers' JSON decoding it reads null, and an empty string for
+// a field that is not required, as a field that was left out.
+func (spec *Spec) Validator(mode string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if mode == Off {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			problems := spec.Validate(r)
+			if len(problems) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if mode == Report {
+				log.Printf("⚠️  %s %s does not match the OpenAPI document: %s", r.Method, r.URL.Path, strings.Join(validation.Messages(problems), "; "))
+				next.ServeHTTP(w, r)
+				return
+			}
+			apierror.Invalid(w, problems)
+		})
+	}
+}
+
+// Validate lists how a request differs from its operation, by field: the
+// parameter name, or the JSON path in the body. The body is read and put
+// back for the handler.
+func (spec *Spec) Validate(r *http.Request) []apierror.FieldError {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	op, ok := spec.operations[r.Method+" "+template]
+	if !ok {
+		return nil
+	}
+
+	var problems []apierror.FieldError
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			if code, msg := checkParam(param.Schema, vars[param.Name]); msg != "" {
+				problems = append(problems, validation.Field(param.Name, code, "path parameter "+param.Name+" "+msg))
+			}
+		case "query":
+			value := query.Get(param.Name)
+			if value == "" {
+				if param.Required {
+					problems = append(problems, validation.Field(param.Name, "required", "query parameter "+param.Name+" is required"))
+				}
+				continue
+			}
+			if code, msg := checkParam(param.Schema, value); msg != "" {
+				problems = append(problems, validation.Field(param.Name, code, "query parameter "+param.Name+" "+msg))
+			}
+		}
+	}
+
+	if op.body == nil || r.Body == nil || op.isFileUpload(r) {
+		return problems
+	}
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return append(problems, validation.Field("body", "invalid", "the request body could not be read"))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.RequestBody.Required {
+			problems = append(problems, validation.Field("body", "required", "a request body is required"))
+		}
+		return problems
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return append(problems, validation.Field("body", "format", "the request body is not valid JSON"))
+	}
+	return spec.check(op.body, body, "", problems)
+}
+
+// isFileUpload reports whether the body is a file rather than JSON, on
+// operations that take both
+func (op *operation) isFileUpload(r *http.Request) bool {
+	if _, ok := op.RequestBody.Content["multipart/form-data"]; !ok {
+		return false
+	}
+	contentType := r.Header.Get("Content-Type")
+	return contentType != "" && !strings.Contains(contentType, "json")
+}
+
+// checkParam checks a path or query value against a plain schema, giving
+// the field error code and message when it does not match
+func checkParam(schema *Schema, value string) (string, string) {
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "type", "must be a whole number"
+		}
+		if schema.Minimum != nil && float64(n) < *schema.Minimum {
+			return "min", fmt.Sprintf("must be at least %g", *schema.Minimum)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "type", "must be a number"
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "type", "must be true or false"
+		}
+	case "string":
+		if len(schema.Enum) > 0 && !contains(schema.Enum, value) {
+			return "oneof", "must be one of: " + strings.Join(schema.Enum, ", ")
+		}
+	}
+	return "", ""
+}
+
+// check adds how a decoded JSON value differs from a schema to problems;
+// at is the value's JSON path, "" for the whole body
+func (spec *Spec) check(schema *Schema, value interface{}, at string, problems []apierror.FieldError) []apierror.FieldError {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		if resolved, ok := spec.Document.Components.Schemas[name]; ok {
+			return spec.check(resolved, value, at, problems)
+		}
+		return problems
+	}
+	for _, part := range schema.AllOf {
+		problems = spec.check(part, value, at, problems)
+	}
+	if value == nil {
+		return problems
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(problems, problem(at, "type", "must be an object"))
+		}
+		for _, name := range schema.Required {
+			if v, ok := object[name]; !ok || v == nil || v == "" {
+				problems = append(problems, problem(join(at, name), "required", "is required"))
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v := object[name]
+			if property, ok := schema.Properties[name]; ok {
+				problems = spec.check(property, v, join(at, name), problems)
+			} else if schema.AdditionalProperties != nil {
+				problems = spec.check(schema.AdditionalProperties, v, join(at, name), problems)
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return append(problems, problem(at, "type", "must be a list"))
+		}
+		if schema.Items != nil {
+			for i, v := range list {
+				problems = spec.check(schema.Items, v, fmt.Sprintf("%s[%d]", at, i), problems)
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return append(problems, problem(at, "type", "must be a string"))
+		}
+		if text == "" {
+			return problems
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, text) {
+			problems = append(problems, problem(at, "oneof", "must be one of: "+strings.Join(schema.Enum, ", ")))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				problems = append(problems, problem(at, "format", "must be a date and time such as 2025-11-30T08:00:00+05:30"))
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return append(problems, problem(at, "type", "must be a whole number"))
+		}
+		n, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return append(problems, problem(at, "type", "must be a whole number"))
+		}
+		problems = checkBounds(schema, float64(n), at, problems)
+	case "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return append(problems, problem(at, "type", "must be a number"))
+		}
+		if n, err := number.Float64(); err == nil {
+			problems = checkBounds(schema, n, at, problems)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(problems, problem(at, "type", "must be true or false"))
+		}
+	}
+	return problems
+}
+
+// checkBounds adds a problem when a number is outside the schema's minimum
+// or maximum
+func checkBounds(schema *Schema, n float64, at string, problems []apierror.FieldError) []apierror.FieldError {
+	if schema.Minimum != nil && n < *schema.Minimum {
+		problems = append(problems, problem(at, "min", fmt.Sprintf("must be at least %g", *schema.Minimum)))
+	}
+	if schema.Maximum != nil && n > *schema.Maximum {
+		problems = append(problems, problem(at, "max", fmt.Sprintf("must be at most %g", *schema.Maximum)))
+	}
+	return problems
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// problem is a field error for the body value at a JSON path
+func problem(at, code, msg string) apierror.FieldError {
+	if at == "" {
+		at = "body"
+	}
+	return validation.Field(at, code, at+" "+msg)
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
@@ -0,0 +1,40 @@
+package phone
+
+import (
+	"strings"
+
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/validation"
+)
+
+// Check normalizes a phone number from a request. On failure the second
+// value is the message for the 400 response.
+func Check(raw string, allowShortCode bool) (Number, string) {
+	parse := Parse
+	if allowShortCode {
+		parse = ParseService
+	}
+	number, err := parse(raw)
+	if err != nil {
+		msg := err.Error()
+		return number, strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	return number, ""
+}
+
+// Field checks a phone number from a request body and normalizes it. On
+// failure the field error points at field.
+func Field(field, raw string, allowShortCode bool) (Number, []apierror.FieldError) {
+	number, msg := Check(raw, allowShortCode)
+	if msg != "" {
+		return number, []apierror.FieldError{validation.Field(field, "phone", msg)}
+	}
+	return number, nil
+}
+
+// E164 is the E.164 form of a stored number, or "" for numbers that
+// predate validation and cannot be parsed
+func E164(raw string) string {
+	number, _ := Parse(raw)
+	return number.E164
+}
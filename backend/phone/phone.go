@@ -0,0 +1,164 @@
+// Package phone parses the many ways a phone number is written ("077...",
+// "+9477...", "0094 77...") into one E.164 form for lookups, plus a tidy
+// display form.
+package phone
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInvalid is returned for text that is not a usable phone number
+	ErrInvalid = errors.New("invalid phone number: use a local number like 077 123 4567 or an international number starting with +")
+	// ErrShortCode is returned by Parse for service numbers such as 119
+	ErrShortCode = errors.New("short service numbers such as 119 are only accepted for emergency contacts")
+)
+
+// Number is a parsed phone number. Short service numbers (e.g. 119, 1990)
+// have no E.164 form.
+type Number struct {
+	E164      string // +94771234567
+	Display   string // 077 123 4567
+	ShortCode bool
+}
+
+// CountryCode is the calling code assumed for numbers written without one,
+// from PHONE_COUNTRY_CODE (default 94, Sri Lanka)
+func CountryCode() string {
+	if code := strings.TrimPrefix(os.Getenv("PHONE_COUNTRY_CODE"), "+"); code != "" {
+		return code
+	}
+	return "94"
+}
+
+// nationalLength is the length of national numbers (without the trunk 0)
+// for countries whose numbering is checked more strictly than E.164 allows
+var nationalLength = map[string]int{
+	"94": 9, // Sri Lanka: 7X XXX XXXX mobiles, 11 XXX XXXX Colombo
+	"91": 10,
+}
+
+// digitsOf strips the separators people type; anything else makes the
+// number invalid
+func digitsOf(raw string) (string, bool) {
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// Parse reads a subscriber number in local or international format
+func Parse(raw string) (Number, error) {
+	number, err := ParseService(raw)
+	if err == nil && number.ShortCode {
+		return Number{}, ErrShortCode
+	}
+	return number, err
+}
+
+// ParseService is Parse that also accepts short service numbers of three
+// to five digits, as used by hotlines
+func ParseService(raw string) (Number, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+	digits, ok := digitsOf(strings.TrimPrefix(raw, "+"))
+	if !ok || digits == "" {
+		return Number{}, ErrInvalid
+	}
+
+	if !international && len(digits) >= 3 && len(digits) <= 5 && digits[0] != '0' {
+		return Number{Display: digits, ShortCode: true}, nil
+	}
+
+	home := CountryCode()
+	var country, national string
+	switch {
+	case international:
+		country, national = splitCountry(digits)
+	case strings.HasPrefix(digits, "00"):
+		country, national = splitCountry(digits[2:])
+	case strings.HasPrefix(digits, "0"):
+		country, national = home, digits[1:]
+	case strings.HasPrefix(digits, home) && len(digits) == len(home)+nationalLength[home]:
+		// "9477..." typed without the +
+		country, national = home, digits[len(home):]
+	default:
+		country, national = home, digits
+	}
+
+	if country == "" || national == "" || national[0] == '0' {
+		return Number{}, ErrInvalid
+	}
+	if want, ok := nationalLength[country]; ok && len(national) != want {
+		return Number{}, ErrInvalid
+	}
+	if total := len(country) + len(national); total < 8 || total > 15 {
+		return Number{}, ErrInvalid
+	}
+
+	number := Number{E164: "+" + country + national}
+	if country == home {
+		number.Display = groupNational("0" + national)
+	} else {
+		number.Display = number.E164
+	}
+	return number, nil
+}
+
+// splitCountry separates the calling code from an international number.
+// Calling codes are prefix-free, so the shortest known code that matches
+// is the right one; unknown codes are assumed to be three digits.
+func splitCountry(digits string) (string, string) {
+	for length := 1; length <= 3 && length < len(digits); length++ {
+		if callingCodes[digits[:length]] {
+			return digits[:length], digits[length:]
+		}
+	}
+	if len(digits) > 3 {
+		return digits[:3], digits[3:]
+	}
+	return "", ""
+}
+
+// groupNational writes a 10-digit national number as 077 123 4567
+func groupNational(national string) string {
+	if len(national) != 10 {
+		return national
+	}
+	return national[:3] + " " + national[3:6] + " " + national[6:]
+}
+
+// Key is the E.164 form of a number for lookups and dedupe, or the text
+// with separators removed when it cannot be parsed
+func Key(raw string) string {
+	if number, err := ParseService(raw); err == nil {
+		if number.E164 != "" {
+			return number.E164
+		}
+		return number.Display
+	}
+	digits, _ := digitsOf(raw)
+	return digits
+}
+
+// callingCodes are the one- and two-digit calling codes, plus the
+// three-digit codes of the region that relief partners call most
+var callingCodes = map[string]bool{
+	"1": true, "7": true,
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true, "36": true, "39": true,
+	"40": true, "41": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "52": true, "53": true, "54": true, "55": true, "56": true, "57": true, "58": true,
+	"60": true, "61": true, "62": true, "63": true, "64": true, "65": true, "66": true,
+	"81": true, "82": true, "84": true, "86": true,
+	"90": true, "91": true, "92": true, "93": true, "94": true, "95": true, "98": true,
+	"880": true, "960": true, "971": true, "973": true, "974": true, "975": true, "977": true,
+}
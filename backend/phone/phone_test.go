@@ -0,0 +1,103 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("PHONE_COUNTRY_CODE", "")
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    Number
+		wantErr error
+	}{
+		{"local mobile", "0771234567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"local with separators", "077-123 4567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"local landline", "(011) 234 5678", Number{E164: "+94112345678", Display: "011 234 5678"}, nil},
+		{"international", "+94 77 123 4567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"international without plus", "94771234567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"0094 prefix", "0094771234567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"without trunk zero", "771234567", Number{E164: "+94771234567", Display: "077 123 4567"}, nil},
+		{"foreign", "+44 20 7946 0958", Number{E164: "+442079460958", Display: "+442079460958"}, nil},
+		{"foreign with 00", "00 44 20 7946 0958", Number{E164: "+442079460958", Display: "+442079460958"}, nil},
+		{"short code", "119", Number{}, ErrShortCode},
+		{"local too short", "077123456", Number{}, ErrInvalid},
+		{"local too long", "07712345678", Number{}, ErrInvalid},
+		{"letters", "077 CALL NOW", Number{}, ErrInvalid},
+		{"empty", "", Number{}, ErrInvalid},
+		{"trunk zero after country code", "+94 077 123 4567", Number{}, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseService(t *testing.T) {
+	t.Setenv("PHONE_COUNTRY_CODE", "")
+
+	tests := []struct {
+		raw  string
+		want Number
+	}{
+		{"119", Number{Display: "119", ShortCode: true}},
+		{"1990", Number{Display: "1990", ShortCode: true}},
+		{" 117 ", Number{Display: "117", ShortCode: true}},
+		{"0771234567", Number{E164: "+94771234567", Display: "077 123 4567"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseService(tt.raw)
+		if err != nil {
+			t.Errorf("ParseService(%q) error = %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseService(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseCountryCode(t *testing.T) {
+	t.Setenv("PHONE_COUNTRY_CODE", "+91")
+
+	got, err := Parse("09876543210")
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	if got.E164 != "+919876543210" {
+		t.Errorf("E164 = %q, want +919876543210", got.E164)
+	}
+}
+
+func TestKey(t *testing.T) {
+	t.Setenv("PHONE_COUNTRY_CODE", "")
+
+	tests := []struct {
+		raw, want string
+	}{
+		{"077 123 4567", "+94771234567"},
+		{"+94771234567", "+94771234567"},
+		{"0094 77 123 4567", "+94771234567"},
+		{"119", "119"},
+		{"12-34", "1234"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Key(tt.raw); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
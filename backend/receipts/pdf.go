@@ -0,0 +1,82 @@
+package receipts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"flood-relief-system/backend/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// ErrPDFLanguage is returned for a PDF in Sinhala or Tamil. The PDF library
+// places glyphs but does not shape complex scripts, so their conjuncts would
+// print wrongly; the HTML receipt is printed from the browser instead.
+var ErrPDFLanguage = errors.New("PDF receipts are only printed in English")
+
+// PDF writes the receipt as a one-page A4 PDF in English, in the TrueType
+// font from RECEIPT_FONT_EN or the built-in Helvetica
+func PDF(w io.Writer, receipt *models.DonationReceipt, donor *models.Donor, lang string) error {
+	if lang != "en" {
+		return ErrPDFLanguage
+	}
+	l := labelsFor(lang)
+	pdf := fpdf.New("P", "mm", "A4", "")
+
+	family := "Helvetica"
+	text := func(s string) string { return s }
+	if path := os.Getenv("RECEIPT_FONT_EN"); path != "" {
+		font, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading receipt font: %w", err)
+		}
+		family = "receipt"
+		pdf.AddUTF8FontFromBytes(family, "", font)
+	} else {
+		text = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+
+	pdf.SetFont(family, "", 16)
+	pdf.CellFormat(0, 9, text(l.Organization), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 13)
+	pdf.CellFormat(0, 8, text(l.Title), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(family, "", 11)
+	pdf.CellFormat(0, 6, text(l.Number+": "+receipt.Number), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, text(l.Date+": "+formatDate(receipt.CreatedAt)), "", 1, "L", false, 0, "")
+	donorLine := l.Donor + ": " + donor.Name
+	if donor.Phone != "" {
+		donorLine += " (" + donor.Phone + ")"
+	}
+	pdf.CellFormat(0, 6, text(donorLine), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{75, 25, 30, 40}
+	for i, header := range []string{l.Item, l.Quantity, l.Unit, l.Received} {
+		pdf.CellFormat(widths[i], 7, text(header), "1", 0, "L", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	for _, line := range receipt.Lines {
+		pdf.CellFormat(widths[0], 7, text(line.ItemName), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 7, fmt.Sprint(line.Quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 7, text(line.Unit), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 7, formatDate(line.ReceivedAt), "1", 0, "L", false, 0, "")
+		pdf.Ln(-1)
+	}
+	pdf.Ln(6)
+
+	pdf.MultiCell(0, 6, text(l.Thanks), "", "L", false)
+	if receipt.IssuedBy != "" {
+		pdf.Ln(4)
+		pdf.CellFormat(0, 6, text(l.IssuedBy+": "+receipt.IssuedBy), "", 1, "L", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
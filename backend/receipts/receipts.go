@@ -0,0 +1,144 @@
+// Package receipts renders donation acknowledgement receipts as HTML and PDF
+// in English, Sinhala and Tamil.
+package receipts
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"flood-relief-system/backend/models"
+)
+
+// Languages receipts can be printed in
+var Languages = []string{"en", "si", "ta"}
+
+// IsLanguage reports whether receipts can be printed in lang
+func IsLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// NumberSeries is the sequence receipts issued in a year are numbered from
+func NumberSeries(year int) string {
+	return fmt.Sprintf("donation-receipt-%d", year)
+}
+
+// FormatNumber turns a sequence number into a receipt number
+func FormatNumber(year, n int) string {
+	return fmt.Sprintf("DR-%d-%06d", year, n)
+}
+
+// labels is the fixed text of a receipt in one language
+type labels struct {
+	Organization string
+	Title        string
+	Number       string
+	Date         string
+	Donor        string
+	Item         string
+	Quantity     string
+	Unit         string
+	Received     string
+	IssuedBy     string
+	Thanks       string
+}
+
+var translations = map[string]labels{
+	"en": {
+		Organization: "Flood Relief Coordination",
+		Title:        "Donation Acknowledgement Receipt",
+		Number:       "Receipt No.",
+		Date:         "Date",
+		Donor:        "Received from",
+		Item:         "Item",
+		Quantity:     "Quantity",
+		Unit:         "Unit",
+		Received:     "Date received",
+		IssuedBy:     "Issued by",
+		Thanks:       "Thank you for your generous donation to the flood relief effort.",
+	},
+	"si": {
+		Organization: "ගංවතුර සහන සම්බන්ධීකරණය",
+		Title:        "පරිත්‍යාග පිළිගැනීමේ රිසිට්පත",
+		Number:       "රිසිට්පත් අංකය",
+		Date:         "දිනය",
+		Donor:        "පරිත්‍යාගශීලියා",
+		Item:         "භාණ්ඩය",
+		Quantity:     "ප්‍රමාණය",
+		Unit:         "ඒකකය",
+		Received:     "ලැබුණු දිනය",
+		IssuedBy:     "නිකුත් කළේ",
+		Thanks:       "ගංවතුර සහන කටයුතු සඳහා ඔබ කළ පරිත්‍යාගයට අපගේ හෘදයාංගම ස්තූතිය.",
+	},
+	"ta": {
+		Organization: "வெள்ள நிவாரண ஒருங்கிணைப்பு",
+		Title:        "நன்கொடை ஒப்புகை ரசீது",
+		Number:       "ரசீது எண்",
+		Date:         "தேதி",
+		Donor:        "நன்கொடையாளர்",
+		Item:         "பொருள்",
+		Quantity:     "அளவு",
+		Unit:         "அலகு",
+		Received:     "பெற்ற தேதி",
+		IssuedBy:     "வழங்கியவர்",
+		Thanks:       "வெள்ள நிவாரணப் பணிக்கு நீங்கள் வழங்கிய நன்கொடைக்கு எங்கள் மனமார்ந்த நன்றி.",
+	},
+}
+
+// labelsFor falls back to English for unknown languages
+func labelsFor(lang string) labels {
+	if l, ok := translations[lang]; ok {
+		return l
+	}
+	return translations["en"]
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{"date": formatDate}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.L.Title}} {{.Receipt.Number}}</title>
+<style>
+body { font-family: "Noto Sans", "Noto Sans Sinhala", "Noto Sans Tamil", sans-serif; max-width: 720px; margin: 2em auto; color: #222; }
+h1 { font-size: 1.4em; margin-bottom: 0; }
+h2 { font-size: 1.1em; font-weight: normal; margin-top: 0.2em; }
+table { width: 100%; border-collapse: collapse; margin: 1.5em 0; }
+th, td { border: 1px solid #999; padding: 0.4em; text-align: left; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.L.Organization}}</h1>
+<h2>{{.L.Title}}</h2>
+<p>{{.L.Number}}: <strong>{{.Receipt.Number}}</strong><br>
+{{.L.Date}}: {{date .Receipt.CreatedAt}}<br>
+{{.L.Donor}}: {{.Donor.Name}}{{if .Donor.Phone}} ({{.Donor.Phone}}){{end}}</p>
+<table>
+<tr><th>{{.L.Item}}</th><th>{{.L.Quantity}}</th><th>{{.L.Unit}}</th><th>{{.L.Received}}</th></tr>
+{{range .Receipt.Lines}}<tr><td>{{.ItemName}}</td><td class="number">{{.Quantity}}</td><td>{{.Unit}}</td><td>{{date .ReceivedAt}}</td></tr>
+{{end}}</table>
+<p>{{.L.Thanks}}</p>
+{{if .Receipt.IssuedBy}}<p>{{.L.IssuedBy}}: {{.Receipt.IssuedBy}}</p>{{end}}
+</body>
+</html>
+`))
+
+// HTML writes the receipt as a standalone HTML page
+func HTML(w io.Writer, receipt *models.DonationReceipt, donor *models.Donor, lang string) error {
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Lang":    lang,
+		"L":       labelsFor(lang),
+		"Receipt": receipt,
+		"Donor":   donor,
+	})
+}
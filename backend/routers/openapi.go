@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"flood-relief-system/backend/apierror"
+	"flood-relief-system/backend/openapi"
+	"net/http"
+)
+
+var apiInfo = openapi.Info{
+	Title:       "Flood Relief System API",
+	Description: "Help requests, rescue operations, relief stock, shelters and emergency contacts for flood relief coordination.",
+	Version:     "1.0.0",
+}
+
+// The document built by SetupRoutes, and the routes and annotations that
+// did not match
+var (
+	spec    *openapi.Spec
+	specErr error
+)
+
+// Spec returns the OpenAPI document of the routes once SetupRoutes has run
+func Spec() (*openapi.Spec, error) {
+	return spec, specErr
+}
+
+// routeOperations annotates the handlers of this package
+var routeOperations = []openapi.Op{
+	{Handler: HealthCheck, Tag: "System", Summary: "Check that the API is running",
+		Response: openapi.Fields{"status": "", "message": ""}},
+	{Handler: OpenAPIDocument, Tag: "System", Summary: "The OpenAPI 3 document of this API",
+		Response: &openapi.Schema{Type: "object"}},
+	{Handler: APIDocs, Tag: "System", Summary: "A page to browse the API documentation",
+		Produces: []string{"text/html"}},
+}
+
+// OpenAPIDocument - GET
+// http://localhost:8081/api/v1/openapi.json
+func OpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	if spec == nil {
+		apierror.Write(w, http.StatusInternalServerError, "OpenAPI document is not built")
+		return
+	}
+	data, err := spec.JSON()
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "Failed to write OpenAPI document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// APIDocs - GET
+// Browsable documentation, read from openapi.json
+// http://localhost:8081/api/v1/docs
+func APIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.DocsPage)
+}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"flood-relief-system/backend/apierror"
 	"flood-relief-system/backend/controllers"
+	"flood-relief-system/backend/openapi"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -19,6 +22,10 @@ func SetupRoutes() *mux.Router {
 	// frontend eken connection ek check karanne meken
 	api.HandleFunc("/health", HealthCheck).Methods("GET")
 
+	// API documentation: the OpenAPI document and a page to read it
+	api.HandleFunc("/openapi.json", OpenAPIDocument).Methods("GET")
+	api.HandleFunc("/docs", APIDocs).Methods("GET")
+
 	// // Help Requests Routes
 
 	// CREATE - Add new help request
@@ -27,6 +34,9 @@ func SetupRoutes() *mux.Router {
 	// READ - Get all help requests
 	api.HandleFunc("/help-requests", controllers.GetAllHelpRequests).Methods("GET")
 
+	// BULK IMPORT - CSV or XLSX (registered before /{id} so it is not read as an ID)
+	api.HandleFunc("/help-requests/import", controllers.ImportHelpRequests).Methods("POST")
+
 	// READ ONE - Get single help request by ID
 	api.HandleFunc("/help-requests/{id}", controllers.GetHelpRequestByID).Methods("GET")
 
@@ -41,6 +51,7 @@ func SetupRoutes() *mux.Router {
 	// CREATE - Register new volunteer
 	api.HandleFunc("/volunteers", controllers.CreateVolunteer).Methods("POST")
 	api.HandleFunc("/volunteers", controllers.GetAllVolunteers).Methods("GET")
+	api.HandleFunc("/volunteers/import", controllers.ImportVolunteers).Methods("POST")
 	api.HandleFunc("/volunteers/{id}", controllers.GetVolunteerByID).Methods("GET")
 	api.HandleFunc("/volunteers/{id}", controllers.UpdateVolunteer).Methods("PUT")
 	api.HandleFunc("/volunteers/{id}", controllers.DeleteVolunteer).Methods("DELETE")
@@ -49,12 +60,117 @@ func SetupRoutes() *mux.Router {
 	// CREATE - Relief Supplies
 	api.HandleFunc("/relief-supplies", controllers.CreateReliefSupply).Methods("POST")
 	api.HandleFunc("/relief-supplies", controllers.GetAllReliefSupplies).Methods("GET")
+	// Registered before /relief-supplies/{id} so they are not read as an ID
+	api.HandleFunc("/relief-supplies/expiring", controllers.GetExpiringSupplies).Methods("GET")
+	api.HandleFunc("/relief-supplies/expire", controllers.RunExpiryCheck).Methods("POST")
+	api.HandleFunc("/relief-supplies/labels", controllers.GetSupplyLabelSheet).Methods("GET")
+	api.HandleFunc("/relief-supplies/import", controllers.ImportReliefSupplies).Methods("POST")
 	api.HandleFunc("/relief-supplies/{id}", controllers.GetReliefSupplyById).Methods("GET")
 	api.HandleFunc("/relief-supplies/{id}", controllers.UpdateReliefSupply).Methods("PUT")
 	api.HandleFunc("/relief-supplies/{id}", controllers.DeleteReliefSupply).Methods("DELETE")
 	// Bonus: Get only available supplies
 	api.HandleFunc("/relief-supplies/category/{category}", controllers.GetSuppliesByCategory).Methods("GET")
 	api.HandleFunc("/relief-supplies/available/{available}", controllers.GetAvailableSupplies).Methods("GET")
+	// Stock ledger
+	api.HandleFunc("/relief-supplies/{id}/movements", controllers.CreateStockMovement).Methods("POST")
+	api.HandleFunc("/relief-supplies/{id}/movements", controllers.GetStockMovements).Methods("GET")
+	api.HandleFunc("/relief-supplies/{id}/balance", controllers.GetSupplyBalance).Methods("GET")
+	api.HandleFunc("/relief-supplies/{id}/availability", controllers.GetSupplyAvailability).Methods("GET")
+	api.HandleFunc("/relief-supplies/{id}/label", controllers.GetSupplyLabel).Methods("GET")
+
+	// Label Scan Routes
+	api.HandleFunc("/scan/{code}", controllers.ScanLabel).Methods("GET")
+	api.HandleFunc("/scan/{code}/issue", controllers.ScanIssue).Methods("POST")
+	api.HandleFunc("/scan/{code}/receive", controllers.ScanReceive).Methods("POST")
+	api.HandleFunc("/scan/{code}/transfer", controllers.ScanTransfer).Methods("POST")
+
+	// Supply Allocation Routes
+	api.HandleFunc("/allocations", controllers.CreateSupplyAllocation).Methods("POST")
+	api.HandleFunc("/allocations", controllers.GetAllSupplyAllocations).Methods("GET")
+	// First-expiry-first-out lot suggestions and reservations
+	api.HandleFunc("/allocations/suggest", controllers.SuggestAllocationLots).Methods("GET")
+	api.HandleFunc("/allocations/fefo", controllers.CreateFEFOAllocations).Methods("POST")
+	api.HandleFunc("/allocations/{id}", controllers.GetSupplyAllocationByID).Methods("GET")
+	api.HandleFunc("/allocations/{id}/pick", controllers.PickSupplyAllocation).Methods("POST")
+	api.HandleFunc("/allocations/{id}/deliver", controllers.DeliverSupplyAllocation).Methods("POST")
+	api.HandleFunc("/allocations/{id}/cancel", controllers.CancelSupplyAllocation).Methods("POST")
+
+	// Unit of Measure Routes
+	api.HandleFunc("/units", controllers.CreateUnit).Methods("POST")
+	api.HandleFunc("/units", controllers.GetUnits).Methods("GET")
+	api.HandleFunc("/units/convert", controllers.ConvertQuantity).Methods("GET")
+	api.HandleFunc("/units/{id}", controllers.UpdateUnit).Methods("PUT")
+	api.HandleFunc("/units/{id}", controllers.DeleteUnit).Methods("DELETE")
+	api.HandleFunc("/unit-conversions", controllers.CreateUnitConversion).Methods("POST")
+	api.HandleFunc("/unit-conversions", controllers.GetUnitConversions).Methods("GET")
+	api.HandleFunc("/unit-conversions/{id}", controllers.DeleteUnitConversion).Methods("DELETE")
+	api.HandleFunc("/category-units", controllers.GetCategoryUnits).Methods("GET")
+	api.HandleFunc("/category-units/{category}", controllers.SetCategoryUnit).Methods("PUT")
+
+	// Donor Routes
+	api.HandleFunc("/donors", controllers.CreateDonor).Methods("POST")
+	api.HandleFunc("/donors", controllers.GetAllDonors).Methods("GET")
+	api.HandleFunc("/donors/{id}", controllers.GetDonorByID).Methods("GET")
+	api.HandleFunc("/donors/{id}", controllers.UpdateDonor).Methods("PUT")
+	api.HandleFunc("/donors/{id}", controllers.DeleteDonor).Methods("DELETE")
+	api.HandleFunc("/donors/{id}/donations", controllers.GetDonorDonations).Methods("GET")
+	api.HandleFunc("/donors/{id}/receipts", controllers.CreateDonationReceipt).Methods("POST")
+	api.HandleFunc("/donors/{id}/receipts", controllers.GetDonorReceipts).Methods("GET")
+	api.HandleFunc("/receipts/{id}", controllers.GetDonationReceipt).Methods("GET")
+
+	// Relief Kit Routes
+	api.HandleFunc("/kits", controllers.CreateKitTemplate).Methods("POST")
+	api.HandleFunc("/kits", controllers.GetAllKitTemplates).Methods("GET")
+	api.HandleFunc("/kits/{id}", controllers.GetKitTemplateByID).Methods("GET")
+	api.HandleFunc("/kits/{id}", controllers.UpdateKitTemplate).Methods("PUT")
+	api.HandleFunc("/kits/{id}", controllers.DeleteKitTemplate).Methods("DELETE")
+	api.HandleFunc("/kits/{id}/buildable", controllers.GetBuildableKits).Methods("GET")
+	api.HandleFunc("/kits/{id}/assemble", controllers.AssembleKits).Methods("POST")
+	api.HandleFunc("/kits/{id}/assemblies", controllers.GetKitAssemblies).Methods("GET")
+
+	// Household and Distribution Routes
+	api.HandleFunc("/households", controllers.CreateHousehold).Methods("POST")
+	api.HandleFunc("/households", controllers.GetAllHouseholds).Methods("GET")
+	api.HandleFunc("/households/{id}", controllers.GetHouseholdByID).Methods("GET")
+	api.HandleFunc("/households/{id}", controllers.UpdateHousehold).Methods("PUT")
+	api.HandleFunc("/households/{id}", controllers.DeleteHousehold).Methods("DELETE")
+	api.HandleFunc("/households/{id}/distributions", controllers.GetHouseholdDistributions).Methods("GET")
+	api.HandleFunc("/households/{id}/entitlements", controllers.GetHouseholdEntitlements).Methods("GET")
+	api.HandleFunc("/distribution-events", controllers.CreateDistributionEvent).Methods("POST")
+	api.HandleFunc("/distribution-events", controllers.GetAllDistributionEvents).Methods("GET")
+	api.HandleFunc("/distribution-events/{id}", controllers.GetDistributionEventByID).Methods("GET")
+	api.HandleFunc("/distribution-events/{id}/close", controllers.CloseDistributionEvent).Methods("POST")
+	api.HandleFunc("/distribution-events/{id}/distributions", controllers.CreateDistribution).Methods("POST")
+	api.HandleFunc("/distribution-events/{id}/distributions", controllers.GetEventDistributions).Methods("GET")
+	api.HandleFunc("/entitlement-rules", controllers.CreateEntitlementRule).Methods("POST")
+	api.HandleFunc("/entitlement-rules", controllers.GetEntitlementRules).Methods("GET")
+	api.HandleFunc("/entitlement-rules/{id}", controllers.UpdateEntitlementRule).Methods("PUT")
+	api.HandleFunc("/entitlement-rules/{id}", controllers.DeleteEntitlementRule).Methods("DELETE")
+
+	// Warehouse Routes
+	api.HandleFunc("/warehouses", controllers.CreateWarehouse).Methods("POST")
+	api.HandleFunc("/warehouses", controllers.GetAllWarehouses).Methods("GET")
+	api.HandleFunc("/warehouses/{id}", controllers.GetWarehouseByID).Methods("GET")
+	api.HandleFunc("/warehouses/{id}", controllers.UpdateWarehouse).Methods("PUT")
+	api.HandleFunc("/warehouses/{id}", controllers.DeleteWarehouse).Methods("DELETE")
+	api.HandleFunc("/warehouses/{id}/stock", controllers.GetWarehouseStock).Methods("GET")
+	// Stock summary: ?by=warehouse, category or item
+	api.HandleFunc("/stock/summary", controllers.GetStockSummary).Methods("GET")
+	api.HandleFunc("/stock/forecast", controllers.GetStockForecast).Methods("GET")
+	api.HandleFunc("/stock/shortages", controllers.GetShortageReport).Methods("GET")
+	api.HandleFunc("/reorder-thresholds", controllers.CreateReorderThreshold).Methods("POST")
+	api.HandleFunc("/reorder-thresholds", controllers.GetReorderThresholds).Methods("GET")
+	api.HandleFunc("/reorder-thresholds/{id}", controllers.UpdateReorderThreshold).Methods("PUT")
+	api.HandleFunc("/reorder-thresholds/{id}", controllers.DeleteReorderThreshold).Methods("DELETE")
+
+	// Transfer Order Routes
+	api.HandleFunc("/transfer-orders", controllers.CreateTransferOrder).Methods("POST")
+	api.HandleFunc("/transfer-orders", controllers.GetAllTransferOrders).Methods("GET")
+	api.HandleFunc("/transfer-orders/{id}", controllers.GetTransferOrderByID).Methods("GET")
+	api.HandleFunc("/transfer-orders/{id}/dispatch", controllers.DispatchTransferOrder).Methods("POST")
+	api.HandleFunc("/transfer-orders/{id}/in-transit", controllers.MarkTransferInTransit).Methods("POST")
+	api.HandleFunc("/transfer-orders/{id}/receive", controllers.ReceiveTransferOrder).Methods("POST")
+	api.HandleFunc("/transfer-orders/{id}/cancel", controllers.CancelTransferOrder).Methods("POST")
 
 	// Rescue Operations Routes
 	//CREATE - Rescue Operation
@@ -67,17 +183,118 @@ func SetupRoutes() *mux.Router {
 	api.HandleFunc("/rescue-operations/status/active", controllers.GetActiveOperations).Methods("GET")
 	api.HandleFunc("/rescue-operations/priority/{priority}", controllers.GetOperationsByPriority).Methods("GET")
 
+	// Evacuee Routes
+	api.HandleFunc("/rescue-operations/{id}/evacuees", controllers.CreateEvacuee).Methods("POST")
+	api.HandleFunc("/rescue-operations/{id}/evacuees", controllers.GetOperationEvacuees).Methods("GET")
+	api.HandleFunc("/help-requests/{id}/evacuees", controllers.GetHelpRequestEvacuees).Methods("GET")
+	// Search by ?name= and ?phone=
+	api.HandleFunc("/evacuees", controllers.SearchEvacuees).Methods("GET")
+	api.HandleFunc("/evacuees/{id}", controllers.GetEvacueeByID).Methods("GET")
+	api.HandleFunc("/evacuees/{id}", controllers.UpdateEvacuee).Methods("PUT")
+	api.HandleFunc("/evacuees/{id}", controllers.DeleteEvacuee).Methods("DELETE")
+
+	// Shelter Routes
+	api.HandleFunc("/shelters", controllers.CreateShelter).Methods("POST")
+	api.HandleFunc("/shelters", controllers.GetAllShelters).Methods("GET")
+	// Registered before /shelters/{id} so they are not read as an ID
+	api.HandleFunc("/shelters/available", controllers.GetAvailableShelters).Methods("GET")
+	api.HandleFunc("/shelters/alerts/near-capacity", controllers.GetNearCapacityShelters).Methods("GET")
+	api.HandleFunc("/shelters/{id}", controllers.GetShelterByID).Methods("GET")
+	api.HandleFunc("/shelters/{id}", controllers.UpdateShelter).Methods("PUT")
+	api.HandleFunc("/shelters/{id}", controllers.DeleteShelter).Methods("DELETE")
+	api.HandleFunc("/shelters/{id}/evacuees", controllers.GetShelterEvacuees).Methods("GET")
+	api.HandleFunc("/shelters/{id}/check-in", controllers.CheckInEvacuee).Methods("POST")
+	api.HandleFunc("/shelters/{id}/check-out", controllers.CheckOutEvacuee).Methods("POST")
+
+	// Missing Persons Routes
+	api.HandleFunc("/missing-persons", controllers.CreateMissingPersonReport).Methods("POST")
+	api.HandleFunc("/missing-persons", controllers.GetAllMissingPersonReports).Methods("GET")
+	// Review queue of candidate matches
+	api.HandleFunc("/missing-persons/matches/review", controllers.GetMatchReviewQueue).Methods("GET")
+	api.HandleFunc("/missing-persons/matches/{id}/confirm", controllers.ConfirmMatch).Methods("POST")
+	api.HandleFunc("/missing-persons/matches/{id}/reject", controllers.RejectMatch).Methods("POST")
+	api.HandleFunc("/missing-persons/{id}", controllers.GetMissingPersonReportByID).Methods("GET")
+	api.HandleFunc("/missing-persons/{id}", controllers.UpdateMissingPersonReport).Methods("PUT")
+	api.HandleFunc("/missing-persons/{id}", controllers.DeleteMissingPersonReport).Methods("DELETE")
+	api.HandleFunc("/missing-persons/{id}/match", controllers.RunMissingPersonMatching).Methods("POST")
+	api.HandleFunc("/missing-persons/{id}/matches", controllers.GetReportMatches).Methods("GET")
+
 	// Emergency Contacts Routes
 	// CREATE - Emergency Contact
 	api.HandleFunc("/emergency-contacts", controllers.CreateEmergencyContact).Methods("POST")
 	api.HandleFunc("/emergency-contacts", controllers.GetAllEmergencyContacts).Methods("GET")
+	// Registered before /emergency-contacts/{id} so they are not read as an ID
+	api.HandleFunc("/emergency-contacts/on-call", controllers.GetOnCall).Methods("GET")
+	api.HandleFunc("/emergency-contacts/verification-due", controllers.GetVerificationDueContacts).Methods("GET")
+	api.HandleFunc("/emergency-contacts/export", controllers.ExportEmergencyContacts).Methods("GET")
+	api.HandleFunc("/emergency-contacts/import", controllers.ImportEmergencyContacts).Methods("POST")
 	api.HandleFunc("/emergency-contacts/{id}", controllers.GetEmergencyContactByID).Methods("GET")
 	api.HandleFunc("/emergency-contacts/{id}", controllers.UpdateEmergencyContact).Methods("PUT")
 	api.HandleFunc("/emergency-contacts/{id}", controllers.DeleteEmergencyContact).Methods("DELETE")
+	api.HandleFunc("/emergency-contacts/{id}/verify", controllers.VerifyEmergencyContact).Methods("POST")
+	api.HandleFunc("/emergency-contacts/{id}/unreachable", controllers.MarkContactUnreachable).Methods("POST")
+	api.HandleFunc("/emergency-contacts/{id}/vcard", controllers.GetEmergencyContactVCard).Methods("GET")
 	// Bonus: Get only active contacts
 	api.HandleFunc("/emergency-contacts/status/active", controllers.GetActiveContacts).Methods("GET")
 	api.HandleFunc("/emergency-contacts/service/{service_type}", controllers.GetContactsByServiceType).Methods("GET")
 
+	// On-call and Escalation Routes
+	api.HandleFunc("/on-call-shifts", controllers.CreateOnCallShift).Methods("POST")
+	api.HandleFunc("/on-call-shifts", controllers.GetOnCallShifts).Methods("GET")
+	api.HandleFunc("/on-call-shifts/{id}", controllers.UpdateOnCallShift).Methods("PUT")
+	api.HandleFunc("/on-call-shifts/{id}", controllers.DeleteOnCallShift).Methods("DELETE")
+	api.HandleFunc("/escalation-chains", controllers.CreateEscalationChain).Methods("POST")
+	api.HandleFunc("/escalation-chains", controllers.GetEscalationChains).Methods("GET")
+	api.HandleFunc("/escalation-chains/{id}", controllers.GetEscalationChainByID).Methods("GET")
+	api.HandleFunc("/escalation-chains/{id}", controllers.UpdateEscalationChain).Methods("PUT")
+	api.HandleFunc("/escalation-chains/{id}", controllers.DeleteEscalationChain).Methods("DELETE")
+	api.HandleFunc("/escalation-chains/{id}/trigger", controllers.TriggerEscalation).Methods("POST")
+	api.HandleFunc("/escalations", controllers.GetEscalations).Methods("GET")
+	api.HandleFunc("/escalations/{id}/acknowledge", controllers.AcknowledgeEscalation).Methods("POST")
+	api.HandleFunc("/escalations/{id}/cancel", controllers.CancelEscalation).Methods("POST")
+
+	// Change Stream (Server-Sent Events, or WebSocket on upgrade)
+	api.HandleFunc("/events", controllers.StreamEvents).Methods("GET")
+
+	// Webhook Routes
+	api.HandleFunc("/webhooks", controllers.CreateWebhook).Methods("POST")
+	api.HandleFunc("/webhooks", controllers.GetWebhooks).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", controllers.GetWebhookByID).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", controllers.UpdateWebhook).Methods("PUT")
+	api.HandleFunc("/webhooks/{id}", controllers.DeleteWebhook).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id}/rotate-secret", controllers.RotateWebhookSecret).Methods("POST")
+	api.HandleFunc("/webhooks/{id}/deliveries", controllers.GetWebhookDeliveries).Methods("GET")
+	api.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}", controllers.GetWebhookDelivery).Methods("GET")
+	api.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}/redeliver", controllers.RedeliverWebhook).Methods("POST")
+
+	// Dashboard Statistics Routes
+	api.HandleFunc("/stats", controllers.GetStats).Methods("GET")
+	api.HandleFunc("/stats/series/{metric}", controllers.GetStatsSeries).Methods("GET")
+
+	// Situation Report Routes
+	api.HandleFunc("/sitreps", controllers.CreateSitRep).Methods("POST")
+	api.HandleFunc("/sitreps", controllers.GetSitReps).Methods("GET")
+	api.HandleFunc("/sitreps/preview", controllers.PreviewSitRep).Methods("GET")
+	api.HandleFunc("/sitreps/{id}", controllers.GetSitRep).Methods("GET")
+	api.HandleFunc("/sitrep-templates/{format}", controllers.GetSitRepTemplate).Methods("GET")
+	api.HandleFunc("/sitrep-templates/{format}", controllers.UpdateSitRepTemplate).Methods("PUT")
+	api.HandleFunc("/sitrep-templates/{format}", controllers.ResetSitRepTemplate).Methods("DELETE")
+
+	// Describe every route above and check requests against the description
+	spec, specErr = openapi.Build(router, "/api/v1", apiInfo, append(routeOperations, controllers.Operations...))
+	if specErr != nil {
+		log.Printf("⚠️  OpenAPI document does not match the routes:\n%s", specErr)
+	}
+	api.Use(spec.Validator(openapi.ValidationMode()))
+
+	// Unknown paths and methods answer in the same error shape as handlers
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, http.StatusNotFound, "No route for "+r.URL.Path)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
+
 	return router
 }
 
@@ -0,0 +1,30 @@
+// Package sequence numbers printed documents without gaps.
+package sequence
+
+import (
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Next returns the next number in the named series. Call it inside the
+// transaction that stores the document: the counter row stays locked until
+// commit and a rollback gives the number back, so numbers have no gaps.
+func Next(tx *gorm.DB, name string) (int, error) {
+	seq := models.DocumentSequence{Name: name}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&seq, "name = ?", name).Error; err != nil {
+		return 0, err
+	}
+
+	seq.Last++
+	if err := tx.Model(&seq).Update("last", seq.Last).Error; err != nil {
+		return 0, err
+	}
+
+	return seq.Last, nil
+}
@@ -0,0 +1,112 @@
+package sitrep
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDF lays out rendered Markdown as an A4 document. It understands the
+// Markdown the templates use: headings, bullet lists, tables and
+// paragraphs; other markup is printed as it is. SITREP_FONT may name a
+// TrueType font for text outside Latin-1.
+func PDF(w io.Writer, markdown string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+
+	family := "Helvetica"
+	text := func(s string) string { return s }
+	if path := os.Getenv("SITREP_FONT"); path != "" {
+		font, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading SitRep font: %w", err)
+		}
+		family = "sitrep"
+		pdf.AddUTF8FontFromBytes(family, "", font)
+	} else {
+		text = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(family, "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	usable := width - left - right
+
+	var table [][]string
+	flushTable := func() {
+		if len(table) == 0 {
+			return
+		}
+		columns := len(table[0])
+		pdf.SetFont(family, "", 9)
+		for i, row := range table {
+			fill := i == 0
+			pdf.SetFillColor(235, 235, 235)
+			for c := 0; c < columns; c++ {
+				cell := ""
+				if c < len(row) {
+					cell = row[c]
+				}
+				pdf.CellFormat(usable/float64(columns), 6, text(cell), "1", 0, "L", fill, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(3)
+		table = nil
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimRight(line, " \r")
+
+		if strings.HasPrefix(line, "|") {
+			cells := strings.Split(strings.Trim(line, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			// Skip the | --- | row under the header
+			if strings.Trim(strings.Join(cells, ""), "-: ") == "" {
+				continue
+			}
+			table = append(table, cells)
+			continue
+		}
+		flushTable()
+
+		plain := strings.ReplaceAll(line, "**", "")
+		switch {
+		case line == "":
+			pdf.Ln(2)
+		case strings.HasPrefix(line, "### "):
+			pdf.SetFont(family, "", 11)
+			pdf.MultiCell(0, 6, text(plain[4:]), "", "L", false)
+		case strings.HasPrefix(line, "## "):
+			pdf.Ln(2)
+			pdf.SetFont(family, "", 13)
+			pdf.MultiCell(0, 7, text(plain[3:]), "", "L", false)
+		case strings.HasPrefix(line, "# "):
+			pdf.SetFont(family, "", 17)
+			pdf.MultiCell(0, 9, text(plain[2:]), "", "L", false)
+		case strings.HasPrefix(line, "- "):
+			pdf.SetFont(family, "", 10)
+			pdf.SetX(left + 4)
+			pdf.MultiCell(0, 5, text("- "+plain[2:]), "", "L", false)
+		default:
+			pdf.SetFont(family, "", 10)
+			pdf.MultiCell(0, 5, text(plain), "", "L", false)
+		}
+	}
+	flushTable()
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,167 @@
+// Package sitrep renders situation reports from editable templates: the
+// Markdown and HTML templates are Go templates over a Report, and the PDF is
+// laid out from the rendered Markdown.
+package sitrep
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"text/template"
+	"time"
+
+	"flood-relief-system/backend/forecast"
+)
+
+// Template formats
+const (
+	Markdown = "md"
+	HTML     = "html"
+)
+
+// ErrFormat is returned for template formats other than md and html
+var ErrFormat = errors.New("unknown SitRep template format")
+
+// Count is one row of a breakdown such as help requests by status
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// HelpRequests summarizes help requests. New were created in the period;
+// Closed were completed in it; Open is everything not completed at the end.
+type HelpRequests struct {
+	ByStatus   []Count `json:"by_status"`
+	ByPriority []Count `json:"by_priority"` // open requests only
+	New        int     `json:"new"`
+	Closed     int     `json:"closed"`
+	Open       int     `json:"open"`
+}
+
+// Rescues summarizes rescue operations in the period
+type Rescues struct {
+	Started       int `json:"started"`
+	Completed     int `json:"completed"`
+	Failed        int `json:"failed"`
+	Active        int `json:"active"` // still running at the end of the period
+	PeopleRescued int `json:"people_rescued"`
+}
+
+// Volunteers counts registered volunteers and those on a rescue operation
+// during the period
+type Volunteers struct {
+	Active int `json:"active"`
+	OnDuty int `json:"on_duty"`
+}
+
+// StockLevel is stock on hand for a category in its canonical unit
+type StockLevel struct {
+	Category string  `json:"category"`
+	Unit     string  `json:"unit"`
+	Quantity float64 `json:"quantity"`
+	Lots     int     `json:"lots"`
+}
+
+// Report is everything a SitRep shows. It is archived as JSON so an old
+// SitRep can be rendered again as it was.
+type Report struct {
+	From         time.Time                   `json:"from"`
+	To           time.Time                   `json:"to"`
+	GeneratedAt  time.Time                   `json:"generated_at"`
+	TimeZone     string                      `json:"time_zone"`
+	HelpRequests HelpRequests                `json:"help_requests"`
+	Rescues      Rescues                     `json:"rescues"`
+	Volunteers   Volunteers                  `json:"volunteers"`
+	Stock        []StockLevel                `json:"stock"`
+	Shortages    []forecast.CategoryShortage `json:"shortages"`
+}
+
+//go:embed templates/sitrep.md.tmpl
+var defaultMarkdown string
+
+//go:embed templates/sitrep.html.tmpl
+var defaultHTML string
+
+// DefaultTemplate is the built-in template for a format
+func DefaultTemplate(format string) (string, error) {
+	switch format {
+	case Markdown:
+		return defaultMarkdown, nil
+	case HTML:
+		return defaultHTML, nil
+	}
+	return "", ErrFormat
+}
+
+// funcs are the helpers templates can use. Times are shown in the report's
+// time zone.
+func funcs(report *Report) map[string]interface{} {
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return map[string]interface{}{
+		"date":     func(t time.Time) string { return t.In(loc).Format("2 Jan 2006") },
+		"datetime": func(t time.Time) string { return t.In(loc).Format("2 Jan 2006 15:04") },
+		"number":   func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+		"cover": func(days *float64) string {
+			if days == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f days", *days)
+		},
+	}
+}
+
+// Render writes the report through a Markdown or HTML template. HTML
+// templates escape the report's text; Markdown templates do not.
+func Render(w io.Writer, format, body string, report *Report) error {
+	switch format {
+	case Markdown:
+		t, err := template.New("sitrep").Funcs(funcs(report)).Parse(body)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, report)
+	case HTML:
+		t, err := htmltemplate.New("sitrep").Funcs(funcs(report)).Parse(body)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, report)
+	}
+	return ErrFormat
+}
+
+// Check renders a template against a sample report, so a broken edit is
+// refused instead of breaking the next SitRep
+func Check(format, body string) error {
+	cover := 1.5
+	sample := &Report{
+		From:        time.Now().Add(-24 * time.Hour),
+		To:          time.Now(),
+		GeneratedAt: time.Now(),
+		TimeZone:    "UTC",
+		HelpRequests: HelpRequests{
+			ByStatus:   []Count{{Key: "pending", Count: 1}},
+			ByPriority: []Count{{Key: "high", Count: 1}},
+		},
+		Stock: []StockLevel{{Category: "Food", Unit: "kg", Quantity: 10, Lots: 1}},
+		Shortages: []forecast.CategoryShortage{{
+			Category: "Food", Status: forecast.LowCover, MinDaysOfCover: &cover,
+			Items: []forecast.Forecast{{ItemName: "Rice", Unit: "kg", DaysOfCover: &cover, Status: forecast.LowCover}},
+		}},
+	}
+	return Render(io.Discard, format, body, sample)
+}
+
+// RenderString is Render into a string
+func RenderString(format, body string, report *Report) (string, error) {
+	var out bytes.Buffer
+	err := Render(&out, format, body, report)
+	return out.String(), err
+}
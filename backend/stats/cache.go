@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CacheTTL is how long a computed aggregate is served before it is worked
+// out again: STATS_CACHE_SECONDS (default 30, 0 turns caching off)
+func CacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("STATS_CACHE_SECONDS"))
+	if err != nil || seconds < 0 {
+		seconds = 30
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+var errComputePanicked = errors.New("computing the aggregate failed")
+
+// entry is one cached value. ready is closed once value and err are set, so
+// requests that arrive while it is being computed wait for it instead of
+// running the same queries again.
+type entry struct {
+	ready   chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// Cache keeps aggregates for a short time, keyed by the request that asked
+// for them. Only this server's copy is cached; each instance works figures
+// out on its own.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewCache returns an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: map[string]*entry{}}
+}
+
+// Get returns the cached value for key, or computes it with compute and
+// keeps it for ttl. Errors are not kept.
+func (c *Cache) Get(key string, ttl time.Duration, compute func() (interface{}, error)) (interface{}, error) {
+	if ttl <= 0 {
+		return compute()
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && (e.expires.IsZero() || now.Before(e.expires)) {
+		c.mu.Unlock()
+		<-e.ready
+		return e.value, e.err
+	}
+
+	// Drop expired entries while the lock is held anyway
+	for k, old := range c.entries {
+		if !old.expires.IsZero() && now.After(old.expires) {
+			delete(c.entries, k)
+		}
+	}
+	e = &entry{ready: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	// Settle the entry even if compute panics, so waiters are not left hanging
+	computed := false
+	defer func() {
+		if !computed {
+			e.err = errComputePanicked
+		}
+		c.mu.Lock()
+		if e.err != nil {
+			delete(c.entries, key)
+		} else {
+			e.expires = time.Now().Add(ttl)
+		}
+		c.mu.Unlock()
+		close(e.ready)
+	}()
+	e.value, e.err = compute()
+	computed = true
+
+	return e.value, e.err
+}
@@ -0,0 +1,174 @@
+// Package stats computes dashboard aggregates in the database: record counts
+// grouped by a column and time series in fixed buckets.
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxBuckets caps how many points one series may have
+const MaxBuckets = 2000
+
+var (
+	ErrBucket     = errors.New("bucket must be a number followed by m, h, d or w, or one of minute, hour, day, week")
+	ErrTooMany    = fmt.Errorf("too many buckets, at most %d", MaxBuckets)
+	ErrFilterName = errors.New("unknown filter")
+)
+
+// Count is the number of records with one value of a column
+type Count struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Group counts the rows of table by column, largest first. Empty values are
+// counted under "unspecified". table and column come from the caller, never
+// from the request.
+func Group(db *gorm.DB, table, column string) ([]Count, int64, error) {
+	counts := []Count{}
+	err := db.Table(table).
+		Select("COALESCE(NULLIF(" + column + ", ''), 'unspecified') AS key, COUNT(*) AS count").
+		Group("1").Order("count DESC, key").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	return counts, total, nil
+}
+
+// Metric is a series that can be bucketed: what to aggregate in which table,
+// on which timestamp, which rows always count (Where) and which columns
+// filters may narrow it by
+type Metric struct {
+	Table     string
+	TimeField string
+	Aggregate string
+	Where     string
+	Filters   []string
+}
+
+// Metrics are the series the dashboard can ask for
+var Metrics = map[string]Metric{
+	"help_requests":        {Table: "help_requests", TimeField: "created_at", Aggregate: "COUNT(*)", Filters: []string{"status", "priority"}},
+	"help_requests_closed": {Table: "help_requests", TimeField: "updated_at", Aggregate: "COUNT(*)", Where: "status = 'completed'", Filters: []string{"priority"}},
+	"rescue_operations":    {Table: "rescue_operations", TimeField: "start_time", Aggregate: "COUNT(*)", Filters: []string{"status", "priority"}},
+	"people_rescued":       {Table: "rescue_operations", TimeField: "end_time", Aggregate: "COALESCE(SUM(people_rescued), 0)", Filters: []string{"status", "priority"}},
+	"volunteers":           {Table: "volunteers", TimeField: "created_at", Aggregate: "COUNT(*)", Filters: []string{"status"}},
+	"relief_supplies":      {Table: "relief_supplies", TimeField: "created_at", Aggregate: "COUNT(*)", Filters: []string{"category", "status"}},
+}
+
+// ParseBucket reads a bucket size such as 15m, 1h, 6h, 1d or 1w. The names
+// minute, hour, day and week are the single unit.
+func ParseBucket(value string) (time.Duration, error) {
+	switch value {
+	case "minute":
+		value = "1m"
+	case "hour":
+		value = "1h"
+	case "day":
+		value = "1d"
+	case "week":
+		value = "1w"
+	}
+	if len(value) < 2 {
+		return 0, ErrBucket
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, ErrBucket
+	}
+	unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[value[len(value)-1]]
+	if unit == 0 {
+		return 0, ErrBucket
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Origin is where buckets of the given size start so that they line up with
+// the clock in loc: the hour for buckets under an hour, local midnight for
+// day-sized buckets and the Monday before for weeks
+func Origin(from time.Time, bucket time.Duration, loc *time.Location) time.Time {
+	local := from.In(loc)
+	var origin time.Time
+	switch {
+	case bucket < time.Hour:
+		origin = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	case bucket%(7*24*time.Hour) == 0:
+		origin = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		origin = origin.AddDate(0, 0, -(int(origin.Weekday())+6)%7)
+	default:
+		origin = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	}
+	// Move up to the last bucket boundary at or before from
+	steps := from.Sub(origin) / bucket
+	return origin.Add(steps * bucket)
+}
+
+// Point is the aggregate for the bucket starting at Start
+type Point struct {
+	Start time.Time `json:"start"`
+	Value int64     `json:"value"`
+}
+
+// Series aggregates metric in buckets from origin up to to. Buckets with no
+// rows are included with zero. filters maps a column in metric.Filters to
+// the value it must have.
+func Series(db *gorm.DB, metric Metric, origin, to time.Time, bucket time.Duration, filters map[string]string) ([]Point, error) {
+	n := int((to.Sub(origin) + bucket - 1) / bucket)
+	if n > MaxBuckets {
+		return nil, ErrTooMany
+	}
+
+	query := db.Table(metric.Table).
+		Select(fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%s - ?)) / ?)::bigint AS slot, %s AS value", metric.TimeField, metric.Aggregate),
+			origin, bucket.Seconds()).
+		Where(metric.TimeField+" >= ? AND "+metric.TimeField+" < ?", origin, to)
+	if metric.Where != "" {
+		query = query.Where(metric.Where)
+	}
+	for column, value := range filters {
+		if !contains(metric.Filters, column) {
+			return nil, fmt.Errorf("%w %q", ErrFilterName, column)
+		}
+		query = query.Where(column+" = ?", value)
+	}
+
+	var rows []struct {
+		Slot  int
+		Value int64
+	}
+	if err := query.Group("slot").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, n)
+	for i := range points {
+		points[i].Start = origin.Add(time.Duration(i) * bucket)
+	}
+	for _, row := range rows {
+		if row.Slot >= 0 && row.Slot < n {
+			points[row.Slot].Value = row.Value
+		}
+	}
+	return points, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
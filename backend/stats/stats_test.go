@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBucket(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"15m", 15 * time.Minute, false},
+		{"1h", time.Hour, false},
+		{"6h", 6 * time.Hour, false},
+		{"1d", 24 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{"minute", time.Minute, false},
+		{"hour", time.Hour, false},
+		{"day", 24 * time.Hour, false},
+		{"week", 7 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"h", 0, true},
+		{"0h", 0, true},
+		{"-1h", 0, true},
+		{"1y", 0, true},
+		{"1.5h", 0, true},
+		{"month", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBucket(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBucket(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBucket(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Languages are the languages export headers are written in
+var Languages = []string{"en", "si", "ta"}
+
+// IsLanguage reports whether headers can be written in lang
+func IsLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Location is the time zone dates are exported in, from APP_TIMEZONE
+// (default Asia/Colombo)
+func Location() *time.Location {
+	name := os.Getenv("APP_TIMEZONE")
+	if name == "" {
+		name = "Asia/Colombo"
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	// Without tzdata on the host, Sri Lanka is still a fixed +05:30
+	return time.FixedZone("+0530", 5*3600+1800)
+}
+
+// Column is one exported field of a model, named by its JSON name
+type Column struct {
+	Name  string
+	index []int
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ColumnsOf lists the plain fields of a model struct in declaration order.
+// Associations (other structs and slices) are left out; they have their own
+// endpoints.
+func ColumnsOf(model reflect.Type) []Column {
+	var columns []Column
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		kind := field.Type
+		if kind.Kind() == reflect.Ptr {
+			kind = kind.Elem()
+		}
+		if field.Anonymous && kind.Kind() == reflect.Struct {
+			for _, c := range ColumnsOf(kind) {
+				columns = append(columns, Column{Name: c.Name, index: append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		if kind == timeType || (kind.Kind() != reflect.Struct && kind.Kind() != reflect.Slice && kind.Kind() != reflect.Map) {
+			if name == "" {
+				name = field.Name
+			}
+			columns = append(columns, Column{Name: name, index: []int{i}})
+		}
+	}
+	return columns
+}
+
+// Cells renders one record for export. Times are written in loc, except
+// dates stored without a time of day (midnight UTC), which are written as
+// plain dates.
+func Cells(record reflect.Value, columns []Column, loc *time.Location) []interface{} {
+	cells := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value, err := record.FieldByIndexErr(column.index)
+		if err != nil {
+			cells[i] = ""
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				cells[i] = ""
+				continue
+			}
+			value = value.Elem()
+		}
+
+		switch v := value.Interface().(type) {
+		case time.Time:
+			switch {
+			case v.IsZero():
+				cells[i] = ""
+			case v.UTC().Equal(v.UTC().Truncate(24 * time.Hour)):
+				cells[i] = v.UTC().Format("2006-01-02")
+			default:
+				cells[i] = v.In(loc).Format("2006-01-02 15:04")
+			}
+		default:
+			cells[i] = v
+		}
+	}
+	return cells
+}
+
+// Writer writes exported rows as a spreadsheet
+type Writer interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// NewWriter starts a "csv" or "xlsx" export to w
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case "csv":
+		// The byte order mark makes Excel read the Sinhala and Tamil headers as UTF-8
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+		return &csvWriter{out: csv.NewWriter(w)}, nil
+	case "xlsx":
+		book := excelize.NewFile()
+		stream, err := book.NewStreamWriter("Sheet1")
+		if err != nil {
+			book.Close()
+			return nil, err
+		}
+		return &xlsxWriter{w: w, book: book, stream: stream}, nil
+	}
+	return nil, ErrFormat
+}
+
+// csvWriter flushes every few hundred rows so the download starts straight away
+type csvWriter struct {
+	out  *csv.Writer
+	rows int
+}
+
+// formulaPrefixes start text that a spreadsheet opening the CSV would run
+// as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+func (c *csvWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = fmt.Sprint(cell)
+
+		// Quote text such as "=HYPERLINK(...)" typed into a request so it
+		// stays text; numbers are written as they are
+		s := record[i]
+		if reflect.ValueOf(cell).Kind() == reflect.String && s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+			record[i] = "'" + s
+		}
+	}
+	if err := c.out.Write(record); err != nil {
+		return err
+	}
+	if c.rows++; c.rows%500 == 0 {
+		c.out.Flush()
+	}
+	return c.out.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.out.Flush()
+	return c.out.Error()
+}
+
+// xlsxWriter streams rows to a temporary file rather than holding the sheet
+// in memory; the workbook is written out on Close
+type xlsxWriter struct {
+	w      io.Writer
+	book   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+}
+
+func (x *xlsxWriter) WriteRow(cells []interface{}) error {
+	x.rows++
+	cell, err := excelize.CoordinatesToCellName(1, x.rows)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, cells)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.book.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.book.Write(x.w)
+}
+
+// Headers are the column headers in lang. Fields without a translation
+// fall back to English, which is made from the JSON name.
+func Headers(columns []Column, lang string) []string {
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		if label, ok := headerTranslations[lang][column.Name]; ok {
+			headers[i] = label
+		} else {
+			headers[i] = englishHeader(column.Name)
+		}
+	}
+	return headers
+}
+
+// englishHeader turns "donor_phone_e164" into "Donor Phone (E.164)"
+func englishHeader(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		switch word {
+		case "id":
+			words[i] = "ID"
+		case "e164":
+			words[i] = "(E.164)"
+		case "at":
+			words[i] = "at"
+		default:
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// headerTranslations are the Sinhala and Tamil headers for the fields most
+// entities share
+var headerTranslations = map[string]map[string]string{
+	"si": {
+		"id":             "අංකය",
+		"name":           "නම",
+		"phone":          "දුරකථන අංකය",
+		"email":          "විද්‍යුත් තැපෑල",
+		"location":       "ස්ථානය",
+		"address":        "ලිපිනය",
+		"district":       "දිස්ත්‍රික්කය",
+		"status":         "තත්ත්වය",
+		"priority":       "ප්‍රමුඛතාවය",
+		"description":    "විස්තරය",
+		"notes":          "සටහන්",
+		"category":       "වර්ගය",
+		"item_name":      "භාණ්ඩය",
+		"quantity":       "ප්‍රමාණය",
+		"unit":           "ඒකකය",
+		"capacity":       "ධාරිතාව",
+		"skills":         "කුසලතා",
+		"age":            "වයස",
+		"expiry_date":    "කල් ඉකුත් වන දිනය",
+		"donor_name":     "පරිත්‍යාගශීලියා",
+		"created_at":     "ඇතුළත් කළ දිනය",
+		"updated_at":     "යාවත්කාලීන කළ දිනය",
+		"contact_person": "සම්බන්ධ කර ගත යුතු පුද්ගලයා",
+	},
+	"ta": {
+		"id":             "இல.",
+		"name":           "பெயர்",
+		"phone":          "தொலைபேசி எண்",
+		"email":          "மின்னஞ்சல்",
+		"location":       "இடம்",
+		"address":        "முகவரி",
+		"district":       "மாவட்டம்",
+		"status":         "நிலை",
+		"priority":       "முன்னுரிமை",
+		"description":    "விவரம்",
+		"notes":          "குறிப்புகள்",
+		"category":       "வகை",
+		"item_name":      "பொருள்",
+		"quantity":       "அளவு",
+		"unit":           "அலகு",
+		"capacity":       "கொள்ளளவு",
+		"skills":         "திறன்கள்",
+		"age":            "வயது",
+		"expiry_date":    "காலாவதி தேதி",
+		"donor_name":     "நன்கொடையாளர்",
+		"created_at":     "பதிவு செய்த தேதி",
+		"updated_at":     "புதுப்பித்த தேதி",
+		"contact_person": "தொடர்பு நபர்",
+	},
+}
@@ -0,0 +1,230 @@
+// Package tabular reads spreadsheets for bulk imports (CSV files and the
+// first sheet of XLSX workbooks) and writes list exports in the same
+// formats. Import columns are matched to fields by a mapping the caller
+// sends, or by name.
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// ErrNoHeader is returned for a file without a header row
+	ErrNoHeader = errors.New("the file has no header row")
+	// ErrFormat is returned for formats other than CSV and XLSX
+	ErrFormat = errors.New("unsupported format")
+)
+
+// Kind is how a cell is converted before it reaches the model
+type Kind int
+
+const (
+	Text Kind = iota
+	Int       // whole number
+	ID        // positive whole number, e.g. warehouse_id
+	Date      // 2006-01-02, RFC 3339 or an Excel date
+)
+
+// Field is a model field that can be imported, named by its JSON name
+type Field struct {
+	Name string
+	Kind Kind
+}
+
+// Sheet is a parsed file. Lines holds the line (CSV) or row (XLSX) each record
+// starts on, for error reports.
+type Sheet struct {
+	Header  []string
+	Records [][]string
+	Lines   []int
+}
+
+// Read parses the file as "csv" or "xlsx". Blank rows are dropped.
+func Read(data []byte, format string) (*Sheet, error) {
+	switch format {
+	case "csv":
+		return readCSV(data)
+	case "xlsx":
+		return readXLSX(data)
+	}
+	return nil, ErrFormat
+}
+
+func readCSV(data []byte) (*Sheet, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	sheet := &Sheet{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		sheet.add(record, line)
+	}
+	return sheet.check()
+}
+
+func readXLSX(data []byte) (*Sheet, error) {
+	book, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	defer book.Close()
+
+	sheets := book.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrNoHeader
+	}
+	rows, err := book.GetRows(sheets[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &Sheet{}
+	for i, row := range rows {
+		sheet.add(row, i+1)
+	}
+	return sheet.check()
+}
+
+// add keeps the first non-blank row as the header and the rest as records
+func (s *Sheet) add(record []string, line int) {
+	blank := true
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return
+	}
+	if s.Header == nil {
+		s.Header = record
+		return
+	}
+	s.Records = append(s.Records, record)
+	s.Lines = append(s.Lines, line)
+}
+
+func (s *Sheet) check() (*Sheet, error) {
+	if s.Header == nil {
+		return nil, ErrNoHeader
+	}
+	return s, nil
+}
+
+// normalize makes "Item Name", "item_name" and "ITEM-NAME" the same header
+func normalize(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(header)
+}
+
+// Columns works out which column feeds each field. The mapping is
+// field → column header; fields it leaves out are matched to a column with
+// the same name. It is an error for the mapping to name an unknown field or a
+// column the file does not have.
+func (s *Sheet) Columns(fields []Field, mapping map[string]string) (map[string]int, error) {
+	positions := map[string]int{}
+	for i, header := range s.Header {
+		if _, seen := positions[normalize(header)]; !seen {
+			positions[normalize(header)] = i
+		}
+	}
+
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.Name] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("mapping names unknown field %q", field)
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range fields {
+		if header, ok := mapping[field.Name]; ok {
+			position, found := positions[normalize(header)]
+			if !found {
+				return nil, fmt.Errorf("mapping for %s names column %q, which is not in the file", field.Name, header)
+			}
+			columns[field.Name] = position
+		} else if position, found := positions[field.Name]; found {
+			columns[field.Name] = position
+		}
+	}
+	return columns, nil
+}
+
+// Values converts one record into field values ready to be marshalled onto
+// a model. Empty cells are left out; cells that cannot be converted are
+// reported as problems.
+func Values(record []string, fields []Field, columns map[string]int) (map[string]interface{}, []string) {
+	values := map[string]interface{}{}
+	var problems []string
+	for _, field := range fields {
+		position, ok := columns[field.Name]
+		if !ok || position >= len(record) {
+			continue
+		}
+		cell := strings.TrimSpace(record[position])
+		if cell == "" {
+			continue
+		}
+
+		value, err := convert(cell, field.Kind)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field.Name, err))
+			continue
+		}
+		values[field.Name] = value
+	}
+	return values, problems
+}
+
+func convert(cell string, kind Kind) (interface{}, error) {
+	switch kind {
+	case Int, ID:
+		// Spreadsheets often store whole numbers as 12.0
+		number, err := strconv.ParseFloat(strings.ReplaceAll(cell, ",", ""), 64)
+		if err != nil || number != float64(int64(number)) {
+			return nil, fmt.Errorf("%q is not a whole number", cell)
+		}
+		if kind == ID && number <= 0 {
+			return nil, fmt.Errorf("%q is not a valid ID", cell)
+		}
+		return int64(number), nil
+	case Date:
+		return parseDate(cell)
+	}
+	return cell, nil
+}
+
+// parseDate accepts ISO dates and the serial numbers XLSX stores dates as
+func parseDate(cell string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "2006/01/02"} {
+		if t, err := time.Parse(layout, cell); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(cell, 64); err == nil && serial > 0 {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date; use YYYY-MM-DD", cell)
+}
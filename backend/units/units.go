@@ -0,0 +1,203 @@
+// Package units normalizes and converts relief supply units using the
+// managed catalogue (models.Unit, models.UnitConversion, models.CategoryUnit).
+package units
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownUnit   = errors.New("unknown unit")
+	ErrNoConversion  = errors.New("no conversion between units")
+	errBadConversion = errors.New("conversion factor must be greater than zero")
+)
+
+// Catalog is an in-memory copy of the unit catalogue for one request
+type Catalog struct {
+	byName        map[string]models.Unit // code and aliases, lower case
+	byCode        map[string]models.Unit
+	conversions   []models.UnitConversion
+	categoryUnits map[string]string
+}
+
+// key is how codes and aliases are compared: trimmed and lower case
+func key(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Load reads the catalogue from the database
+func Load(db *gorm.DB) (*Catalog, error) {
+	var unitList []models.Unit
+	if err := db.Find(&unitList).Error; err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		byName:        map[string]models.Unit{},
+		byCode:        map[string]models.Unit{},
+		categoryUnits: map[string]string{},
+	}
+	for _, u := range unitList {
+		c.byCode[u.Code] = u
+		c.byName[key(u.Code)] = u
+		for _, alias := range strings.Split(u.Aliases, ",") {
+			if k := key(alias); k != "" {
+				c.byName[k] = u
+			}
+		}
+	}
+
+	if err := db.Find(&c.conversions).Error; err != nil {
+		return nil, err
+	}
+
+	var categoryUnits []models.CategoryUnit
+	if err := db.Find(&categoryUnits).Error; err != nil {
+		return nil, err
+	}
+	for _, cu := range categoryUnits {
+		c.categoryUnits[cu.Category] = cu.UnitCode
+	}
+
+	return c, nil
+}
+
+// Normalize returns the catalogue unit for a code or alias such as "Kg"
+func (c *Catalog) Normalize(raw string) (models.Unit, error) {
+	u, ok := c.byName[key(raw)]
+	if !ok {
+		return models.Unit{}, fmt.Errorf("%w %q", ErrUnknownUnit, raw)
+	}
+	return u, nil
+}
+
+// Codes lists every canonical unit code, sorted
+func (c *Catalog) Codes() []string {
+	codes := make([]string, 0, len(c.byCode))
+	for code := range c.byCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// CanonicalUnit is the unit totals for a category are reported in.
+// Categories without a configured unit fall back to "piece".
+func (c *Catalog) CanonicalUnit(category string) string {
+	if code, ok := c.categoryUnits[category]; ok {
+		return code
+	}
+	return "piece"
+}
+
+// direct converts between units of the same dimension
+func (c *Catalog) direct(quantity float64, from, to models.Unit) (float64, bool) {
+	if from.Code == to.Code {
+		return quantity, true
+	}
+	if from.Dimension == to.Dimension && from.Dimension != "package" {
+		return quantity * from.ToBase / to.ToBase, true
+	}
+	return 0, false
+}
+
+// Convert turns a quantity of an item from one unit into another. It tries
+// a dimension conversion first (kg to g), then the item's own conversions in
+// either direction (bottle to L, then L to ml).
+func (c *Catalog) Convert(itemName string, quantity float64, fromCode, toCode string) (float64, error) {
+	from, err := c.Normalize(fromCode)
+	if err != nil {
+		return 0, err
+	}
+	to, err := c.Normalize(toCode)
+	if err != nil {
+		return 0, err
+	}
+
+	if q, ok := c.direct(quantity, from, to); ok {
+		return q, nil
+	}
+
+	for _, conv := range c.conversions {
+		if !strings.EqualFold(conv.ItemName, itemName) || conv.Factor <= 0 {
+			continue
+		}
+
+		convFrom, errFrom := c.Normalize(conv.FromUnit)
+		convTo, errTo := c.Normalize(conv.ToUnit)
+		if errFrom != nil || errTo != nil {
+			continue
+		}
+
+		// from -> conv.From -> conv.To -> to
+		if q, ok := c.direct(quantity, from, convFrom); ok {
+			if q, ok := c.direct(q*conv.Factor, convTo, to); ok {
+				return q, nil
+			}
+		}
+
+		// Same conversion read backwards
+		if q, ok := c.direct(quantity, from, convTo); ok {
+			if q, ok := c.direct(q/conv.Factor, convFrom, to); ok {
+				return q, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s to %s for %q", ErrNoConversion, from.Code, to.Code, itemName)
+}
+
+// ValidateConversion checks an item-specific conversion against the catalogue
+// and stores the canonical unit codes on it
+func (c *Catalog) ValidateConversion(conv *models.UnitConversion) error {
+	if conv.Factor <= 0 {
+		return errBadConversion
+	}
+	from, err := c.Normalize(conv.FromUnit)
+	if err != nil {
+		return err
+	}
+	to, err := c.Normalize(conv.ToUnit)
+	if err != nil {
+		return err
+	}
+
+	conv.FromUnit = from.Code
+	conv.ToUnit = to.Code
+	return nil
+}
+
+// Defaults is the catalogue seeded on first start
+var Defaults = []models.Unit{
+	{Code: "kg", Name: "Kilogram", Dimension: "mass", ToBase: 1, Aliases: "kgs,kilo,kilos,kilogram,kilograms"},
+	{Code: "g", Name: "Gram", Dimension: "mass", ToBase: 0.001, Aliases: "gm,gms,gram,grams"},
+	{Code: "t", Name: "Tonne", Dimension: "mass", ToBase: 1000, Aliases: "ton,tons,tonne,tonnes,mt"},
+	{Code: "L", Name: "Litre", Dimension: "volume", ToBase: 1, Aliases: "l,ltr,ltrs,litre,litres,liter,liters"},
+	{Code: "ml", Name: "Millilitre", Dimension: "volume", ToBase: 0.001, Aliases: "millilitre,millilitres,milliliter,milliliters"},
+	{Code: "piece", Name: "Piece", Dimension: "count", ToBase: 1, Aliases: "pc,pcs,pieces,unit,units,item,items,nos,no"},
+	{Code: "pair", Name: "Pair", Dimension: "count", ToBase: 2, Aliases: "pairs"},
+	{Code: "dozen", Name: "Dozen", Dimension: "count", ToBase: 12, Aliases: "doz,dozens"},
+	{Code: "packet", Name: "Packet", Dimension: "package", ToBase: 1, Aliases: "packets,pack,packs,pkt,pkts"},
+	{Code: "bottle", Name: "Bottle", Dimension: "package", ToBase: 1, Aliases: "bottles,btl"},
+	{Code: "box", Name: "Box", Dimension: "package", ToBase: 1, Aliases: "boxes"},
+	{Code: "bag", Name: "Bag", Dimension: "package", ToBase: 1, Aliases: "bags,sack,sacks"},
+	{Code: "carton", Name: "Carton", Dimension: "package", ToBase: 1, Aliases: "cartons,ctn"},
+	{Code: "can", Name: "Can", Dimension: "package", ToBase: 1, Aliases: "cans,tin,tins"},
+	{Code: "kit", Name: "Kit", Dimension: "package", ToBase: 1, Aliases: "kits"},
+}
+
+// DefaultCategoryUnits are the canonical units seeded on first start
+var DefaultCategoryUnits = []models.CategoryUnit{
+	{Category: "Food", UnitCode: "kg"},
+	{Category: "Medical", UnitCode: "piece"},
+	{Category: "Clothing", UnitCode: "piece"},
+	{Category: "Shelter", UnitCode: "piece"},
+	{Category: "Other", UnitCode: "piece"},
+}
@@ -0,0 +1,220 @@
+// Package validation checks decoded request bodies against validate struct
+// tags, so the rules for a form live on the model next to its fields:
+//
+//	Name     string `json:"name" validate:"required"`
+//	Priority string `json:"priority" validate:"oneof=low medium high critical"`
+//	Quantity int    `json:"quantity" validate:"required,min=1"`
+//
+// Rules are required (not the zero value; a list must not be empty),
+// oneof (space-separated; an empty value is left to required), min and
+// max (a number's bounds, or a list's length) and dive, which checks
+// the rules of a nested struct or of each item of a list. Problems are
+// reported by JSON path, e.g. members[1].name, so clients can mark the
+// form field at fault.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/apierror"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Struct checks every rule, for bodies that create something
+func Struct(v interface{}) []apierror.FieldError {
+	return check(reflect.ValueOf(v), "", true, nil)
+}
+
+// Partial skips required, for update bodies where a zero value means the
+// field was left out. Nested structs and list items that are given are
+// still checked in full.
+func Partial(v interface{}) []apierror.FieldError {
+	return check(reflect.ValueOf(v), "", false, nil)
+}
+
+// Field is one field error, for checks the tags cannot express
+func Field(field, code, message string) apierror.FieldError {
+	return apierror.FieldError{Field: field, Code: code, Message: message}
+}
+
+// Messages lists the messages of field errors, for the per-row problems
+// of bulk imports
+func Messages(fields []apierror.FieldError) []string {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return messages
+}
+
+func check(v reflect.Value, at string, required bool, problems []apierror.FieldError) []apierror.FieldError {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return problems
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return problems
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			value := v.Field(i)
+			if field.Anonymous && name == "" {
+				problems = check(value, at, required, problems)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			path := join(at, name)
+			rules := field.Tag.Get("validate")
+			if rules == "" {
+				continue
+			}
+			problems = apply(value, path, rules, required, problems)
+			if hasRule(rules, "dive") {
+				// Items given in an update are whole, so they are checked in full
+				problems = check(value, path, true, problems)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			problems = check(v.Index(i), fmt.Sprintf("%s[%d]", at, i), required, problems)
+		}
+	}
+	return problems
+}
+
+// apply checks one field's rules, stopping at the first that fails
+func apply(v reflect.Value, path, rules string, required bool, problems []apierror.FieldError) []apierror.FieldError {
+	if !required && isEmpty(v) {
+		// Left out of an update
+		return problems
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		var problem *apierror.FieldError
+		switch name {
+		case "required":
+			if isEmpty(v) {
+				problem = &apierror.FieldError{Field: path, Code: "required", Message: path + " is required"}
+			}
+		case "oneof":
+			problem = oneOf(v, path, strings.Fields(arg))
+		case "min":
+			problem = bound(v, path, arg, false)
+		case "max":
+			problem = bound(v, path, arg, true)
+		}
+		if problem != nil {
+			return append(problems, *problem)
+		}
+	}
+	return problems
+}
+
+func hasRule(rules, rule string) bool {
+	for _, r := range strings.Split(rules, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+func oneOf(v reflect.Value, path string, values []string) *apierror.FieldError {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String || v.String() == "" {
+		return nil
+	}
+	for _, value := range values {
+		if v.String() == value {
+			return nil
+		}
+	}
+	return &apierror.FieldError{
+		Field:   path,
+		Code:    "oneof",
+		Message: path + " must be one of: " + strings.Join(values, ", "),
+	}
+}
+
+// bound checks min (or max with upper) against a number's value or a
+// list's length
+func bound(v reflect.Value, path, arg string, upper bool) *apierror.FieldError {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var n float64
+	list := false
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	case reflect.Slice, reflect.Array:
+		n, list = float64(v.Len()), true
+	default:
+		return nil
+	}
+
+	code, msg := "min", "at least"
+	if upper {
+		code, msg = "max", "at most"
+	}
+	if (!upper && n >= limit) || (upper && n <= limit) {
+		return nil
+	}
+	if list {
+		return &apierror.FieldError{Field: path, Code: code, Message: fmt.Sprintf("%s must have %s %s item(s)", path, msg, arg)}
+	}
+	return &apierror.FieldError{Field: path, Code: code, Message: fmt.Sprintf("%s must be %s %s", path, msg, arg)}
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
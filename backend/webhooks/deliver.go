@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// batchSize is how many deliveries one pass claims
+const batchSize = 20
+
+// claimLease keeps a claimed delivery away from other servers while it is
+// being sent; it is longer than the request timeout
+const claimLease = 2 * time.Minute
+
+// DeliverDue sends the deliveries that are due, in batches, until none are
+// left. Deliveries are claimed with SKIP LOCKED so several servers can run
+// this at once. It returns how many were attempted.
+func DeliverDue(db *gorm.DB, settings Settings) (int, error) {
+	total := 0
+	for {
+		batch, err := claim(db)
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := range batch {
+			wg.Add(1)
+			go func(d *models.WebhookDelivery) {
+				defer wg.Done()
+				attempt(db, d, settings)
+			}(&batch[i])
+		}
+		wg.Wait()
+		total += len(batch)
+	}
+}
+
+// claim takes due deliveries of active subscriptions and pushes their next
+// attempt out by the lease
+func claim(db *gorm.DB) ([]models.WebhookDelivery, error) {
+	var batch []models.WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", Pending, now).
+			Where("subscription_id IN (?)", tx.Model(&models.WebhookSubscription{}).Select("id").Where("is_active = ?", true)).
+			Order("next_attempt_at, id").Limit(batchSize).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(batch))
+		for i, d := range batch {
+			ids[i] = d.ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(claimLease)).Error
+	})
+	return batch, err
+}
+
+// attempt sends one delivery and records the outcome on it and on its
+// subscription
+func attempt(db *gorm.DB, d *models.WebhookDelivery, settings Settings) {
+	var subscription models.WebhookSubscription
+	if err := db.First(&subscription, d.SubscriptionID).Error; err != nil {
+		return
+	}
+
+	status, err := send(&subscription, d)
+	now := time.Now()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = status
+	d.LastError = ""
+
+	succeeded := err == nil
+	if succeeded {
+		d.Status = Delivered
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	} else {
+		d.LastError = truncate(err.Error(), 500)
+		if d.Attempts >= settings.MaxAttempts {
+			d.Status = Failed
+			d.NextAttemptAt = nil
+		} else {
+			next := now.Add(settings.RetryWait(d.Attempts))
+			d.NextAttemptAt = &next
+		}
+	}
+
+	db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status",
+			"last_error", "delivered_at").Save(d).Error; err != nil {
+			return err
+		}
+
+		if succeeded {
+			return tx.Model(&models.WebhookSubscription{}).Where("id = ?", subscription.ID).
+				Updates(map[string]interface{}{"consecutive_failures": 0, "last_success_at": now}).Error
+		}
+
+		if err := tx.Model(&models.WebhookSubscription{}).Where("id = ?", subscription.ID).
+			Updates(map[string]interface{}{
+				"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+				"last_failure_at":      now,
+			}).Error; err != nil {
+			return err
+		}
+		// Switch the endpoint off once it has failed too often in a row
+		return tx.Model(&models.WebhookSubscription{}).
+			Where("id = ? AND is_active = ? AND consecutive_failures >= ?", subscription.ID, true, settings.DisableAfter).
+			Updates(map[string]interface{}{
+				"is_active":       false,
+				"disabled_at":     now,
+				"disabled_reason": fmt.Sprintf("Disabled after %d failed deliveries in a row", settings.DisableAfter),
+			}).Error
+	})
+}
+
+// send posts the payload, signed, and returns the status of the answer. Any
+// 2xx status is success. The answer itself is not kept, so a subscription
+// cannot be used to read what some other server says.
+func send(s *models.WebhookSubscription, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FloodRelief-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(s.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,111 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrURL            = errors.New("url must be an absolute http or https URL")
+	ErrBlockedAddress = errors.New("url must not point at a loopback, private, link-local or unspecified address")
+)
+
+// reservedNets are ranges that reach the server's own network but are not
+// covered by the net.IP predicates: "this network" and carrier-grade NAT,
+// where some clouds keep their metadata service
+var reservedNets = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10")
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// allowPrivateTargets lets a development setup deliver to its own machine
+// or network when WEBHOOK_ALLOW_PRIVATE_TARGETS is true
+func allowPrivateTargets() bool {
+	return os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") == "true"
+}
+
+// blocked reports whether an address is one partners must not be able to
+// make the server call: anything on the server itself or its network
+func blocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL checks a subscription URL when it is saved: it must be absolute
+// http or https and every address its host resolves to must be public.
+// Delivery checks the address again when it connects, since DNS can change.
+func CheckURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrURL
+	}
+	if allowPrivateTargets() {
+		return nil
+	}
+
+	ips, err := net.LookupIP(target.Hostname())
+	if err != nil || len(ips) == 0 {
+		return ErrURL
+	}
+	for _, ip := range ips {
+		if blocked(ip) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to blocked addresses. It runs after name
+// resolution, on the address actually dialled.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	if allowPrivateTargets() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blocked(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// client posts deliveries. It goes to the endpoint directly rather than
+// through a proxy, so the dial check sees the real address, and it does not
+// follow redirects, which could lead anywhere.
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialControl}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        20,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
@@ -0,0 +1,197 @@
+// Package webhooks sends change events to partner systems. Deliveries are
+// queued in the transaction that records the event, signed with the
+// subscription's secret and retried with exponential backoff; a
+// subscription whose endpoint keeps failing is switched off.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"flood-relief-system/backend/events"
+	"flood-relief-system/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Delivery statuses
+const (
+	Pending   = "pending"
+	Delivered = "delivered"
+	Failed    = "failed"
+)
+
+var ErrEventType = errors.New("event types must be *, an entity with .* or an entity with .created, .updated or .deleted")
+
+// envInt reads a positive integer setting, falling back to def
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// Settings tune delivery:
+// WEBHOOK_MAX_ATTEMPTS is how often a delivery is tried before it fails (default 8),
+// WEBHOOK_RETRY_BASE_SECONDS is the wait after the first failure, doubled each time (default 30),
+// WEBHOOK_DISABLE_AFTER_FAILURES is how many failures in a row switch a subscription off (default 20).
+type Settings struct {
+	MaxAttempts  int
+	RetryBase    time.Duration
+	DisableAfter int
+}
+
+// LoadSettings reads the delivery settings from the environment
+func LoadSettings() Settings {
+	return Settings{
+		MaxAttempts:  envInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		RetryBase:    time.Duration(envInt("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second,
+		DisableAfter: envInt("WEBHOOK_DISABLE_AFTER_FAILURES", 20),
+	}
+}
+
+// maxRetryWait caps the backoff between attempts
+const maxRetryWait = 6 * time.Hour
+
+// RetryWait is how long to wait after the given number of failed attempts
+func (s Settings) RetryWait(attempts int) time.Duration {
+	wait := s.RetryBase
+	for i := 1; i < attempts && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	return wait
+}
+
+// ParseEventTypes checks a comma-separated list of event types and returns
+// it tidied up. Types are help_request.created and the like; entity.*
+// takes every action of an entity and * takes everything.
+func ParseEventTypes(list string) (string, error) {
+	var types []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if item == "*" {
+			types = append(types, item)
+			continue
+		}
+
+		name, action, ok := strings.Cut(item, ".")
+		entity, known := events.EntityName(name)
+		if !ok || !known {
+			return "", fmt.Errorf("%w: %q", ErrEventType, item)
+		}
+		switch action {
+		case "*", events.Created, events.Updated, events.Deleted:
+		default:
+			return "", fmt.Errorf("%w: %q", ErrEventType, item)
+		}
+		types = append(types, entity+"."+action)
+	}
+	if len(types) == 0 {
+		return "", ErrEventType
+	}
+	return strings.Join(types, ","), nil
+}
+
+// Matches reports whether a subscription's event types take an event type
+func Matches(types, eventType string) bool {
+	entity, _, _ := strings.Cut(eventType, ".")
+	for _, t := range strings.Split(types, ",") {
+		if t == "*" || t == eventType || t == entity+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign is the X-Webhook-Signature of a body sent at timestamp (Unix
+// seconds): sha256= and the hex HMAC-SHA256 of "timestamp.body" keyed with
+// the secret. Receivers recompute it and compare in constant time, and
+// reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue queues a delivery of the event to every active subscription that
+// takes its type. It runs in the transaction that records the event (see
+// events.OnRecord), so each event is queued once however many servers run.
+func Enqueue(tx *gorm.DB, e *models.ChangeEvent) error {
+	var subscriptions []models.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	eventType := e.Entity + "." + e.Action
+	var payload []byte
+	now := time.Now()
+	for _, s := range subscriptions {
+		if !Matches(s.EventTypes, eventType) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(events.MessageOf(e)); err != nil {
+				return err
+			}
+		}
+
+		delivery := models.WebhookDelivery{
+			SubscriptionID: s.ID,
+			ChangeEventID:  e.ID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         Pending,
+			NextAttemptAt:  &now,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver queues the payload of an earlier delivery again, keeping the
+// original in the log
+func Redeliver(db *gorm.DB, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		ChangeEventID:  original.ChangeEventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         Pending,
+		NextAttemptAt:  &now,
+		RedeliveryOf:   &original.ID,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
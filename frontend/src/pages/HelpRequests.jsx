@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import { helpRequestsAPI } from '../services/api';
+import { helpRequestsAPI, subscribeEvents } from '../services/api';
 
 function HelpRequests() {
    // STATE MANAGEMENT
@@ -80,6 +80,24 @@ function HelpRequests() {
       fetchHelpRequests();
    }, []);
 
+   // LIVE UPDATES - apply changes made by other coordinators without a refresh
+   useEffect(() => {
+      const close = subscribeEvents(['help_request'], {
+         onEvent: (event) => {
+            setRequests((current) => {
+               const others = current.filter(r => r.id !== event.entity_id);
+               if (event.action === 'deleted') return others;
+               const exists = others.length !== current.length;
+               return exists
+                  ? current.map(r => (r.id === event.entity_id ? event.data : r))
+                  : [event.data, ...current];
+            });
+         },
+         onReset: () => fetchHelpRequests(),
+      });
+      return close;
+   }, []);
+
    // CREATE OR UPDATE REQUEST
    const handleSubmit = async (e) => {
       e.preventDefault();
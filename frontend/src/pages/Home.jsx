@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
-import { helpRequestsAPI, volunteersAPI } from '../services/api';
+import { statsAPI } from '../services/api';
 
 function Home() {
    const [stats, setStats] = useState({
@@ -17,27 +17,23 @@ function Home() {
    try {
       setLoading(true);
 
-      // Fetch help requests
-      const requestsResponse = await helpRequestsAPI.getAll();
-      // ✅ ADD SAFETY CHECK
-      const requests = Array.isArray(requestsResponse.data) 
-         ? requestsResponse.data 
-         : [];
+      // Counted on the server, so the dashboard no longer downloads every record
+      const response = await statsAPI.getOverview();
+      const entities = response.data?.entities || {};
+      const requests = entities.help_requests || { total: 0, by: {} };
+      const volunteers = entities.volunteers || { total: 0, by: {} };
 
-      // Fetch volunteers
-      const volunteersResponse = await volunteersAPI.getAll();
-      // ✅ ADD SAFETY CHECK
-      const volunteers = Array.isArray(volunteersResponse.data) 
-         ? volunteersResponse.data 
-         : [];
+      // ✅ Count for one key of a grouped count, 0 when it is missing
+      const countOf = (counts, key) =>
+         (Array.isArray(counts) ? counts : []).find(c => c.key === key)?.count || 0;
 
       // Calculate statistics
       setStats({
-         totalRequests: requests.length,
-         pendingRequests: requests.filter(r => r.status === 'pending').length,
-         completedRequests: requests.filter(r => r.status === 'completed').length,
-         totalVolunteers: volunteers.length,
-         availableVolunteers: volunteers.filter(v => v.availability === 'available').length,
+         totalRequests: requests.total,
+         pendingRequests: countOf(requests.by.status, 'pending'),
+         completedRequests: countOf(requests.by.status, 'completed'),
+         totalVolunteers: volunteers.total,
+         availableVolunteers: countOf(volunteers.by.availability, 'available'),
       });
 
    } catch (err) {
@@ -145,6 +145,16 @@ export const reliefSuppliesAPI = {
 }
 
 
+// DASHBOARD STATS API
+export const statsAPI = {
+    // GET counts by status, priority, category and service type
+    getOverview: () => api.get('/stats'),
+
+    // GET time series, e.g. getSeries('people_rescued', { bucket: '1d' })
+    getSeries: (metric, params) => api.get(`/stats/series/${metric}`, { params }),
+}
+
+
 
 
 
@@ -154,5 +164,32 @@ export const reliefSuppliesAPI = {
 
 
 
+// CHANGE STREAM
+// Opens /events as Server-Sent Events for the given entities (e.g. ['help_request']).
+// onEvent gets every change; onReset is called when the server cannot replay
+// what was missed and the data should be reloaded. Returns a close function.
+export const subscribeEvents = (entities, { onEvent, onReset }, filters = {}) => {
+    const query = new URLSearchParams({ entity: entities.join(','), ...filters });
+    const source = new EventSource(`${API_BASE_URL}/events?${query}`);
+
+    // The browser sends Last-Event-ID itself when it reconnects. The replay
+    // starts a little before it, since events can commit out of order, so
+    // skip the events already handled.
+    const seen = new Set();
+    const handle = (e) => {
+        const event = JSON.parse(e.data);
+        if (seen.has(event.id)) return;
+        seen.add(event.id);
+        if (seen.size > 1000) seen.delete(seen.values().next().value);
+        onEvent(event);
+    };
+    entities.forEach((entity) => {
+        ['created', 'updated', 'deleted'].forEach((action) =>
+            source.addEventListener(`${entity}.${action}`, handle));
+    });
+    if (onReset) source.addEventListener('reset', onReset);
+
+    return () => source.close();
+};
 
 export default api;
\ No newline at end of file